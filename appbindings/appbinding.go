@@ -0,0 +1,90 @@
+package main
+
+import (
+	"github.com/ArnobKumarSaha/k8s/bindings"
+	bapi "go.bytebuilders.dev/catalog/api/v1alpha1"
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kmapi "kmodules.xyz/client-go/api/v1"
+	appcat "kmodules.xyz/custom-resources/apis/appcatalog/v1alpha1"
+	"net"
+	"strconv"
+	"strings"
+)
+
+const managedBy = "appbindings.catalog.appscode.com"
+
+// newAppBinding returns the AppBinding generated for b, named after it in its
+// namespace, which is where the consumer of the binding lives.
+func newAppBinding(b *bapi.GenericBinding) (*appcat.AppBinding, error) {
+	ab := &appcat.AppBinding{
+		TypeMeta: metav1.TypeMeta{APIVersion: appcat.SchemeGroupVersion.String(), Kind: appcat.ResourceKindApp},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      b.Name,
+			Namespace: b.Namespace,
+		},
+	}
+	url, err := clientURL(b)
+	if err != nil {
+		return nil, err
+	}
+	setAppBinding(ab, b, url)
+	return ab, nil
+}
+
+// clientURL returns the URL of the gateway of b that clients connect to.
+func clientURL(b *bapi.GenericBinding) (string, error) {
+	host, port, err := bindings.Endpoint(b.Status.Gateway, "")
+	if err != nil {
+		return "", err
+	}
+	return bindings.URLScheme(bindings.DatabaseKind(b)) + "://" + net.JoinHostPort(host, strconv.Itoa(int(port))), nil
+}
+
+// setAppBinding sets the fields of ab the generator owns from b and its client
+// url, keeping the ones others may have added.
+func setAppBinding(ab *appcat.AppBinding, b *bapi.GenericBinding, url string) {
+	kind := bindings.DatabaseKind(b)
+	if ab.Labels == nil {
+		ab.Labels = map[string]string{}
+	}
+	ab.Labels["app.kubernetes.io/managed-by"] = managedBy
+	ab.Labels["app.kubernetes.io/instance"] = b.Spec.SourceRef.Name
+	ab.OwnerReferences = upsertOwner(ab.OwnerReferences, *metav1.NewControllerRef(b, b.GroupVersionKind()))
+
+	sourceNamespace := b.Spec.SourceRef.Namespace
+	if sourceNamespace == "" {
+		sourceNamespace = b.Namespace
+	}
+	ab.Spec.Type = appcat.AppType(bindings.KubeDBGroup + "/" + strings.ToLower(kind))
+	ab.Spec.AppRef = &kmapi.TypedObjectReference{
+		APIGroup:  bindings.KubeDBGroup,
+		Kind:      kind,
+		Namespace: sourceNamespace,
+		Name:      b.Spec.SourceRef.Name,
+	}
+	ab.Spec.ClientConfig.URL = &url
+	ab.Spec.ClientConfig.Service = nil
+	ab.Spec.Secret = &core.LocalObjectReference{Name: b.Status.SecretRef.Name}
+}
+
+// upsertOwner replaces the controller reference in refs with ref.
+func upsertOwner(refs []metav1.OwnerReference, ref metav1.OwnerReference) []metav1.OwnerReference {
+	for i := range refs {
+		if refs[i].Controller != nil && *refs[i].Controller {
+			refs[i] = ref
+			return refs
+		}
+	}
+	return append(refs, ref)
+}
+
+// ownedBy tells whether ab was generated for b, so that AppBindings of the same
+// name made by others, like the ones KubeDB makes for its databases, are left
+// alone.
+func ownedBy(ab *appcat.AppBinding, b *bapi.GenericBinding) bool {
+	if ref := metav1.GetControllerOf(ab); ref != nil {
+		return ref.UID == b.UID
+	}
+	return ab.Labels["app.kubernetes.io/managed-by"] == managedBy
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/ArnobKumarSaha/k8s/bindings"
+	bapi "go.bytebuilders.dev/catalog/api/v1alpha1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+	cu "kmodules.xyz/client-go/client"
+	appcat "kmodules.xyz/custom-resources/apis/appcatalog/v1alpha1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"strings"
+)
+
+// generator keeps the AppBinding of each binding of one kind in sync with it.
+// The AppBinding is deleted with the binding by the garbage collector, and
+// by the generator when the binding leaves the Current phase, as its secret
+// is no longer valid then.
+type generator struct {
+	client.Client
+	gvk schema.GroupVersionKind
+}
+
+func (r *generator) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	u := &unstructured.Unstructured{}
+	u.SetGroupVersionKind(r.gvk)
+	if err := r.Get(ctx, req.NamespacedName, u); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if u.GetDeletionTimestamp() != nil {
+		return ctrl.Result{}, nil
+	}
+	b, err := bindings.ToGeneric(u)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{}, r.sync(ctx, b)
+}
+
+// sync creates, patches or deletes the AppBinding of b.
+func (r *generator) sync(ctx context.Context, b *bapi.GenericBinding) error {
+	var cur appcat.AppBinding
+	err := r.Get(ctx, types.NamespacedName{Namespace: b.Namespace, Name: b.Name}, &cur)
+	exists := err == nil
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	if exists && !ownedBy(&cur, b) {
+		klog.Warningf("%s %s/%s: AppBinding %s exists and was not generated for it, leaving it alone", b.Kind, b.Namespace, b.Name, b.Name)
+		return nil
+	}
+
+	if why := bindings.Ready(b); why != "" {
+		if exists {
+			klog.Infof("%s %s/%s: %s, deleting AppBinding", b.Kind, b.Namespace, b.Name, why)
+			return client.IgnoreNotFound(r.Delete(ctx, &cur))
+		}
+		klog.V(2).Infof("%s %s/%s: %s, waiting", b.Kind, b.Namespace, b.Name, why)
+		return nil
+	}
+	url, err := clientURL(b)
+	if err != nil {
+		// The binding is updated when its gateway is, which triggers a retry.
+		klog.Warningf("%s %s/%s: %v", b.Kind, b.Namespace, b.Name, err)
+		return nil
+	}
+
+	ab := &appcat.AppBinding{}
+	ab.Name, ab.Namespace = b.Name, b.Namespace
+	vt, err := cu.CreateOrPatch(ctx, r.Client, ab, func(obj client.Object, createOp bool) client.Object {
+		in := obj.(*appcat.AppBinding)
+		setAppBinding(in, b, url)
+		return in
+	})
+	if err != nil {
+		return err
+	}
+	klog.V(1).Infof("%s %s/%s: AppBinding %s", b.Kind, b.Namespace, b.Name, vt)
+	return nil
+}
+
+// bindingKinds returns the binding kinds of the catalog that are served by the
+// cluster.
+func bindingKinds(mapper meta.RESTMapper) []schema.GroupVersionKind {
+	var out []schema.GroupVersionKind
+	for _, kind := range bindings.Kinds(scm) {
+		gvk := bapi.GroupVersion.WithKind(kind)
+		if _, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version); err != nil {
+			klog.V(2).Infof("skipping %s: %v", gvk, err)
+			continue
+		}
+		out = append(out, gvk)
+	}
+	return out
+}
+
+func setupGenerators(mgr ctrl.Manager) error {
+	abGVK := appcat.SchemeGroupVersion.WithKind(appcat.ResourceKindApp)
+	if _, err := mgr.GetRESTMapper().RESTMapping(abGVK.GroupKind(), abGVK.Version); err != nil {
+		return fmt.Errorf("%s is not served by the cluster: %w", abGVK, err)
+	}
+	kinds := bindingKinds(mgr.GetRESTMapper())
+	if len(kinds) == 0 {
+		return fmt.Errorf("no binding kind of %s is served by the cluster", bapi.GroupVersion)
+	}
+	for _, gvk := range kinds {
+		obj := &unstructured.Unstructured{}
+		obj.SetGroupVersionKind(gvk)
+		err := ctrl.NewControllerManagedBy(mgr).
+			Named(strings.ToLower(gvk.Kind) + "-appbinding").
+			For(obj).
+			Owns(&appcat.AppBinding{}).
+			Complete(&generator{Client: mgr.GetClient(), gvk: gvk})
+		if err != nil {
+			return err
+		}
+		klog.Infof("generating AppBindings for %s", gvk)
+	}
+	return nil
+}
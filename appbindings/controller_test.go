@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"github.com/ArnobKumarSaha/k8s/bindings"
+	"github.com/ArnobKumarSaha/k8s/objsource"
+	bapi "go.bytebuilders.dev/catalog/api/v1alpha1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	appcat "kmodules.xyz/custom-resources/apis/appcatalog/v1alpha1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"testing"
+)
+
+// loadBinding returns the binding of kind named name from testdata as an
+// unstructured object, so that cases can change any field of it.
+func loadBinding(t *testing.T, kind, name string) *unstructured.Unstructured {
+	t.Helper()
+	src, err := objsource.LoadDir("testdata", scm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	objs, err := src.List(context.TODO(), bapi.GroupVersion.WithKind(kind), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, obj := range objs {
+		if obj.GetName() != name {
+			continue
+		}
+		content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+		if err != nil {
+			t.Fatal(err)
+		}
+		u := &unstructured.Unstructured{Object: content}
+		u.SetGroupVersionKind(bapi.GroupVersion.WithKind(kind))
+		return u
+	}
+	t.Fatalf("no %s %s in testdata", kind, name)
+	return nil
+}
+
+// generated returns the AppBinding the generator makes for binding.
+func generated(t *testing.T, binding *unstructured.Unstructured) *appcat.AppBinding {
+	t.Helper()
+	b, err := bindings.ToGeneric(binding)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ab, err := newAppBinding(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ab
+}
+
+func TestGenerator(t *testing.T) {
+	cases := []struct {
+		name string
+		kind string
+		// binding is the name of the binding in testdata.
+		binding string
+		// existing returns the AppBinding in the cluster before the binding
+		// changed, if any.
+		existing func(t *testing.T, b *unstructured.Unstructured) *appcat.AppBinding
+		// change is applied to the binding before it is reconciled.
+		change func(b *unstructured.Unstructured)
+		// want checks the AppBinding after the reconcile, nil if there is none.
+		want func(t *testing.T, ab *appcat.AppBinding)
+	}{
+		{
+			name:    "creates the AppBinding of a Current binding",
+			kind:    "SinglestoreBinding",
+			binding: "ssbinding",
+			want: func(t *testing.T, ab *appcat.AppBinding) {
+				if ab == nil {
+					t.Fatal("no AppBinding was created")
+				}
+				if got := *ab.Spec.ClientConfig.URL; got != "mysql://sdb-sample.demo.db.example.com:3306" {
+					t.Errorf("url = %s", got)
+				}
+				if ab.Spec.Secret == nil || ab.Spec.Secret.Name != "ssbinding-secret" {
+					t.Errorf("secret = %v, want ssbinding-secret", ab.Spec.Secret)
+				}
+				if ab.Spec.AppRef == nil || ab.Spec.AppRef.Kind != "Singlestore" || ab.Spec.AppRef.Name != "sdb-sample" {
+					t.Errorf("appRef = %+v, want Singlestore sdb-sample", ab.Spec.AppRef)
+				}
+				if ref := ab.OwnerReferences; len(ref) != 1 || ref[0].UID != "9a1f0c52-6c0e-4c43-9d7b-3a4b8e2f1d10" || ref[0].Kind != "SinglestoreBinding" {
+					t.Errorf("ownerReferences = %+v, want the binding as controller", ref)
+				}
+			},
+		},
+		{
+			name:    "syncs the AppBinding with a changed binding",
+			kind:    "SinglestoreBinding",
+			binding: "ssbinding",
+			existing: func(t *testing.T, b *unstructured.Unstructured) *appcat.AppBinding {
+				ab := generated(t, b)
+				ab.Labels["team"] = "db"
+				return ab
+			},
+			change: func(b *unstructured.Unstructured) {
+				_ = unstructured.SetNestedField(b.Object, "ssbinding-secret-rotated", "status", "secretRef", "name")
+				_ = unstructured.SetNestedField(b.Object, "sdb.demo.db.example.com", "status", "gateway", "hostname")
+			},
+			want: func(t *testing.T, ab *appcat.AppBinding) {
+				if ab == nil {
+					t.Fatal("the AppBinding was deleted")
+				}
+				if got := *ab.Spec.ClientConfig.URL; got != "mysql://sdb.demo.db.example.com:3306" {
+					t.Errorf("url = %s, want the new hostname", got)
+				}
+				if ab.Spec.Secret == nil || ab.Spec.Secret.Name != "ssbinding-secret-rotated" {
+					t.Errorf("secret = %v, want ssbinding-secret-rotated", ab.Spec.Secret)
+				}
+				if ab.Labels["team"] != "db" {
+					t.Errorf("labels = %v, want the team label others added kept", ab.Labels)
+				}
+			},
+		},
+		{
+			name:     "deletes the AppBinding of a binding that is no longer Current",
+			kind:     "SinglestoreBinding",
+			binding:  "ssbinding",
+			existing: generated,
+			change: func(b *unstructured.Unstructured) {
+				_ = unstructured.SetNestedField(b.Object, string(bapi.BindingPhaseExpired), "status", "phase")
+			},
+		},
+		{
+			name:    "skips a binding that is not Current yet",
+			kind:    "PostgresBinding",
+			binding: "pgbinding",
+		},
+		{
+			name:    "leaves an AppBinding it did not generate alone",
+			kind:    "SinglestoreBinding",
+			binding: "ssbinding",
+			existing: func(t *testing.T, b *unstructured.Unstructured) *appcat.AppBinding {
+				url := "mysql://sdb-sample.demo.svc:3306"
+				ab := &appcat.AppBinding{}
+				ab.Name, ab.Namespace = b.GetName(), b.GetNamespace()
+				ab.Spec.ClientConfig.URL = &url
+				return ab
+			},
+			want: func(t *testing.T, ab *appcat.AppBinding) {
+				if ab == nil {
+					t.Fatal("the AppBinding was deleted")
+				}
+				if got := *ab.Spec.ClientConfig.URL; got != "mysql://sdb-sample.demo.svc:3306" || len(ab.OwnerReferences) != 0 {
+					t.Errorf("AppBinding was changed to %s, owned by %+v", got, ab.OwnerReferences)
+				}
+			},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			b := loadBinding(t, c.kind, c.binding)
+			builder := fake.NewClientBuilder().WithScheme(scm)
+			if c.existing != nil {
+				builder = builder.WithObjects(c.existing(t, b))
+			}
+			if c.change != nil {
+				c.change(b)
+			}
+			kc := builder.WithObjects(b).Build()
+
+			r := &generator{Client: kc, gvk: b.GroupVersionKind()}
+			key := types.NamespacedName{Namespace: b.GetNamespace(), Name: b.GetName()}
+			if _, err := r.Reconcile(context.TODO(), ctrl.Request{NamespacedName: key}); err != nil {
+				t.Fatal(err)
+			}
+
+			var ab *appcat.AppBinding
+			var got appcat.AppBinding
+			if err := kc.Get(context.TODO(), key, &got); err == nil {
+				ab = &got
+			} else if !apierrors.IsNotFound(err) {
+				t.Fatal(err)
+			}
+			if c.want == nil {
+				if ab != nil {
+					t.Errorf("AppBinding %s exists, want none", client.ObjectKeyFromObject(ab))
+				}
+				return
+			}
+			c.want(t, ab)
+		})
+	}
+}
@@ -0,0 +1,143 @@
+// appbindings generates an appcatalog AppBinding for every Current catalog
+// binding, so that tools which consume AppBindings can connect through the
+// binding. The AppBinding is named after the binding, in its namespace, with
+// the URL of the primary service of the binding's offshoot gateway, the secret
+// of its status, and the binding as controller owner.
+//
+//	go run ./appbindings --context=kind-kind run [-n demo]
+//	go run ./appbindings render ./appbindings/testdata
+//
+// render prints the AppBindings for the bindings in files, without a cluster.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/ArnobKumarSaha/k8s/bindings"
+	"github.com/ArnobKumarSaha/k8s/kubeclient"
+	"github.com/ArnobKumarSaha/k8s/objsource"
+	bapi "go.bytebuilders.dev/catalog/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/klog/v2"
+	appcat "kmodules.xyz/custom-resources/apis/appcatalog/v1alpha1"
+	"os"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+	"sigs.k8s.io/yaml"
+)
+
+var (
+	scm = runtime.NewScheme()
+)
+
+func init() {
+	utilruntime.Must(clientgoscheme.AddToScheme(scm))
+	utilruntime.Must(bapi.AddToScheme(scm))
+	utilruntime.Must(appcat.AddToScheme(scm))
+}
+
+var kopts kubeclient.Options
+
+func main() {
+	kopts.AddFlags(flag.CommandLine)
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "Usage: appbindings [flags] run|render [args]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var err error
+	switch flag.Arg(0) {
+	case "run":
+		err = runGenerators(flag.Args()[1:])
+	case "render":
+		err = runRender(flag.Args()[1:])
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		klog.Fatalln(err)
+	}
+}
+
+func runGenerators(args []string) error {
+	fs := flag.NewFlagSet("run", flag.ExitOnError)
+	namespace := fs.String("n", "", "Only generate AppBindings for bindings of this namespace.")
+	_ = fs.Parse(args)
+
+	ctrl.SetLogger(klog.NewKlogr())
+	opts := ctrl.Options{
+		Scheme:  scm,
+		Metrics: metricsserver.Options{BindAddress: "0"},
+	}
+	if *namespace != "" {
+		opts.Cache.DefaultNamespaces = map[string]cache.Config{*namespace: {}}
+	}
+	cfg, err := kubeclient.New(kopts, scm).RESTConfig()
+	if err != nil {
+		return err
+	}
+	mgr, err := ctrl.NewManager(cfg, opts)
+	if err != nil {
+		return err
+	}
+	if err := setupGenerators(mgr); err != nil {
+		return err
+	}
+	return mgr.Start(ctrl.SetupSignalHandler())
+}
+
+func runRender(args []string) error {
+	fs := flag.NewFlagSet("render", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: appbindings render <file or directory>...")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	for _, p := range fs.Args() {
+		src, err := objsource.LoadDir(p, scm)
+		if err != nil {
+			return err
+		}
+		for _, kind := range bindings.Kinds(scm) {
+			objs, err := src.List(context.TODO(), bapi.GroupVersion.WithKind(kind), "")
+			if err != nil {
+				return err
+			}
+			for _, obj := range objs {
+				b, err := bindings.ToGeneric(obj)
+				if err != nil {
+					return err
+				}
+				if why := bindings.Ready(b); why != "" {
+					fmt.Fprintf(os.Stderr, "%s %s/%s: %s, skipped\n", kind, b.Namespace, b.Name, why)
+					continue
+				}
+				ab, err := newAppBinding(b)
+				if err != nil {
+					return fmt.Errorf("%s %s/%s: %w", kind, b.Namespace, b.Name, err)
+				}
+				data, err := yaml.Marshal(ab)
+				if err != nil {
+					return err
+				}
+				fmt.Printf("---\n%s", data)
+			}
+		}
+	}
+	return nil
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"fmt"
 	appsv1 "k8s.io/api/apps/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/fields"
@@ -70,10 +71,24 @@ func testCreateOrPatch(config *rest.Config) error {
 			Namespace: "demo",
 		},
 	}
+	version := "5.0.3"
+	var cur dbapi.MongoDB
+	err = kc.Get(context.TODO(), client.ObjectKeyFromObject(mg), &cur)
+	if err == nil && cur.Spec.Version != version {
+		// The ops manager has to run an UpdateVersion ops request to actually move the
+		// database to a new version, editing spec.version only breaks the running pods.
+		return fmt.Errorf("mongodb %s/%s runs %s, plan the update to %s with `go run ./ops upgrade-plan -n %s --to %s mongodb %s`",
+			cur.Namespace, cur.Name, cur.Spec.Version, version, cur.Namespace, version, cur.Name)
+	} else if client.IgnoreNotFound(err) != nil {
+		return err
+	}
+
 	klog.Infof("Trying to create mongodb")
 	v, err := cu.CreateOrPatch(context.TODO(), kc, mg, func(obj client.Object, createOp bool) client.Object {
 		db := obj.(*dbapi.MongoDB)
-		db.Spec.Version = "5.0.3"
+		if createOp {
+			db.Spec.Version = version
+		}
 		db.Spec.Replicas = pointer.Int32(1)
 		return db
 	})
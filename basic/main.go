@@ -2,16 +2,20 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
+	"github.com/ArnobKumarSaha/k8s/kubeclient"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
-	"k8s.io/client-go/tools/clientcmd"
-	"k8s.io/client-go/util/homedir"
-	"path/filepath"
 )
 
+var kopts kubeclient.Options
+
 func main() {
+	kopts.AddFlags(flag.CommandLine)
+	flag.Parse()
+
 	config := getRESTConfig()
 	_ = kubernetesClient(config)
 	_ = kubeBuilderClient(config)
@@ -20,16 +24,7 @@ func main() {
 }
 
 func getRESTConfig() *rest.Config {
-	//var kubeconfig string
-	//if home := homedir.HomeDir(); home != "" {
-	//	flag.StringVar(&kubeconfig, "kubeconfig", filepath.Join(home, ".kube", "config"), "(optional) absolute path to the kubeconfig file")
-	//} else {
-	//	flag.StringVar(&kubeconfig, "kubeconfig", "", "absolute path to the kubeconfig file")
-	//}
-	//flag.Parse()
-
-	home := homedir.HomeDir()
-	config, err := clientcmd.BuildConfigFromFlags("", filepath.Join(home, ".kube", "config"))
+	config, err := kubeclient.New(kopts, scm).RESTConfig()
 	if err != nil {
 		panic(err.Error())
 	}
@@ -0,0 +1,142 @@
+// Package bindings reads catalog bindings of any kind through the GenericBinding
+// duck type, and tells how to reach the database behind them: the kind of the
+// database and the host and port of the binding's offshoot gateway.
+package bindings
+
+import (
+	"fmt"
+	bapi "go.bytebuilders.dev/catalog/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	ofst "kmodules.xyz/offshoot-api/api/v1"
+	"sort"
+	"strings"
+)
+
+const (
+	KubeDBGroup  = "kubedb.com"
+	PrimaryAlias = "primary"
+)
+
+// urlSchemes are the URL schemes clients of the databases expect, by the
+// lowercase kind of the database. Others get tcp.
+var urlSchemes = map[string]string{
+	"postgres":      "postgresql",
+	"pgbouncer":     "postgresql",
+	"pgpool":        "postgresql",
+	"mysql":         "mysql",
+	"mariadb":       "mysql",
+	"perconaxtradb": "mysql",
+	"proxysql":      "mysql",
+	"singlestore":   "mysql",
+	"mongodb":       "mongodb",
+	"ferretdb":      "mongodb",
+	"redis":         "redis",
+	"memcached":     "memcached",
+	"mssqlserver":   "sqlserver",
+	"elasticsearch": "https",
+	"solr":          "http",
+	"druid":         "http",
+	"rabbitmq":      "amqp",
+	"kafka":         "kafka",
+	"zookeeper":     "zk",
+	"clickhouse":    "clickhouse",
+}
+
+// Kinds returns the binding kinds of the catalog registered in scheme, sorted.
+func Kinds(scheme *runtime.Scheme) []string {
+	var kinds []string
+	for kind := range scheme.KnownTypes(bapi.GroupVersion) {
+		if strings.HasSuffix(kind, "Binding") && kind != "GenericBinding" {
+			kinds = append(kinds, kind)
+		}
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+// ToGeneric duckifies a typed or unstructured binding of any kind into a
+// GenericBinding. The binding kinds share its fields, so kinds Duckify does not
+// know yet are converted field by field.
+func ToGeneric(obj runtime.Object) (*bapi.GenericBinding, error) {
+	var b bapi.GenericBinding
+	if _, ok := obj.(*unstructured.Unstructured); !ok {
+		if err := b.Duckify(obj); err == nil {
+			return &b, nil
+		}
+	}
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil, err
+	}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(content, &b); err != nil {
+		return nil, err
+	}
+	gvk := obj.GetObjectKind().GroupVersionKind()
+	if !strings.HasSuffix(gvk.Kind, "Binding") {
+		return nil, fmt.Errorf("%v is not a binding", gvk)
+	}
+	b.TypeMeta = metav1.TypeMeta{APIVersion: gvk.GroupVersion().String(), Kind: gvk.Kind}
+	return &b, nil
+}
+
+// DatabaseKind is the KubeDB kind a binding binds to, e.g. Singlestore for a
+// SinglestoreBinding.
+func DatabaseKind(b *bapi.GenericBinding) string {
+	return strings.TrimSuffix(b.Kind, "Binding")
+}
+
+// URLScheme returns the URL scheme of the database kind.
+func URLScheme(kind string) string {
+	if s, ok := urlSchemes[strings.ToLower(kind)]; ok {
+		return s
+	}
+	return "tcp"
+}
+
+// Ready tells why b cannot be connected to yet, or "".
+func Ready(b *bapi.GenericBinding) string {
+	switch {
+	case b.Status.Phase != bapi.BindingPhaseCurrent:
+		return fmt.Sprintf("phase is %q", b.Status.Phase)
+	case b.Status.SecretRef == nil || b.Status.SecretRef.Name == "":
+		return "no secret in status"
+	case b.Status.Gateway == nil:
+		return "no gateway in status"
+	}
+	return ""
+}
+
+// Endpoint picks the host and port of the gateway service with alias, or of
+// its first service if alias is empty and there is no primary one.
+func Endpoint(gw *ofst.Gateway, alias string) (string, int32, error) {
+	host := gw.Hostname
+	if host == "" {
+		host = gw.IP
+	}
+	if host == "" {
+		return "", 0, fmt.Errorf("gateway %s/%s has neither hostname nor ip", gw.Namespace, gw.Name)
+	}
+	want := alias
+	if want == "" {
+		want = PrimaryAlias
+	}
+	var svc *ofst.NamedServiceStatus
+	for i := range gw.Services {
+		if gw.Services[i].Alias == want {
+			svc = &gw.Services[i]
+			break
+		}
+	}
+	if svc == nil && alias == "" && len(gw.Services) > 0 {
+		svc = &gw.Services[0]
+	}
+	switch {
+	case svc == nil:
+		return "", 0, fmt.Errorf("gateway %s/%s has no %s service", gw.Namespace, gw.Name, want)
+	case len(svc.Ports) == 0:
+		return "", 0, fmt.Errorf("gateway %s/%s has no port for the %s service", gw.Namespace, gw.Name, svc.Alias)
+	}
+	return host, svc.Ports[0].Port, nil
+}
@@ -0,0 +1,107 @@
+// Package cassette records the API requests of a client to a JSONL file, a
+// cassette, and replays a cassette from a local server. Runs against a cluster
+// that is gone can so be repeated offline, request by request.
+package cassette
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Interaction is one request and its response, a line of a cassette.
+type Interaction struct {
+	Time   time.Time `json:"time"`
+	Method string    `json:"method"`
+	// URL is the path and query of the request.
+	URL         string          `json:"url"`
+	RequestBody json.RawMessage `json:"requestBody,omitempty"`
+	// Status is 0 if the request failed without a response.
+	Status       int             `json:"status"`
+	ContentType  string          `json:"contentType,omitempty"`
+	ResponseBody json.RawMessage `json:"responseBody,omitempty"`
+	Error        string          `json:"error,omitempty"`
+}
+
+// base64Prefix marks a body stored as base64, as it is not UTF-8, like a
+// protobuf response. A JSON string of text never starts with it, as text bodies
+// of the API server are JSON and so start with a brace.
+const base64Prefix = "base64:"
+
+// encodeBody keeps JSON bodies as they are, so cassettes stay readable, and
+// stores anything else, like watch streams, as a JSON string. Bodies that are
+// not UTF-8 would be changed by that and are stored as base64.
+func encodeBody(b []byte) json.RawMessage {
+	if len(b) == 0 {
+		return nil
+	}
+	if json.Valid(b) {
+		return json.RawMessage(b)
+	}
+	s := string(b)
+	if !utf8.Valid(b) {
+		s = base64Prefix + base64.StdEncoding.EncodeToString(b)
+	}
+	out, _ := json.Marshal(s)
+	return out
+}
+
+// decodeBody reverses encodeBody. API responses are JSON objects, so a JSON
+// string can only come from encodeBody.
+func decodeBody(raw json.RawMessage) []byte {
+	if len(raw) > 0 && raw[0] == '"' {
+		var s string
+		if json.Unmarshal(raw, &s) == nil {
+			if data, ok := strings.CutPrefix(s, base64Prefix); ok {
+				if b, err := base64.StdEncoding.DecodeString(data); err == nil {
+					return b
+				}
+			}
+			return []byte(s)
+		}
+	}
+	return raw
+}
+
+// matchKey identifies requests that replay the same interaction. The timeout
+// parameters are random in watches of client-go, so they are ignored.
+func matchKey(method, rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return method + " " + rawURL
+	}
+	q := u.Query()
+	q.Del("timeout")
+	q.Del("timeoutSeconds")
+	return method + " " + u.Path + "?" + q.Encode()
+}
+
+// Load reads the interactions of a cassette.
+func Load(path string) ([]Interaction, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var out []Interaction
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for line := 1; sc.Scan(); line++ {
+		if len(sc.Bytes()) == 0 {
+			continue
+		}
+		var in Interaction
+		if err := json.Unmarshal(sc.Bytes(), &in); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		out = append(out, in)
+	}
+	return out, sc.Err()
+}
@@ -0,0 +1,202 @@
+package cassette
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRedact(t *testing.T) {
+	cases := []struct {
+		name string
+		path string
+		body string
+		want string
+	}{
+		{
+			name: "secret",
+			path: "/api/v1/namespaces/demo/secrets/auth",
+			body: `{"kind":"Secret","metadata":{"name":"auth","annotations":{"kubectl.kubernetes.io/last-applied-configuration":"{\"data\":{\"password\":\"cGFzcw==\"}}"}},"data":{"password":"cGFzcw=="},"stringData":{"user":"root"}}`,
+			want: `{"data":{"password":"UkVEQUNURUQ="},"kind":"Secret","metadata":{"annotations":{"kubectl.kubernetes.io/last-applied-configuration":"REDACTED"},"name":"auth"},"stringData":{"user":"REDACTED"}}` + "\n",
+		},
+		{
+			name: "secret list",
+			path: "/api/v1/secrets",
+			body: `{"kind":"SecretList","items":[{"metadata":{"name":"a"},"data":{"token":"dG9rZW4="}}]}`,
+			want: `{"items":[{"data":{"token":"UkVEQUNURUQ="},"metadata":{"name":"a"}}],"kind":"SecretList"}` + "\n",
+		},
+		{
+			name: "patch of a secret",
+			path: "/api/v1/namespaces/demo/secrets/auth",
+			body: `{"data":{"password":"bmV3"}}`,
+			want: `{"data":{"password":"UkVEQUNURUQ="}}` + "\n",
+		},
+		{
+			name: "json patch of a secret",
+			path: "/api/v1/namespaces/demo/secrets/auth",
+			body: `[{"op":"replace","path":"/data/password","value":"bmV3"},{"op":"add","path":"/stringData","value":{"user":"root"}},` +
+				`{"op":"add","path":"/metadata/annotations/kubectl.kubernetes.io~1last-applied-configuration","value":"{}"},` +
+				`{"op":"test","path":"/metadata/labels/app","value":"web"},{"op":"remove","path":"/data/token"}]`,
+			want: `[{"op":"replace","path":"/data/password","value":"UkVEQUNURUQ="},{"op":"add","path":"/stringData","value":{"user":"REDACTED"}},` +
+				`{"op":"add","path":"/metadata/annotations/kubectl.kubernetes.io~1last-applied-configuration","value":"REDACTED"},` +
+				`{"op":"test","path":"/metadata/labels/app","value":"web"},{"op":"remove","path":"/data/token"}]` + "\n",
+		},
+		{
+			name: "json patch of a config map",
+			path: "/api/v1/namespaces/demo/configmaps/cfg",
+			body: `[{"op":"replace","path":"/data/a","value":"b"}]`,
+			want: `[{"op":"replace","path":"/data/a","value":"b"}]` + "\n",
+		},
+		{
+			name: "watch of secrets",
+			path: "/api/v1/namespaces/demo/secrets",
+			body: `{"type":"ADDED","object":{"kind":"Secret","data":{"a":"YQ=="}}}` + "\n" + `{"type":"MODIFIED","object":{"kind":"Secret","data":{"a":"Yg=="}}}`,
+			want: `{"object":{"data":{"a":"UkVEQUNURUQ="},"kind":"Secret"},"type":"ADDED"}` + "\n" + `{"object":{"data":{"a":"UkVEQUNURUQ="},"kind":"Secret"},"type":"MODIFIED"}` + "\n",
+		},
+		{
+			name: "token request",
+			path: "/api/v1/namespaces/demo/serviceaccounts/default/token",
+			body: `{"kind":"TokenRequest","status":{"token":"eyJhbGciOi","expirationTimestamp":"2024-01-01T00:00:00Z"}}`,
+			want: `{"kind":"TokenRequest","status":{"expirationTimestamp":"2024-01-01T00:00:00Z","token":"REDACTED"}}` + "\n",
+		},
+		{
+			name: "protobuf secret",
+			path: "/api/v1/namespaces/demo/secrets/auth",
+			body: "k8s\x00\n\x0c\n\x02v1\x12\x06Secret",
+			want: `"UkVEQUNURUQ="`,
+		},
+		{
+			name: "config map",
+			path: "/api/v1/namespaces/demo/configmaps/cfg",
+			body: `{"kind":"ConfigMap","data":{"a":"b"}}`,
+			want: `{"data":{"a":"b"},"kind":"ConfigMap"}` + "\n",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := string(redact(c.path, []byte(c.body))); got != c.want {
+				t.Errorf("redact(%s) = %s, want %s", c.path, got, c.want)
+			}
+		})
+	}
+}
+
+func TestEncodeBody(t *testing.T) {
+	for _, body := range []string{
+		`{"kind":"Pod"}`,
+		`{"type":"ADDED","object":{}}` + "\n" + `{"type":"DELETED","object":{}}` + "\n",
+		"k8s\x00\n\x0c\n\x02v1\x12\x03Pod\x1a\xff\xfe",
+	} {
+		raw := encodeBody([]byte(body))
+		if !json.Valid(raw) {
+			t.Errorf("encodeBody(%q) = %s, not JSON", body, raw)
+		}
+		if got := decodeBody(raw); !bytes.Equal(got, []byte(body)) {
+			t.Errorf("decodeBody(encodeBody(%q)) = %q", body, got)
+		}
+	}
+}
+
+func TestRecordReplay(t *testing.T) {
+	var accepts []string
+	cluster := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accepts = append(accepts, r.Header.Get("Accept"))
+		var obj runtime.Object
+		switch r.URL.Path {
+		case "/api/v1/namespaces/demo/configmaps/cfg":
+			obj = &core.ConfigMap{
+				TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+				ObjectMeta: metav1.ObjectMeta{Namespace: "demo", Name: "cfg"},
+				Data:       map[string]string{"mode": "ha"},
+			}
+		case "/api/v1/namespaces/demo/secrets/auth":
+			obj = &core.Secret{
+				TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Secret"},
+				ObjectMeta: metav1.ObjectMeta{Namespace: "demo", Name: "auth"},
+				Data:       map[string][]byte{"password": []byte("s3cr3t")},
+			}
+		default:
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(obj)
+	}))
+	defer cluster.Close()
+
+	path := filepath.Join(t.TempDir(), "cassette.jsonl")
+	rec, err := NewRecorder(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &rest.Config{Host: cluster.URL}
+	rec.Configure(cfg)
+	kc, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.TODO()
+	if _, err := kc.CoreV1().ConfigMaps("demo").Get(ctx, "cfg", metav1.GetOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := kc.CoreV1().Secrets("demo").Get(ctx, "auth", metav1.GetOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := rec.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for _, a := range accepts {
+		if strings.Contains(a, runtime.ContentTypeProtobuf) {
+			t.Errorf("recorded client asked for %s, want JSON only", a)
+		}
+	}
+
+	interactions, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(interactions) != 2 {
+		t.Fatalf("cassette has %d interactions, want 2", len(interactions))
+	}
+	srv := NewServer(interactions)
+	if _, err := srv.Start("127.0.0.1:0"); err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+	cfg, err = srv.RESTConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	kc, err = kubernetes.NewForConfig(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cm, err := kc.CoreV1().ConfigMaps("demo").Get(ctx, "cfg", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cm.Data["mode"] != "ha" {
+		t.Errorf("replayed ConfigMap data = %v, want mode=ha", cm.Data)
+	}
+	secret, err := kc.CoreV1().Secrets("demo").Get(ctx, "auth", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(secret.Data["password"]); got != "REDACTED" {
+		t.Errorf("replayed Secret password = %q, want REDACTED", got)
+	}
+	if _, err := kc.CoreV1().Pods("demo").Get(ctx, "web-0", metav1.GetOptions{}); err == nil {
+		t.Error("replayed Get of a Pod not in the cassette succeeded")
+	}
+}
@@ -0,0 +1,118 @@
+package cassette
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/rest"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// Recorder appends every request made through it to a cassette. Interactions
+// are written when the response body is read to the end or closed, so a watch
+// is written when it ends.
+type Recorder struct {
+	mu sync.Mutex
+	f  *os.File
+}
+
+// NewRecorder appends to the cassette at path, creating it if needed.
+func NewRecorder(path string) (*Recorder, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return &Recorder{f: f}, nil
+}
+
+func (r *Recorder) Close() error {
+	return r.f.Close()
+}
+
+// Configure records the requests of cfg. It makes cfg ask for JSON instead of
+// protobuf, as only JSON can be redacted and read back.
+func (r *Recorder) Configure(cfg *rest.Config) {
+	cfg.ContentType = runtime.ContentTypeJSON
+	cfg.AcceptContentTypes = runtime.ContentTypeJSON
+	cfg.Wrap(r.Wrap)
+}
+
+// Wrap fits rest.Config.Wrap:
+//
+//	cfg.Wrap(rec.Wrap)
+func (r *Recorder) Wrap(next http.RoundTripper) http.RoundTripper {
+	return &recordingTransport{rec: r, next: next}
+}
+
+func (r *Recorder) write(in *Interaction) {
+	data, err := json.Marshal(in)
+	if err != nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, _ = r.f.Write(append(data, '\n'))
+}
+
+type recordingTransport struct {
+	rec  *Recorder
+	next http.RoundTripper
+}
+
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		var err error
+		if reqBody, err = io.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		_ = req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(reqBody))
+	}
+
+	in := &Interaction{
+		Time:        time.Now().UTC(),
+		Method:      req.Method,
+		URL:         req.URL.RequestURI(),
+		RequestBody: encodeBody(redact(req.URL.Path, reqBody)),
+	}
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		in.Error = err.Error()
+		t.rec.write(in)
+		return nil, err
+	}
+	in.Status = resp.StatusCode
+	in.ContentType = resp.Header.Get("Content-Type")
+	resp.Body = &recordingBody{ReadCloser: resp.Body, done: func(body []byte) {
+		in.ResponseBody = encodeBody(redact(req.URL.Path, body))
+		t.rec.write(in)
+	}}
+	return resp, nil
+}
+
+// recordingBody keeps a copy of what is read and hands it to done once.
+type recordingBody struct {
+	io.ReadCloser
+	buf  bytes.Buffer
+	once sync.Once
+	done func(body []byte)
+}
+
+func (b *recordingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.buf.Write(p[:n])
+	if err == io.EOF {
+		b.once.Do(func() { b.done(b.buf.Bytes()) })
+	}
+	return n, err
+}
+
+func (b *recordingBody) Close() error {
+	b.once.Do(func() { b.done(b.buf.Bytes()) })
+	return b.ReadCloser.Close()
+}
@@ -0,0 +1,138 @@
+package cassette
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+)
+
+// redacted is "REDACTED" in base64, so redacted Secrets still decode.
+const redacted = "UkVEQUNURUQ="
+
+const lastAppliedAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+
+// redact removes the values of Secrets, and the tokens of TokenRequests, from a
+// request or response body. Bodies of requests to a secrets resource, like
+// patches, have no kind and are redacted as Secrets, and JSON Patches to them
+// have the values below data and stringData redacted. Watch streams are
+// redacted event by event.
+func redact(path string, body []byte) []byte {
+	if len(body) == 0 {
+		return body
+	}
+	secretPath := isSecretPath(path)
+
+	var out bytes.Buffer
+	dec := json.NewDecoder(bytes.NewReader(body))
+	for {
+		var v interface{}
+		err := dec.Decode(&v)
+		if errors.Is(err, io.EOF) {
+			return out.Bytes()
+		}
+		if err != nil {
+			// Not JSON, e.g. protobuf, or cut off. Keep nothing that could hold a secret.
+			if secretPath {
+				return []byte(`"` + redacted + `"`)
+			}
+			return body
+		}
+		redactValue(v, secretPath)
+		data, _ := json.Marshal(v)
+		out.Write(data)
+		out.WriteByte('\n')
+	}
+}
+
+func isSecretPath(path string) bool {
+	for _, seg := range strings.Split(path, "/") {
+		if seg == "secrets" || seg == "token" {
+			return true
+		}
+	}
+	return false
+}
+
+func redactValue(v interface{}, secretPath bool) {
+	if ops, ok := v.([]interface{}); ok {
+		if secretPath {
+			redactJSONPatch(ops)
+		}
+		return
+	}
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return
+	}
+	kind, _ := m["kind"].(string)
+	switch {
+	case kind == "Secret" || (secretPath && kind == ""):
+		redactSecret(m)
+	case kind == "SecretList":
+		items, _ := m["items"].([]interface{})
+		for _, item := range items {
+			if im, ok := item.(map[string]interface{}); ok {
+				redactSecret(im)
+			}
+		}
+	case kind == "TokenRequest":
+		if status, ok := m["status"].(map[string]interface{}); ok && status["token"] != nil {
+			status["token"] = "REDACTED"
+		}
+	}
+	// Watch events wrap the object.
+	if m["type"] != nil && m["object"] != nil {
+		redactValue(m["object"], secretPath)
+	}
+}
+
+func redactSecret(m map[string]interface{}) {
+	if data, ok := m["data"].(map[string]interface{}); ok {
+		for k := range data {
+			data[k] = redacted
+		}
+	}
+	if data, ok := m["stringData"].(map[string]interface{}); ok {
+		for k := range data {
+			data[k] = "REDACTED"
+		}
+	}
+	// kubectl apply keeps a full copy of the object in an annotation.
+	if md, ok := m["metadata"].(map[string]interface{}); ok {
+		if ann, ok := md["annotations"].(map[string]interface{}); ok && ann[lastAppliedAnnotation] != nil {
+			ann[lastAppliedAnnotation] = "REDACTED"
+		}
+	}
+}
+
+// redactJSONPatch redacts the values a JSON Patch to a Secret writes to its
+// data, its stringData or its last applied configuration, or to the whole
+// Secret.
+func redactJSONPatch(ops []interface{}) {
+	for _, op := range ops {
+		m, ok := op.(map[string]interface{})
+		if !ok || m["value"] == nil {
+			continue
+		}
+		path, _ := m["path"].(string)
+		switch {
+		case path == "" || path == "/":
+			redactValue(m["value"], true)
+		case path == "/data" || path == "/stringData" || path == "/metadata" || path == "/metadata/annotations":
+			// Wrap the value to redact it as the field of a Secret.
+			secret := map[string]interface{}{}
+			if path == "/metadata/annotations" {
+				secret["metadata"] = map[string]interface{}{"annotations": m["value"]}
+			} else {
+				secret[strings.TrimPrefix(path, "/")] = m["value"]
+			}
+			redactSecret(secret)
+		case strings.HasPrefix(path, "/data/"):
+			m["value"] = redacted
+		case strings.HasPrefix(path, "/stringData/"), path == "/metadata/annotations/"+strings.ReplaceAll(lastAppliedAnnotation, "/", "~1"):
+			m["value"] = "REDACTED"
+		}
+	}
+}
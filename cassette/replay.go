@@ -0,0 +1,136 @@
+package cassette
+
+import (
+	"fmt"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/json"
+	"k8s.io/client-go/rest"
+	"k8s.io/klog/v2"
+	"net"
+	"net/http"
+	"sync"
+)
+
+// Server answers requests from a cassette. Requests with the same method, path
+// and query get the recorded responses in recorded order, and the last one once
+// they run out. A watch that runs out stays open without events, like a quiet
+// cluster, so informers do not spin.
+type Server struct {
+	mu     sync.Mutex
+	queues map[string][]Interaction
+	last   map[string]Interaction
+
+	srv *http.Server
+	url string
+}
+
+func NewServer(interactions []Interaction) *Server {
+	s := &Server{queues: map[string][]Interaction{}, last: map[string]Interaction{}}
+	for _, in := range interactions {
+		key := matchKey(in.Method, in.URL)
+		s.queues[key] = append(s.queues[key], in)
+	}
+	return s
+}
+
+func (s *Server) next(key string, watch bool) (Interaction, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if q := s.queues[key]; len(q) > 0 {
+		s.queues[key] = q[1:]
+		s.last[key] = q[0]
+		return q[0], true
+	}
+	if watch {
+		return Interaction{}, false
+	}
+	in, ok := s.last[key]
+	return in, ok
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	key := matchKey(r.Method, r.URL.RequestURI())
+	watch := r.URL.Query().Get("watch") == "true" || r.URL.Query().Get("watch") == "1"
+	in, ok := s.next(key, watch)
+	switch {
+	case !ok && watch && s.seen(key):
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if f, ok := w.(http.Flusher); ok {
+			f.Flush()
+		}
+		<-r.Context().Done()
+		return
+	case !ok:
+		klog.Warningf("cassette has no response for %s", key)
+		writeStatus(w, http.StatusNotFound, metav1.StatusReasonNotFound, "not in cassette: "+key)
+		return
+	case in.Status == 0:
+		writeStatus(w, http.StatusServiceUnavailable, metav1.StatusReasonServiceUnavailable, "recorded error: "+in.Error)
+		return
+	}
+	if in.ContentType != "" {
+		w.Header().Set("Content-Type", in.ContentType)
+	}
+	w.WriteHeader(in.Status)
+	_, _ = w.Write(decodeBody(in.ResponseBody))
+}
+
+func (s *Server) seen(key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.last[key]
+	return ok
+}
+
+func writeStatus(w http.ResponseWriter, code int, reason metav1.StatusReason, msg string) {
+	data, _ := json.Marshal(&metav1.Status{
+		TypeMeta: metav1.TypeMeta{Kind: "Status", APIVersion: "v1"},
+		Status:   metav1.StatusFailure,
+		Message:  msg,
+		Reason:   reason,
+		Code:     int32(code),
+	})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_, _ = w.Write(data)
+}
+
+// Start serves on addr, e.g. 127.0.0.1:0, in the background and returns the URL.
+func (s *Server) Start(addr string) (string, error) {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return "", err
+	}
+	s.srv = &http.Server{Handler: s}
+	s.url = "http://" + l.Addr().String()
+	go func() {
+		if err := s.srv.Serve(l); err != nil && err != http.ErrServerClosed {
+			klog.Errorf("replay server: %v", err)
+		}
+	}()
+	return s.url, nil
+}
+
+func (s *Server) Close() error {
+	if s.srv == nil {
+		return nil
+	}
+	return s.srv.Close()
+}
+
+// RESTConfig returns a config for the started server. It asks for JSON, as the
+// Recorder does.
+func (s *Server) RESTConfig() (*rest.Config, error) {
+	if s.url == "" {
+		return nil, fmt.Errorf("replay server is not started")
+	}
+	return &rest.Config{
+		Host: s.url,
+		ContentConfig: rest.ContentConfig{
+			ContentType:        runtime.ContentTypeJSON,
+			AcceptContentTypes: runtime.ContentTypeJSON,
+		},
+	}, nil
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"fmt"
+	"github.com/go-sql-driver/mysql"
+	"io"
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"net"
+	"net/url"
+	"sigs.k8s.io/yaml"
+	"strconv"
+	"strings"
+)
+
+const maskedValue = "****"
+
+// connInfo is what a client needs to connect to the database behind a binding.
+type connInfo struct {
+	Kind     string // lowercase kind of the database, e.g. singlestore
+	Scheme   string
+	Host     string
+	Port     int32
+	Username string
+	Password string
+}
+
+func (c connInfo) hostPort() string {
+	return net.JoinHostPort(c.Host, strconv.Itoa(int(c.Port)))
+}
+
+// masked returns c with the password replaced.
+func (c connInfo) masked() connInfo {
+	if c.Password != "" {
+		c.Password = maskedValue
+	}
+	return c
+}
+
+// database is the database clients of kind connect to unless told otherwise.
+func (c connInfo) database() string {
+	switch c.Scheme {
+	case "postgresql":
+		return "postgres"
+	case "sqlserver":
+		return "master"
+	}
+	return ""
+}
+
+// userInfo keeps the mask readable, url.UserPassword would escape it.
+func (c connInfo) userInfo() string {
+	if c.Password == maskedValue {
+		return url.User(c.Username).String() + ":" + maskedValue
+	}
+	return url.UserPassword(c.Username, c.Password).String()
+}
+
+// dsn is the connection string of the usual driver of the database kind, e.g.
+// the DSN of go-sql-driver/mysql for the MySQL family.
+func (c connInfo) dsn() string {
+	switch c.Scheme {
+	case "mysql":
+		cfg := mysql.NewConfig()
+		cfg.User, cfg.Passwd = c.Username, c.Password
+		cfg.Net, cfg.Addr = "tcp", c.hostPort()
+		return cfg.FormatDSN()
+	case "postgresql":
+		return fmt.Sprintf("postgresql://%s@%s/%s", c.userInfo(), c.hostPort(), c.database())
+	case "mongodb":
+		return fmt.Sprintf("mongodb://%s@%s/?authSource=admin", c.userInfo(), c.hostPort())
+	case "redis":
+		return fmt.Sprintf("redis://%s@%s/0", c.userInfo(), c.hostPort())
+	case "sqlserver":
+		return fmt.Sprintf("sqlserver://%s@%s?database=%s", c.userInfo(), c.hostPort(), c.database())
+	}
+	return fmt.Sprintf("%s://%s@%s", c.Scheme, c.userInfo(), c.hostPort())
+}
+
+// jdbcURL returns "" for the kinds without a JDBC driver.
+func (c connInfo) jdbcURL() string {
+	query := url.Values{"user": {c.Username}, "password": {c.Password}}.Encode()
+	if c.Password == maskedValue {
+		query = "password=" + maskedValue + "&user=" + url.QueryEscape(c.Username)
+	}
+	switch c.Kind {
+	case "singlestore":
+		return fmt.Sprintf("jdbc:singlestore://%s/?%s", c.hostPort(), query)
+	case "mariadb":
+		return fmt.Sprintf("jdbc:mariadb://%s/?%s", c.hostPort(), query)
+	}
+	switch c.Scheme {
+	case "mysql":
+		return fmt.Sprintf("jdbc:mysql://%s/?%s", c.hostPort(), query)
+	case "postgresql":
+		return fmt.Sprintf("jdbc:postgresql://%s/%s?%s", c.hostPort(), c.database(), query)
+	case "sqlserver":
+		return fmt.Sprintf("jdbc:sqlserver://%s;databaseName=%s;user=%s;password=%s;encrypt=true", c.hostPort(), c.database(), jdbcValue(c.Username), jdbcValue(c.Password))
+	case "clickhouse":
+		return fmt.Sprintf("jdbc:clickhouse://%s/?%s", c.hostPort(), query)
+	}
+	return ""
+}
+
+// jdbcValue quotes a property value of a SQL Server JDBC URL in braces, with
+// closing braces doubled, if it has characters that would end or split it.
+func jdbcValue(v string) string {
+	if !strings.ContainsAny(v, ";={}") && strings.TrimSpace(v) == v {
+		return v
+	}
+	return "{" + strings.ReplaceAll(v, "}", "}}") + "}"
+}
+
+// env returns the variables of an environment block, in a stable order.
+func (c connInfo) env(prefix string) [][2]string {
+	vars := [][2]string{
+		{prefix + "HOST", c.Host},
+		{prefix + "PORT", strconv.Itoa(int(c.Port))},
+		{prefix + "USERNAME", c.Username},
+		{prefix + "PASSWORD", c.Password},
+		{prefix + "URL", c.dsn()},
+	}
+	if jdbc := c.jdbcURL(); jdbc != "" {
+		vars = append(vars, [2]string{prefix + "JDBC_URL", jdbc})
+	}
+	return vars
+}
+
+func writeDSN(w io.Writer, c connInfo) error {
+	_, err := fmt.Fprintln(w, c.dsn())
+	return err
+}
+
+func writeJDBC(w io.Writer, c connInfo) error {
+	jdbc := c.jdbcURL()
+	if jdbc == "" {
+		return fmt.Errorf("%s has no JDBC driver", c.Kind)
+	}
+	_, err := fmt.Fprintln(w, jdbc)
+	return err
+}
+
+// writeEnv writes a block for .env files, with the values quoted so that POSIX
+// shells can source it as well.
+func writeEnv(w io.Writer, c connInfo, prefix string) error {
+	for _, kv := range c.env(prefix) {
+		if _, err := fmt.Fprintf(w, "%s='%s'\n", kv[0], strings.ReplaceAll(kv[1], "'", `'\''`)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeSecret writes a Secret manifest with the environment block as keys, so
+// that an app can take it with envFrom.
+func writeSecret(w io.Writer, c connInfo, prefix, name, namespace string) error {
+	s := core.Secret{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Secret"},
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Type:       core.SecretTypeOpaque,
+		StringData: map[string]string{},
+	}
+	for _, kv := range c.env(prefix) {
+		s.StringData[kv[0]] = kv[1]
+	}
+	data, err := yaml.Marshal(s)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
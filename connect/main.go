@@ -0,0 +1,199 @@
+// connect prints how to connect to the database behind a catalog binding of any
+// kind: the host and port of the primary service of its offshoot gateway, and
+// the credentials of the secret in its status, as a DSN, a JDBC URL, an
+// environment block and a Secret manifest for the app.
+//
+//	go run ./connect -n demo singlestorebinding ssbinding
+//	go run ./connect -n demo -o env --mask singlestorebinding ssbinding
+//	go run ./connect -f ./connect/testdata -n demo -o secret singlestorebinding ssbinding
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/ArnobKumarSaha/k8s/bindings"
+	"github.com/ArnobKumarSaha/k8s/kubeclient"
+	"github.com/ArnobKumarSaha/k8s/objsource"
+	bapi "go.bytebuilders.dev/catalog/api/v1alpha1"
+	"io"
+	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/klog/v2"
+	"os"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"strings"
+)
+
+var (
+	scm = runtime.NewScheme()
+)
+
+func init() {
+	utilruntime.Must(clientgoscheme.AddToScheme(scm))
+	utilruntime.Must(bapi.AddToScheme(scm))
+}
+
+type options struct {
+	namespace  string
+	file       string
+	output     string
+	alias      string
+	mask       bool
+	envPrefix  string
+	secretName string
+}
+
+func main() {
+	var o options
+	flag.StringVar(&o.namespace, "n", "default", "Namespace of the binding.")
+	flag.StringVar(&o.file, "f", "", "Read the binding and its secret from the YAML and JSON files below this file or directory instead of the cluster.")
+	flag.StringVar(&o.output, "o", "all", "Output format: dsn, jdbc, env, secret or all.")
+	flag.StringVar(&o.alias, "alias", "", "Gateway service to connect to, e.g. standby. Defaults to the primary one.")
+	flag.BoolVar(&o.mask, "mask", false, "Print "+maskedValue+" instead of the password.")
+	flag.StringVar(&o.envPrefix, "env-prefix", "DB_", "Prefix of the environment variables and Secret keys.")
+	flag.StringVar(&o.secretName, "secret-name", "", "Name of the Secret manifest. Defaults to <binding>-connection.")
+	var kopts kubeclient.Options
+	kopts.AddFlags(flag.CommandLine)
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "Usage: connect [flags] <binding kind> <name>")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var src objsource.Source
+	if o.file != "" {
+		ds, err := objsource.LoadDir(o.file, scm)
+		if err != nil {
+			klog.Fatalln(err)
+		}
+		src = ds
+	} else {
+		kc, err := kubeclient.New(kopts, scm).Client()
+		if err != nil {
+			klog.Fatalln(err)
+		}
+		src = objsource.Live(kc)
+	}
+
+	if err := run(context.TODO(), os.Stdout, src, o, flag.Arg(0), flag.Arg(1)); err != nil {
+		klog.Fatalln(err)
+	}
+}
+
+// bindingKind resolves a binding kind the way kubectl resolves resources, from
+// its kind, singular or plural name, in any case.
+func bindingKind(arg string) (string, error) {
+	arg = strings.ToLower(arg)
+	for _, kind := range bindings.Kinds(scm) {
+		k := strings.ToLower(kind)
+		if arg == k || arg == k+"s" {
+			return kind, nil
+		}
+	}
+	return "", fmt.Errorf("unknown binding kind %q, expected one of %s", arg, strings.Join(bindings.Kinds(scm), ", "))
+}
+
+func get(ctx context.Context, src objsource.Source, gvk schema.GroupVersionKind, namespace, name string) (client.Object, error) {
+	objs, err := src.List(ctx, gvk, namespace)
+	if err != nil {
+		return nil, err
+	}
+	for _, obj := range objs {
+		if obj.GetName() == name {
+			return obj, nil
+		}
+	}
+	return nil, fmt.Errorf("%s %s/%s not found", gvk.Kind, namespace, name)
+}
+
+func run(ctx context.Context, w io.Writer, src objsource.Source, o options, kindArg, name string) error {
+	if o.secretName == "" {
+		o.secretName = name + "-connection"
+	}
+	write, ok := map[string]func(io.Writer, connInfo) error{
+		"dsn":  writeDSN,
+		"jdbc": writeJDBC,
+		"env": func(w io.Writer, c connInfo) error {
+			return writeEnv(w, c, o.envPrefix)
+		},
+		"secret": func(w io.Writer, c connInfo) error {
+			return writeSecret(w, c, o.envPrefix, o.secretName, o.namespace)
+		},
+		"all": func(w io.Writer, c connInfo) error {
+			return writeAll(w, c, o)
+		},
+	}[o.output]
+	if !ok {
+		return fmt.Errorf("unknown output format %q, expected dsn, jdbc, env, secret or all", o.output)
+	}
+
+	kind, err := bindingKind(kindArg)
+	if err != nil {
+		return err
+	}
+	obj, err := get(ctx, src, bapi.GroupVersion.WithKind(kind), o.namespace, name)
+	if err != nil {
+		return err
+	}
+	b, err := bindings.ToGeneric(obj)
+	if err != nil {
+		return err
+	}
+	if why := bindings.Ready(b); why != "" {
+		return fmt.Errorf("%s %s/%s is not ready: %s", kind, o.namespace, name, why)
+	}
+
+	c := connInfo{Kind: strings.ToLower(bindings.DatabaseKind(b))}
+	c.Scheme = bindings.URLScheme(c.Kind)
+	if c.Host, c.Port, err = bindings.Endpoint(b.Status.Gateway, o.alias); err != nil {
+		return err
+	}
+	secret, err := get(ctx, src, core.SchemeGroupVersion.WithKind("Secret"), o.namespace, b.Status.SecretRef.Name)
+	if err != nil {
+		return err
+	}
+	s := secret.(*core.Secret)
+	c.Username = secretValue(s, core.BasicAuthUsernameKey)
+	c.Password = secretValue(s, core.BasicAuthPasswordKey)
+	if c.Username == "" {
+		return fmt.Errorf("secret %s/%s has no %s", s.Namespace, s.Name, core.BasicAuthUsernameKey)
+	}
+	if o.mask {
+		c = c.masked()
+	}
+	return write(w, c)
+}
+
+func secretValue(s *core.Secret, key string) string {
+	if v, ok := s.StringData[key]; ok {
+		return v
+	}
+	return string(s.Data[key])
+}
+
+func writeAll(w io.Writer, c connInfo, o options) error {
+	fmt.Fprintln(w, "# DSN")
+	if err := writeDSN(w, c); err != nil {
+		return err
+	}
+	if c.jdbcURL() != "" {
+		fmt.Fprintln(w, "\n# JDBC URL")
+		if err := writeJDBC(w, c); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintln(w, "\n# Environment")
+	if err := writeEnv(w, c, o.envPrefix); err != nil {
+		return err
+	}
+	fmt.Fprintln(w, "\n# Secret")
+	return writeSecret(w, c, o.envPrefix, o.secretName, o.namespace)
+}
@@ -1,6 +1,7 @@
 package v1alpha1
 
 import (
+	"encoding/json"
 	"fmt"
 
 	apps "k8s.io/api/apps/v1"
@@ -9,6 +10,9 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+
+	"github.com/ArnobKumarSaha/k8s/api/v1beta1"
 )
 
 func (dst *MyPod) Duckify(srcRaw runtime.Object) error {
@@ -102,3 +106,76 @@ func (dst *MyPod) Duckify(srcRaw runtime.Object) error {
 	}
 	return fmt.Errorf("unknown src type %T", srcRaw)
 }
+
+// ConversionDataAnnotation keeps the v1beta1 fields that v1alpha1 can not
+// represent, so that a v1beta1 object survives a round trip through v1alpha1.
+const ConversionDataAnnotation = "core.duck.dev/v1beta1-conversion-data"
+
+type conversionData struct {
+	Replicas *int32                `json:"replicas,omitempty"`
+	Template *core.PodTemplateSpec `json:"template,omitempty"`
+	Status   *v1beta1.MyPodStatus  `json:"status,omitempty"`
+}
+
+var _ conversion.Convertible = &MyPod{}
+
+// ConvertTo converts this MyPod to the Hub version (v1beta1).
+func (src *MyPod) ConvertTo(dstRaw conversion.Hub) error {
+	dst := dstRaw.(*v1beta1.MyPod)
+	src.ObjectMeta.DeepCopyInto(&dst.ObjectMeta)
+	dst.Spec = v1beta1.MyPodSpec{
+		Selector: src.Spec.Selector.DeepCopy(),
+	}
+	dst.Status = v1beta1.MyPodStatus{}
+
+	data, ok := dst.Annotations[ConversionDataAnnotation]
+	if !ok {
+		return nil
+	}
+	delete(dst.Annotations, ConversionDataAnnotation)
+	if len(dst.Annotations) == 0 {
+		dst.Annotations = nil
+	}
+
+	var extra conversionData
+	if err := json.Unmarshal([]byte(data), &extra); err != nil {
+		return fmt.Errorf("failed to parse annotation %s of MyPod %s/%s: %w", ConversionDataAnnotation, src.Namespace, src.Name, err)
+	}
+	dst.Spec.Replicas = extra.Replicas
+	dst.Spec.Template = extra.Template
+	if extra.Status != nil {
+		dst.Status = *extra.Status
+	}
+	return nil
+}
+
+// ConvertFrom converts from the Hub version (v1beta1) to this version.
+func (dst *MyPod) ConvertFrom(srcRaw conversion.Hub) error {
+	src := srcRaw.(*v1beta1.MyPod)
+	src.ObjectMeta.DeepCopyInto(&dst.ObjectMeta)
+	dst.Spec = MyPodSpec{
+		Selector: src.Spec.Selector.DeepCopy(),
+	}
+	dst.Status = MyPodStatus{}
+
+	extra := conversionData{
+		Replicas: src.Spec.Replicas,
+		Template: src.Spec.Template,
+	}
+	if src.Status != (v1beta1.MyPodStatus{}) {
+		extra.Status = &src.Status
+	}
+	if extra == (conversionData{}) {
+		return nil
+	}
+
+	data, err := json.Marshal(extra)
+	if err != nil {
+		return err
+	}
+	if dst.Annotations == nil {
+		dst.Annotations = map[string]string{}
+	}
+	dst.Annotations[ConversionDataAnnotation] = string(data)
+	return nil
+}
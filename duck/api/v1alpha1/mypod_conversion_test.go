@@ -0,0 +1,122 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	fuzz "github.com/google/gofuzz"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"github.com/ArnobKumarSaha/k8s/api/v1beta1"
+)
+
+const fuzzIterations = 200
+
+// newFuzzer fills the types that do not survive a JSON round trip when filled
+// with random bytes, the way the API machinery fuzzers do.
+func newFuzzer(seed int64) *fuzz.Fuzzer {
+	return fuzz.New().
+		RandSource(rand.NewSource(seed)).
+		NilChance(0.5).
+		NumElements(0, 2).
+		Funcs(
+			func(t *metav1.Time, c fuzz.Continue) {
+				*t = metav1.Unix(c.Int63n(1<<32), 0)
+			},
+			func(f *metav1.FieldsV1, c fuzz.Continue) {
+				f.Raw = []byte(`{}`)
+			},
+			func(q *resource.Quantity, c fuzz.Continue) {
+				*q = *resource.NewQuantity(c.Int63n(1000), resource.DecimalSI)
+			},
+			func(i *intstr.IntOrString, c fuzz.Continue) {
+				if c.RandBool() {
+					*i = intstr.FromInt32(c.Int31())
+				} else {
+					*i = intstr.FromString(c.RandString())
+				}
+			},
+		)
+}
+
+func TestMyPodSpokeRoundTrip(t *testing.T) {
+	f := newFuzzer(1)
+	for i := 0; i < fuzzIterations; i++ {
+		var src MyPod
+		f.Fuzz(&src)
+		src.TypeMeta = metav1.TypeMeta{}
+		delete(src.Annotations, ConversionDataAnnotation)
+
+		var hub v1beta1.MyPod
+		if err := src.ConvertTo(&hub); err != nil {
+			t.Fatal(err)
+		}
+		var dst MyPod
+		if err := dst.ConvertFrom(&hub); err != nil {
+			t.Fatal(err)
+		}
+		if !apiequality.Semantic.DeepEqual(src, dst) {
+			t.Fatalf("v1alpha1 -> v1beta1 -> v1alpha1 is lossy (-want +got):\n%s", cmp.Diff(src, dst))
+		}
+	}
+}
+
+func TestMyPodHubRoundTrip(t *testing.T) {
+	f := newFuzzer(2)
+	for i := 0; i < fuzzIterations; i++ {
+		var src v1beta1.MyPod
+		f.Fuzz(&src)
+		src.TypeMeta = metav1.TypeMeta{}
+		delete(src.Annotations, ConversionDataAnnotation)
+
+		var spoke MyPod
+		if err := spoke.ConvertFrom(&src); err != nil {
+			t.Fatal(err)
+		}
+		var dst v1beta1.MyPod
+		if err := spoke.ConvertTo(&dst); err != nil {
+			t.Fatal(err)
+		}
+		if !apiequality.Semantic.DeepEqual(src, dst) {
+			t.Fatalf("v1beta1 -> v1alpha1 -> v1beta1 is lossy (-want +got):\n%s", cmp.Diff(src, dst))
+		}
+	}
+}
+
+func TestConvertFromDoesNotMutateHub(t *testing.T) {
+	replicas := int32(3)
+	hub := &v1beta1.MyPod{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Annotations: map[string]string{"owner": "me"}},
+		Spec:       v1beta1.MyPodSpec{Replicas: &replicas},
+	}
+	var spoke MyPod
+	if err := spoke.ConvertFrom(hub); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := spoke.Annotations[ConversionDataAnnotation]; !ok {
+		t.Fatalf("expected annotation %s on the v1alpha1 object", ConversionDataAnnotation)
+	}
+	if len(hub.Annotations) != 1 {
+		t.Fatalf("ConvertFrom changed the annotations of the hub: %v", hub.Annotations)
+	}
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	metav1validation "k8s.io/apimachinery/pkg/apis/meta/v1/validation"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+// log is for logging in this package.
+var mypodlog = logf.Log.WithName("mypod-resource")
+
+// The webhooks are set up in internal/webhook/v1alpha1, as the validating one
+// needs the other MyPods to check the selectors for overlaps.
+
+// +kubebuilder:webhook:path=/mutate-core-duck-dev-v1alpha1-mypod,mutating=true,failurePolicy=fail,sideEffects=None,groups=core.duck.dev,resources=mypods,verbs=create;update,versions=v1alpha1,name=mmypod.kb.io,admissionReviewVersions=v1
+
+// MyPodCustomDefaulter sets spec.selector from the labels of a MyPod that has none.
+type MyPodCustomDefaulter struct{}
+
+var _ webhook.CustomDefaulter = &MyPodCustomDefaulter{}
+
+// Default implements webhook.CustomDefaulter so a webhook will be registered for the type
+func (d *MyPodCustomDefaulter) Default(_ context.Context, obj runtime.Object) error {
+	mp, ok := obj.(*MyPod)
+	if !ok {
+		return fmt.Errorf("expected a MyPod object but got %T", obj)
+	}
+	mypodlog.Info("default", "name", mp.Name)
+
+	if mp.Spec.Selector == nil {
+		if matchLabels := defaultMatchLabels(mp.Labels); matchLabels != nil {
+			mp.Spec.Selector = &metav1.LabelSelector{MatchLabels: matchLabels}
+		}
+	}
+	return nil
+}
+
+// defaultMatchLabels picks the labels that identify the pods of an app. It returns
+// nil when the labels do not make that choice unambiguous, and leaves it to the
+// validating webhook to ask for an explicit selector.
+func defaultMatchLabels(in map[string]string) map[string]string {
+	const (
+		nameKey     = "app.kubernetes.io/name"
+		instanceKey = "app.kubernetes.io/instance"
+	)
+	name, hasName := in[nameKey]
+	instance, hasInstance := in[instanceKey]
+	switch {
+	case hasName && hasInstance:
+		return map[string]string{nameKey: name, instanceKey: instance}
+	case in["app"] != "":
+		return map[string]string{"app": in["app"]}
+	}
+	return nil
+}
+
+// ValidateSelector returns the errors of a selector of mp that is missing, empty
+// or invalid.
+func ValidateSelector(mp *MyPod, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	switch sel := mp.Spec.Selector; {
+	case sel == nil:
+		allErrs = append(allErrs, field.Required(fldPath, "selector can not be defaulted from the labels, it must be set explicitly"))
+	case len(sel.MatchLabels) == 0 && len(sel.MatchExpressions) == 0:
+		allErrs = append(allErrs, field.Invalid(fldPath, sel, "empty selector would select every pod in the namespace"))
+	default:
+		allErrs = append(allErrs, metav1validation.ValidateLabelSelector(sel, metav1validation.LabelSelectorValidationOptions{}, fldPath)...)
+	}
+	return allErrs
+}
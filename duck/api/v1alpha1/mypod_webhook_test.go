@@ -0,0 +1,68 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+func newMyPod(name string, labels map[string]string, sel *metav1.LabelSelector) *MyPod {
+	return &MyPod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: labels},
+		Spec:       MyPodSpec{Selector: sel},
+	}
+}
+
+func TestDefaultSelector(t *testing.T) {
+	g := NewWithT(t)
+	d := &MyPodCustomDefaulter{}
+
+	mp := newMyPod("web", map[string]string{"app": "web", "tier": "frontend"}, nil)
+	g.Expect(d.Default(context.TODO(), mp)).To(Succeed())
+	g.Expect(mp.Spec.Selector.MatchLabels).To(Equal(map[string]string{"app": "web"}))
+
+	mp = newMyPod("web", map[string]string{"team": "a", "tier": "frontend"}, nil)
+	g.Expect(d.Default(context.TODO(), mp)).To(Succeed())
+	g.Expect(mp.Spec.Selector).To(BeNil())
+
+	// A single label may well be shared with other apps, like team=a.
+	mp = newMyPod("web", map[string]string{"team": "a"}, nil)
+	g.Expect(d.Default(context.TODO(), mp)).To(Succeed())
+	g.Expect(mp.Spec.Selector).To(BeNil())
+
+	sel := &metav1.LabelSelector{MatchLabels: map[string]string{"role": "web"}}
+	mp = newMyPod("web", map[string]string{"app": "web"}, sel)
+	g.Expect(d.Default(context.TODO(), mp)).To(Succeed())
+	g.Expect(mp.Spec.Selector).To(Equal(sel))
+}
+
+func TestValidateSelector(t *testing.T) {
+	g := NewWithT(t)
+	fldPath := field.NewPath("spec", "selector")
+
+	g.Expect(ValidateSelector(newMyPod("web", nil, nil), fldPath)).To(HaveLen(1))
+	g.Expect(ValidateSelector(newMyPod("web", nil, &metav1.LabelSelector{}), fldPath)).To(HaveLen(1))
+	g.Expect(ValidateSelector(newMyPod("web", nil, &metav1.LabelSelector{
+		MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "app", Operator: metav1.LabelSelectorOpIn}},
+	}), fldPath)).NotTo(BeEmpty())
+	g.Expect(ValidateSelector(newMyPod("web", nil, &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}), fldPath)).To(BeEmpty())
+}
@@ -0,0 +1,167 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"fmt"
+
+	apps "k8s.io/api/apps/v1"
+	batch "k8s.io/api/batch/v1"
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// Hub marks this type as a conversion hub.
+func (*MyPod) Hub() {}
+
+func (dst *MyPod) Duckify(srcRaw runtime.Object) error {
+	switch src := srcRaw.(type) {
+	case *core.ReplicationController:
+		dst.TypeMeta = metav1.TypeMeta{
+			Kind:       "ReplicationController",
+			APIVersion: core.SchemeGroupVersion.String(),
+		}
+		dst.ObjectMeta = src.ObjectMeta
+		dst.Spec = MyPodSpec{
+			Selector: &metav1.LabelSelector{
+				MatchLabels: src.Spec.Selector,
+			},
+			Replicas: src.Spec.Replicas,
+			Template: src.Spec.Template,
+		}
+		dst.Status = MyPodStatus{
+			ObservedGeneration: src.Status.ObservedGeneration,
+			Replicas:           src.Status.Replicas,
+			ReadyReplicas:      src.Status.ReadyReplicas,
+			AvailableReplicas:  src.Status.AvailableReplicas,
+		}
+		return nil
+	case *apps.Deployment:
+		dst.TypeMeta = metav1.TypeMeta{
+			Kind:       "Deployment",
+			APIVersion: apps.SchemeGroupVersion.String(),
+		}
+		dst.ObjectMeta = src.ObjectMeta
+		dst.Spec = MyPodSpec{
+			Selector: src.Spec.Selector,
+			Replicas: src.Spec.Replicas,
+			Template: &src.Spec.Template,
+		}
+		dst.Status = MyPodStatus{
+			ObservedGeneration: src.Status.ObservedGeneration,
+			Replicas:           src.Status.Replicas,
+			ReadyReplicas:      src.Status.ReadyReplicas,
+			AvailableReplicas:  src.Status.AvailableReplicas,
+			UpdatedReplicas:    src.Status.UpdatedReplicas,
+		}
+		return nil
+	case *apps.StatefulSet:
+		dst.TypeMeta = metav1.TypeMeta{
+			Kind:       "StatefulSet",
+			APIVersion: apps.SchemeGroupVersion.String(),
+		}
+		dst.ObjectMeta = src.ObjectMeta
+		dst.Spec = MyPodSpec{
+			Selector: src.Spec.Selector,
+			Replicas: src.Spec.Replicas,
+			Template: &src.Spec.Template,
+		}
+		dst.Status = MyPodStatus{
+			ObservedGeneration: src.Status.ObservedGeneration,
+			Replicas:           src.Status.Replicas,
+			ReadyReplicas:      src.Status.ReadyReplicas,
+			AvailableReplicas:  src.Status.AvailableReplicas,
+			UpdatedReplicas:    src.Status.UpdatedReplicas,
+		}
+		return nil
+	case *apps.DaemonSet:
+		dst.TypeMeta = metav1.TypeMeta{
+			Kind:       "DaemonSet",
+			APIVersion: apps.SchemeGroupVersion.String(),
+		}
+		dst.ObjectMeta = src.ObjectMeta
+		dst.Spec = MyPodSpec{
+			Selector: src.Spec.Selector,
+			Template: &src.Spec.Template,
+		}
+		dst.Status = MyPodStatus{
+			ObservedGeneration: src.Status.ObservedGeneration,
+			Replicas:           src.Status.DesiredNumberScheduled,
+			ReadyReplicas:      src.Status.NumberReady,
+			AvailableReplicas:  src.Status.NumberAvailable,
+			UpdatedReplicas:    src.Status.UpdatedNumberScheduled,
+		}
+		return nil
+	case *batch.Job:
+		dst.TypeMeta = metav1.TypeMeta{
+			Kind:       "Job",
+			APIVersion: batch.SchemeGroupVersion.String(),
+		}
+		dst.ObjectMeta = src.ObjectMeta
+		dst.Spec = MyPodSpec{
+			Selector: src.Spec.Selector,
+			Replicas: src.Spec.Parallelism,
+			Template: &src.Spec.Template,
+		}
+		dst.Status = MyPodStatus{
+			Replicas: src.Status.Active,
+		}
+		if src.Status.Ready != nil {
+			dst.Status.ReadyReplicas = *src.Status.Ready
+		}
+		return nil
+	case *batch.CronJob:
+		dst.TypeMeta = metav1.TypeMeta{
+			Kind:       "CronJob",
+			APIVersion: batch.SchemeGroupVersion.String(),
+		}
+		dst.ObjectMeta = src.ObjectMeta
+		dst.Spec = MyPodSpec{
+			Selector: src.Spec.JobTemplate.Spec.Selector,
+			Template: &src.Spec.JobTemplate.Spec.Template,
+		}
+		dst.Status = MyPodStatus{
+			Replicas: int32(len(src.Status.Active)),
+		}
+		return nil
+	case *unstructured.Unstructured:
+		var obj runtime.Object
+		switch src.GroupVersionKind() {
+		case core.SchemeGroupVersion.WithKind("ReplicationController"):
+			obj = new(core.ReplicationController)
+		case apps.SchemeGroupVersion.WithKind("Deployment"):
+			obj = new(apps.Deployment)
+		case apps.SchemeGroupVersion.WithKind("StatefulSet"):
+			obj = new(apps.StatefulSet)
+		case apps.SchemeGroupVersion.WithKind("DaemonSet"):
+			obj = new(apps.DaemonSet)
+		case batch.SchemeGroupVersion.WithKind("Job"):
+			obj = new(batch.Job)
+		case batch.SchemeGroupVersion.WithKind("CronJob"):
+			obj = new(batch.CronJob)
+		default:
+			return fmt.Errorf("unknown src kind %v", src.GroupVersionKind())
+		}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(src.UnstructuredContent(), obj); err != nil {
+			return err
+		}
+		return dst.Duckify(obj)
+	}
+	return fmt.Errorf("unknown src type %T", srcRaw)
+}
@@ -0,0 +1,55 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package v1beta1
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	apps "k8s.io/api/apps/v1"
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestDuckifyUnstructuredDeployment(t *testing.T) {
+	g := NewWithT(t)
+
+	replicas := int32(3)
+	dep := &apps.Deployment{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "apps/v1", Kind: "Deployment"},
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "demo"},
+		Spec: apps.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+			Template: core.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "web"}},
+				Spec:       core.PodSpec{Containers: []core.Container{{Name: "web", Image: "nginx"}}},
+			},
+		},
+		Status: apps.DeploymentStatus{Replicas: 3, ReadyReplicas: 2},
+	}
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(dep)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	var mp MyPod
+	g.Expect(mp.Duckify(&unstructured.Unstructured{Object: content})).To(Succeed())
+	g.Expect(mp.Kind).To(Equal("Deployment"))
+	g.Expect(*mp.Spec.Replicas).To(Equal(int32(3)))
+	g.Expect(mp.Spec.Template.Spec.Containers[0].Image).To(Equal("nginx"))
+	g.Expect(mp.Status.ReadyReplicas).To(Equal(int32(2)))
+}
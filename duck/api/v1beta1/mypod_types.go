@@ -0,0 +1,92 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package v1beta1
+
+import (
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// MyPodSpec defines the desired state of MyPod
+type MyPodSpec struct {
+	Selector *metav1.LabelSelector `json:"selector"`
+
+	// Replicas is the desired number of pods of the underlying workload. It is not
+	// set for kinds without a replica count, like DaemonSet and CronJob.
+	// +optional
+	Replicas *int32 `json:"replicas,omitempty"`
+
+	// Template is the pod template of the underlying workload.
+	// +optional
+	// +kubebuilder:pruning:PreserveUnknownFields
+	// +kubebuilder:validation:Schemaless
+	// +kubebuilder:validation:Type=object
+	Template *core.PodTemplateSpec `json:"template,omitempty"`
+}
+
+// MyPodStatus defines the observed state of MyPod
+type MyPodStatus struct {
+	// ObservedGeneration is the generation of the underlying workload most recently
+	// observed by its controller.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Replicas is the number of pods currently created by the underlying workload.
+	// +optional
+	Replicas int32 `json:"replicas,omitempty"`
+
+	// ReadyReplicas is the number of those pods that are ready.
+	// +optional
+	ReadyReplicas int32 `json:"readyReplicas,omitempty"`
+
+	// AvailableReplicas is the number of those pods that have been ready for at
+	// least minReadySeconds.
+	// +optional
+	AvailableReplicas int32 `json:"availableReplicas,omitempty"`
+
+	// UpdatedReplicas is the number of those pods that run the current template.
+	// +optional
+	UpdatedReplicas int32 `json:"updatedReplicas,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:storageversion
+// +kubebuilder:printcolumn:name="Replicas",type=integer,JSONPath=`.spec.replicas`
+// +kubebuilder:printcolumn:name="Ready",type=integer,JSONPath=`.status.readyReplicas`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// MyPod is the Schema for the mypods API
+type MyPod struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   MyPodSpec   `json:"spec,omitempty"`
+	Status MyPodStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// MyPodList contains a list of MyPod
+type MyPodList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []MyPod `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&MyPod{}, &MyPodList{})
+}
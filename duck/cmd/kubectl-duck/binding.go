@@ -0,0 +1,94 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	bapi "go.bytebuilders.dev/catalog/api/v1alpha1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/discovery"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// toGeneric duckifies a binding read as an unstructured object. Kinds in the
+// scheme go through GenericBinding.Duckify, while binding kinds newer than the
+// catalog module share its fields and are converted field by field.
+func toGeneric(u *unstructured.Unstructured) (*bapi.GenericBinding, error) {
+	gvk := u.GroupVersionKind()
+	if gvk.GroupVersion() != bapi.GroupVersion || !strings.HasSuffix(gvk.Kind, "Binding") {
+		return nil, fmt.Errorf("unknown src kind %v", gvk)
+	}
+	var b bapi.GenericBinding
+	if obj, err := scheme.New(gvk); err == nil {
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.UnstructuredContent(), obj); err != nil {
+			return nil, err
+		}
+		if err := b.Duckify(obj); err == nil {
+			return &b, nil
+		}
+	}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.UnstructuredContent(), &b); err != nil {
+		return nil, err
+	}
+	b.TypeMeta = metav1.TypeMeta{APIVersion: bapi.GroupVersion.String(), Kind: gvk.Kind}
+	return &b, nil
+}
+
+func sourceName(b *bapi.GenericBinding) string {
+	if b.Spec.SourceRef.Namespace == "" || b.Spec.SourceRef.Namespace == b.Namespace {
+		return b.Spec.SourceRef.Name
+	}
+	return b.Spec.SourceRef.Namespace + "/" + b.Spec.SourceRef.Name
+}
+
+// listBindings discovers the binding kinds served by the cluster and duckifies
+// every object of those kinds.
+func listBindings(ctx context.Context, c client.Client, dc discovery.DiscoveryInterface, opts ...client.ListOption) ([]client.Object, error) {
+	resources, err := dc.ServerResourcesForGroupVersion(bapi.GroupVersion.String())
+	if apierrors.IsNotFound(err) {
+		return nil, fmt.Errorf("%s is not served by this cluster", bapi.GroupVersion)
+	} else if err != nil {
+		return nil, err
+	}
+
+	var out []client.Object
+	for _, r := range resources.APIResources {
+		if strings.Contains(r.Name, "/") || !strings.HasSuffix(r.Kind, "Binding") || r.Kind == "GenericBinding" {
+			continue
+		}
+		var list unstructured.UnstructuredList
+		list.SetGroupVersionKind(bapi.GroupVersion.WithKind(r.Kind + "List"))
+		if err := c.List(ctx, &list, opts...); err != nil {
+			return nil, err
+		}
+		for i := range list.Items {
+			b, err := toGeneric(&list.Items[i])
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, b)
+		}
+	}
+	sortObjects(out)
+	return out, nil
+}
@@ -0,0 +1,192 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// kubectl-duck prints the normalized duck view of the objects in a cluster.
+// Install it anywhere in PATH and run it as
+//
+//	kubectl duck mypods -n foo
+//	kubectl duck bindings -A -o wide
+//	kubectl duck owner web-7d4b9c-x2x9z -n foo
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
+	_ "k8s.io/client-go/plugin/pkg/client/auth"
+
+	bapi "go.bytebuilders.dev/catalog/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/discovery"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	corev1alpha1 "github.com/ArnobKumarSaha/k8s/api/v1alpha1"
+)
+
+var scheme = runtime.NewScheme()
+
+func init() {
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+
+	utilruntime.Must(corev1alpha1.AddToScheme(scheme))
+	utilruntime.Must(bapi.AddToScheme(scheme))
+}
+
+type options struct {
+	kubeconfig    string
+	context       string
+	namespace     string
+	allNamespaces bool
+	selector      string
+	output        string
+}
+
+func (o *options) bindFlags(fs *flag.FlagSet) {
+	fs.StringVar(&o.kubeconfig, "kubeconfig", "", "Path to the kubeconfig file. Defaults to $KUBECONFIG or ~/.kube/config.")
+	fs.StringVar(&o.context, "context", "", "The name of the kubeconfig context to use.")
+	fs.StringVar(&o.namespace, "namespace", "", "The namespace to list from. Defaults to the namespace of the current context.")
+	fs.StringVar(&o.namespace, "n", "", "Shorthand for --namespace.")
+	fs.BoolVar(&o.allNamespaces, "all-namespaces", false, "If set, list across all namespaces.")
+	fs.BoolVar(&o.allNamespaces, "A", false, "Shorthand for --all-namespaces.")
+	fs.StringVar(&o.selector, "selector", "", "Label selector to filter on, e.g. -l app=web.")
+	fs.StringVar(&o.selector, "l", "", "Shorthand for --selector.")
+	fs.StringVar(&o.output, "output", "", "Output format. One of: wide|yaml|json.")
+	fs.StringVar(&o.output, "o", "", "Shorthand for --output.")
+}
+
+// clientConfig returns the REST config and the namespace to use, which is empty
+// with --all-namespaces.
+func (o *options) clientConfig() (*rest.Config, string, error) {
+	loader := clientcmd.NewDefaultClientConfigLoadingRules()
+	loader.ExplicitPath = o.kubeconfig
+	cc := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loader, &clientcmd.ConfigOverrides{
+		CurrentContext: o.context,
+	})
+	cfg, err := cc.ClientConfig()
+	if err != nil {
+		return nil, "", err
+	}
+	ns := ""
+	if !o.allNamespaces {
+		if ns = o.namespace; ns == "" {
+			if ns, _, err = cc.Namespace(); err != nil {
+				return nil, "", err
+			}
+		}
+	}
+	return cfg, ns, nil
+}
+
+func usage(fs *flag.FlagSet) func() {
+	return func() {
+		fmt.Fprintf(fs.Output(), "Usage: kubectl duck <%s> [flags]\n", strings.Join(duckTypeNames(), "|"))
+		fmt.Fprintf(fs.Output(), "       kubectl duck owner <pod> [flags]\n\nFlags:\n")
+		fs.PrintDefaults()
+	}
+}
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+	var o options
+	fs := flag.NewFlagSet("kubectl-duck", flag.ContinueOnError)
+	fs.Usage = usage(fs)
+	o.bindFlags(fs)
+
+	// Flags may come before or after the duck type, as they do for kubectl get.
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("a duck type is required")
+	}
+	name := fs.Arg(0)
+	if err := fs.Parse(fs.Args()[1:]); err != nil {
+		return err
+	}
+	if name == "owner" {
+		if fs.NArg() == 0 {
+			return fmt.Errorf("a pod name is required")
+		}
+		pod := fs.Arg(0)
+		if err := fs.Parse(fs.Args()[1:]); err != nil {
+			return err
+		}
+		if fs.NArg() > 0 {
+			return fmt.Errorf("unexpected arguments %v", fs.Args())
+		}
+		return runOwner(context.Background(), &o, pod)
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments %v", fs.Args())
+	}
+
+	dt, err := lookupDuckType(name)
+	if err != nil {
+		return err
+	}
+	switch o.output {
+	case "", "wide", "yaml", "json":
+	default:
+		return fmt.Errorf("unsupported output format %q", o.output)
+	}
+	sel, err := labels.Parse(o.selector)
+	if err != nil {
+		return err
+	}
+
+	cfg, ns, err := o.clientConfig()
+	if err != nil {
+		return err
+	}
+
+	c, err := client.New(cfg, client.Options{Scheme: scheme})
+	if err != nil {
+		return err
+	}
+	dc, err := discovery.NewDiscoveryClientForConfig(cfg)
+	if err != nil {
+		return err
+	}
+	objs, err := dt.list(context.Background(), c, dc, client.InNamespace(ns), client.MatchingLabelsSelector{Selector: sel})
+	if err != nil {
+		return err
+	}
+	if len(objs) == 0 && (o.output == "" || o.output == "wide") {
+		if ns == "" {
+			fmt.Fprintln(os.Stderr, "No resources found")
+		} else {
+			fmt.Fprintf(os.Stderr, "No resources found in %s namespace.\n", ns)
+		}
+		return nil
+	}
+	return printObjects(os.Stdout, dt, objs, o.output, ns == "")
+}
@@ -0,0 +1,82 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/ArnobKumarSaha/k8s/internal/podowner"
+)
+
+// runOwner prints the owner chain of a pod and the MyPod view of its top owner.
+func runOwner(ctx context.Context, o *options, name string) error {
+	if o.allNamespaces {
+		return fmt.Errorf("owner needs the namespace of the pod, --all-namespaces is not supported")
+	}
+	switch o.output {
+	case "", "wide", "yaml", "json":
+	default:
+		return fmt.Errorf("unsupported output format %q", o.output)
+	}
+	cfg, ns, err := o.clientConfig()
+	if err != nil {
+		return err
+	}
+	c, err := client.New(cfg, client.Options{Scheme: scheme})
+	if err != nil {
+		return err
+	}
+
+	var pod corev1.Pod
+	if err := c.Get(ctx, client.ObjectKey{Namespace: ns, Name: name}, &pod); err != nil {
+		return err
+	}
+	res, err := podowner.New(c).Resolve(ctx, &pod)
+	if err != nil {
+		return err
+	}
+
+	chain := []string{"Pod/" + pod.Name}
+	for _, owner := range res.Owners {
+		s := owner.String()
+		if owner.Missing {
+			s += " (missing)"
+		}
+		chain = append(chain, s)
+	}
+	if o.output == "" || o.output == "wide" {
+		fmt.Println(strings.Join(chain, " -> "))
+	}
+	if res.MyPod == nil {
+		fmt.Fprintf(os.Stderr, "Pod %s/%s is not controlled by any existing workload.\n", ns, name)
+		return nil
+	}
+	if o.output == "" || o.output == "wide" {
+		fmt.Println()
+	}
+	dt, err := lookupDuckType("mypods")
+	if err != nil {
+		return err
+	}
+	return printObjects(os.Stdout, dt, []client.Object{res.MyPod}, o.output, false)
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/duration"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+// printObjects writes objs as a table, or as a v1 List for -o yaml and -o json.
+func printObjects(w io.Writer, dt *duckType, objs []client.Object, output string, withNamespace bool) error {
+	switch output {
+	case "yaml", "json":
+		list := metav1.List{
+			TypeMeta: metav1.TypeMeta{Kind: "List", APIVersion: "v1"},
+			Items:    make([]runtime.RawExtension, 0, len(objs)),
+		}
+		for _, obj := range objs {
+			list.Items = append(list.Items, runtime.RawExtension{Object: obj})
+		}
+		data, err := json.MarshalIndent(&list, "", "    ")
+		if err != nil {
+			return err
+		}
+		if output == "yaml" {
+			if data, err = yaml.JSONToYAML(data); err != nil {
+				return err
+			}
+		} else {
+			data = append(data, '\n')
+		}
+		_, err = w.Write(data)
+		return err
+	}
+
+	wide := output == "wide"
+	columns := dt.columns
+	if wide {
+		columns = append(columns[:len(columns):len(columns)], dt.wideColumns...)
+	}
+	if withNamespace {
+		columns = append([]string{"NAMESPACE"}, columns...)
+	}
+
+	tw := tabwriter.NewWriter(w, 10, 4, 3, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(columns, "\t"))
+	for _, obj := range objs {
+		cells := dt.cells(obj, wide)
+		if withNamespace {
+			cells = append([]string{obj.GetNamespace()}, cells...)
+		}
+		fmt.Fprintln(tw, strings.Join(cells, "\t"))
+	}
+	return tw.Flush()
+}
+
+// age formats the time since t the way kubectl prints the AGE column.
+func age(t metav1.Time) string {
+	if t.IsZero() {
+		return "<unknown>"
+	}
+	return duration.HumanDuration(time.Since(t.Time))
+}
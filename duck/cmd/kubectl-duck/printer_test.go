@@ -0,0 +1,100 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	apps "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	corev1alpha1 "github.com/ArnobKumarSaha/k8s/api/v1alpha1"
+)
+
+func TestPrintMyPodsWide(t *testing.T) {
+	g := NewWithT(t)
+
+	var mp corev1alpha1.MyPod
+	g.Expect(mp.Duckify(&apps.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "demo"},
+		Spec: apps.StatefulSetSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "db"}},
+		},
+	})).To(Succeed())
+
+	dt, err := lookupDuckType("mp")
+	g.Expect(err).NotTo(HaveOccurred())
+
+	var buf bytes.Buffer
+	g.Expect(printObjects(&buf, dt, []client.Object{&mp}, "wide", true)).To(Succeed())
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	g.Expect(lines).To(HaveLen(2))
+	g.Expect(strings.Fields(lines[0])).To(Equal([]string{"NAMESPACE", "NAME", "KIND", "AGE", "SELECTOR"}))
+	g.Expect(strings.Fields(lines[1])).To(Equal([]string{"demo", "db", "StatefulSet", "<unknown>", "app=db"}))
+}
+
+const singlestoreBinding = `
+apiVersion: catalog.appscode.com/v1alpha1
+kind: SinglestoreBinding
+metadata:
+  name: sdb-bind
+  namespace: app
+spec:
+  sourceRef:
+    name: sdb
+    namespace: db
+status:
+  phase: Current
+  secretRef:
+    name: sdb-bind-auth
+`
+
+func TestPrintBindings(t *testing.T) {
+	g := NewWithT(t)
+
+	var u unstructured.Unstructured
+	g.Expect(yaml.Unmarshal([]byte(singlestoreBinding), &u.Object)).To(Succeed())
+	b, err := toGeneric(&u)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(b.Kind).To(Equal("SinglestoreBinding"))
+
+	// A binding kind newer than the catalog module keeps its kind too.
+	u.SetKind("FutureDBBinding")
+	future, err := toGeneric(&u)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(future.Kind).To(Equal("FutureDBBinding"))
+	g.Expect(future.Status.SecretRef.Name).To(Equal("sdb-bind-auth"))
+
+	dt, err := lookupDuckType("bindings")
+	g.Expect(err).NotTo(HaveOccurred())
+
+	var buf bytes.Buffer
+	g.Expect(printObjects(&buf, dt, []client.Object{b}, "wide", false)).To(Succeed())
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	g.Expect(strings.Fields(lines[1])).To(Equal([]string{"sdb-bind", "SinglestoreBinding", "db/sdb", "Current", "<unknown>", "sdb-bind-auth"}))
+
+	buf.Reset()
+	g.Expect(printObjects(&buf, dt, []client.Object{b}, "yaml", false)).To(Succeed())
+	g.Expect(buf.String()).To(ContainSubstring("kind: SinglestoreBinding"))
+	g.Expect(buf.String()).To(ContainSubstring("kind: List"))
+}
@@ -0,0 +1,131 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	bapi "go.bytebuilders.dev/catalog/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/discovery"
+	"kmodules.xyz/client-go/client/duck"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	corev1alpha1 "github.com/ArnobKumarSaha/k8s/api/v1alpha1"
+	"github.com/ArnobKumarSaha/k8s/internal/controller"
+)
+
+// duckType describes how to list and print one duck type. Every listed object
+// still carries the kind of its underlying object, as set by Duckify.
+type duckType struct {
+	names   []string
+	columns []string
+	// wideColumns are appended to columns for -o wide.
+	wideColumns []string
+	list        func(ctx context.Context, c client.Client, dc discovery.DiscoveryInterface, opts ...client.ListOption) ([]client.Object, error)
+	cells       func(obj client.Object, wide bool) []string
+}
+
+var duckTypes = []duckType{
+	{
+		names:       []string{"mypods", "mypod", "mp"},
+		columns:     []string{"NAME", "KIND", "AGE"},
+		wideColumns: []string{"SELECTOR"},
+		list:        listMyPods,
+		cells: func(obj client.Object, wide bool) []string {
+			mp := obj.(*corev1alpha1.MyPod)
+			cells := []string{mp.Name, mp.Kind, age(mp.CreationTimestamp)}
+			if wide {
+				cells = append(cells, metav1.FormatLabelSelector(mp.Spec.Selector))
+			}
+			return cells
+		},
+	},
+	{
+		names:       []string{"bindings", "binding"},
+		columns:     []string{"NAME", "KIND", "SOURCE", "PHASE", "AGE"},
+		wideColumns: []string{"SECRET"},
+		list:        listBindings,
+		cells: func(obj client.Object, wide bool) []string {
+			b := obj.(*bapi.GenericBinding)
+			cells := []string{b.Name, b.Kind, sourceName(b), string(b.Status.Phase), age(b.CreationTimestamp)}
+			if wide {
+				secret := "<none>"
+				if b.Status.SecretRef != nil {
+					secret = b.Status.SecretRef.Name
+				}
+				cells = append(cells, secret)
+			}
+			return cells
+		},
+	},
+}
+
+func duckTypeNames() []string {
+	names := make([]string, 0, len(duckTypes))
+	for _, dt := range duckTypes {
+		names = append(names, dt.names[0])
+	}
+	return names
+}
+
+func lookupDuckType(name string) (*duckType, error) {
+	for i, dt := range duckTypes {
+		for _, n := range dt.names {
+			if n == name {
+				return &duckTypes[i], nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("unknown duck type %q, expected one of %v", name, duckTypeNames())
+}
+
+// listMyPods builds a duck Lister over the same underlying types the MyPod
+// controller watches.
+func listMyPods(ctx context.Context, c client.Client, _ discovery.DiscoveryInterface, opts ...client.ListOption) ([]client.Object, error) {
+	objs := controller.UnderlyingTypes()
+	lister, err := duck.NewLister().
+		ForDuckType(&corev1alpha1.MyPod{}).
+		WithUnderlyingTypes(objs[0], objs[1:]...).
+		Build(c)
+	if err != nil {
+		return nil, err
+	}
+
+	var list corev1alpha1.MyPodList
+	if err := lister.List(ctx, &list, opts...); err != nil {
+		return nil, err
+	}
+	out := make([]client.Object, 0, len(list.Items))
+	for i := range list.Items {
+		out = append(out, &list.Items[i])
+	}
+	sortObjects(out)
+	return out, nil
+}
+
+// sortObjects orders objects like kubectl get does, by namespace and then name.
+func sortObjects(objs []client.Object) {
+	sort.SliceStable(objs, func(i, j int) bool {
+		if objs[i].GetNamespace() != objs[j].GetNamespace() {
+			return objs[i].GetNamespace() < objs[j].GetNamespace()
+		}
+		return objs[i].GetName() < objs[j].GetName()
+	})
+}
@@ -20,6 +20,7 @@ import (
 	"crypto/tls"
 	"flag"
 	"os"
+	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
@@ -29,13 +30,20 @@ import (
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/config"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
 	corev1alpha1 "github.com/ArnobKumarSaha/k8s/api/v1alpha1"
+	corev1beta1 "github.com/ArnobKumarSaha/k8s/api/v1beta1"
+	"github.com/ArnobKumarSaha/k8s/internal/apiserver"
 	"github.com/ArnobKumarSaha/k8s/internal/controller"
+	"github.com/ArnobKumarSaha/k8s/internal/drift"
+	"github.com/ArnobKumarSaha/k8s/internal/monitor"
+	"github.com/ArnobKumarSaha/k8s/internal/pss"
+	webhookv1alpha1 "github.com/ArnobKumarSaha/k8s/internal/webhook/v1alpha1"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -48,6 +56,7 @@ func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
 
 	utilruntime.Must(corev1alpha1.AddToScheme(scheme))
+	utilruntime.Must(corev1beta1.AddToScheme(scheme))
 	// +kubebuilder:scaffold:scheme
 }
 
@@ -57,6 +66,15 @@ func main() {
 	var probeAddr string
 	var secureMetrics bool
 	var enableHTTP2 bool
+	var serveMyPodAPI bool
+	var apiserverAddr string
+	var apiserverCertDir string
+	var kubeContext string
+	var driftDir string
+	var driftInterval time.Duration
+	var pssLevel string
+	var pssEnforce bool
+	var generateMonitors bool
 	flag.StringVar(&metricsAddr, "metrics-bind-address", "0", "The address the metric endpoint binds to. "+
 		"Use the port :8080. If not set, it will be 0 in order to disable the metrics server")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
@@ -67,6 +85,27 @@ func main() {
 		"If set the metrics endpoint is served securely")
 	flag.BoolVar(&enableHTTP2, "enable-http2", false,
 		"If set, HTTP/2 will be enabled for the metrics and webhook servers")
+	flag.BoolVar(&serveMyPodAPI, "serve-mypod-api", false,
+		"If set, mypods.core.duck.dev is served read-only from an aggregated API server instead of the CRD. "+
+			"Requires the APIService from config/apiserver.")
+	flag.StringVar(&apiserverAddr, "apiserver-bind-address", ":8443",
+		"The address the aggregated API server binds to.")
+	flag.StringVar(&apiserverCertDir, "apiserver-cert-dir", "/tmp/k8s-apiserver/serving-certs",
+		"The directory holding tls.crt and tls.key of the aggregated API server.")
+	flag.StringVar(&kubeContext, "context", "",
+		"The name of the kubeconfig context to use. Defaults to the current context.")
+	flag.StringVar(&driftDir, "drift-manifests-dir", "",
+		"If set, the objects of the manifests in this directory are compared with the live ones every "+
+			"--drift-interval, and drifted fields are exported as metrics. The manager needs get permission on them.")
+	flag.DurationVar(&driftInterval, "drift-interval", 5*time.Minute, "How often to check for drift.")
+	flag.StringVar(&pssLevel, "pod-security-level", "",
+		"If set to baseline or restricted, pods, workloads, PetSets and Sidekicks are checked against this "+
+			"Pod Security Standard by a validating webhook, and violations are returned as warnings.")
+	flag.BoolVar(&pssEnforce, "pod-security-enforce", false,
+		"If set, the Pod Security webhook denies violating objects instead of warning.")
+	flag.BoolVar(&generateMonitors, "generate-monitors", false,
+		"If set, a Prometheus operator ServiceMonitor or PodMonitor is generated for every workload annotated with "+
+			monitor.Annotation+", owned by the workload. The ServiceMonitor and PodMonitor CRDs must be installed.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -95,7 +134,13 @@ func main() {
 		TLSOpts: tlsOpts,
 	})
 
-	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
+	restConfig, err := config.GetConfigWithContext(kubeContext)
+	if err != nil {
+		setupLog.Error(err, "unable to load kubeconfig")
+		os.Exit(1)
+	}
+
+	mgr, err := ctrl.NewManager(restConfig, ctrl.Options{
 		Scheme: scheme,
 		Metrics: metricsserver.Options{
 			BindAddress:   metricsAddr,
@@ -124,14 +169,55 @@ func main() {
 	}
 
 	if err = (&controller.MyPodReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:           mgr.GetClient(),
+		Scheme:           mgr.GetScheme(),
+		GenerateMonitors: generateMonitors,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "MyPod")
 		os.Exit(1)
 	}
+	// nolint:goconst
+	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
+		if err = webhookv1alpha1.SetupMyPodWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "MyPod")
+			os.Exit(1)
+		}
+		if err = (&corev1beta1.MyPod{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "MyPod")
+			os.Exit(1)
+		}
+		if pssLevel != "" {
+			if err = (&pss.Validator{Level: pss.Level(pssLevel), Enforce: pssEnforce}).SetupWebhookWithManager(mgr); err != nil {
+				setupLog.Error(err, "unable to create webhook", "webhook", "PodSecurity")
+				os.Exit(1)
+			}
+		}
+	}
 	// +kubebuilder:scaffold:builder
 
+	if serveMyPodAPI {
+		srv, err := apiserver.New(mgr, controller.UnderlyingTypes(), apiserver.Options{
+			BindAddress: apiserverAddr,
+			CertDir:     apiserverCertDir,
+			TLSOpts:     tlsOpts,
+		})
+		if err != nil {
+			setupLog.Error(err, "unable to create aggregated API server")
+			os.Exit(1)
+		}
+		if err := mgr.Add(srv); err != nil {
+			setupLog.Error(err, "unable to add aggregated API server")
+			os.Exit(1)
+		}
+	}
+
+	if driftDir != "" {
+		if err := mgr.Add(drift.New(mgr, drift.Options{Dir: driftDir, Interval: driftInterval})); err != nil {
+			setupLog.Error(err, "unable to add drift detector")
+			os.Exit(1)
+		}
+	}
+
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
 		setupLog.Error(err, "unable to set up health check")
 		os.Exit(1)
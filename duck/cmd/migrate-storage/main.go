@@ -0,0 +1,175 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// migrate-storage moves every object of a CRD to its current storage version.
+//
+// Changing the storage version of a CRD, like MyPod moving from v1alpha1 to
+// v1beta1, only affects objects written afterwards. Objects written before stay
+// encoded in the old version in etcd, and the old version stays listed in the
+// status.storedVersions of the CRD. The old version can only be removed from the
+// CRD once no object is stored in it anymore. The migration is:
+//
+//  1. Deploy the CRD with the new storage version and the manager that serves the
+//     conversion webhook.
+//  2. Run this command. It rewrites every object unchanged, which makes the
+//     kube-apiserver encode it in the new storage version, and then sets
+//     status.storedVersions to only the storage version.
+//  3. In a later release, stop serving the old version and drop it from the CRD.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+
+	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
+	_ "k8s.io/client-go/plugin/pkg/client/auth"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var scheme = runtime.NewScheme()
+
+func init() {
+	utilruntime.Must(apiextensionsv1.AddToScheme(scheme))
+}
+
+const pageSize = 500
+
+func main() {
+	var kubeconfig, kubecontext, crdName string
+	var dryRun bool
+	flag.StringVar(&kubeconfig, "kubeconfig", "", "Path to the kubeconfig file. Defaults to $KUBECONFIG or ~/.kube/config.")
+	flag.StringVar(&kubecontext, "context", "", "The name of the kubeconfig context to use.")
+	flag.StringVar(&crdName, "crd", "mypods.core.duck.dev", "The name of the CustomResourceDefinition to migrate.")
+	flag.BoolVar(&dryRun, "dry-run", false, "If set, only print the objects that would be rewritten.")
+	flag.Parse()
+
+	loader := clientcmd.NewDefaultClientConfigLoadingRules()
+	loader.ExplicitPath = kubeconfig
+	cfg, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loader, &clientcmd.ConfigOverrides{
+		CurrentContext: kubecontext,
+	}).ClientConfig()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+	c, err := client.New(cfg, client.Options{Scheme: scheme})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+	if err := migrate(context.Background(), c, crdName, dryRun); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+func migrate(ctx context.Context, c client.Client, crdName string, dryRun bool) error {
+	var crd apiextensionsv1.CustomResourceDefinition
+	if err := c.Get(ctx, client.ObjectKey{Name: crdName}, &crd); err != nil {
+		return err
+	}
+	storage := storageVersion(&crd)
+	if storage == "" {
+		return fmt.Errorf("%s has no storage version", crdName)
+	}
+	if slices.Equal(crd.Status.StoredVersions, []string{storage}) {
+		fmt.Printf("%s: every object is stored as %s, nothing to migrate\n", crdName, storage)
+		return nil
+	}
+	fmt.Printf("%s: storage version is %s, stored versions are %v\n", crdName, storage, crd.Status.StoredVersions)
+
+	var rewritten int
+	var next string
+	for {
+		var list unstructured.UnstructuredList
+		list.SetGroupVersionKind(schema.GroupVersionKind{Group: crd.Spec.Group, Version: storage, Kind: crd.Spec.Names.ListKind})
+		if err := c.List(ctx, &list, client.Limit(pageSize), client.Continue(next)); err != nil {
+			return err
+		}
+		for i := range list.Items {
+			obj := &list.Items[i]
+			if dryRun {
+				fmt.Printf("would rewrite %s/%s\n", obj.GetNamespace(), obj.GetName())
+				continue
+			}
+			if err := rewrite(ctx, c, obj); err != nil {
+				return fmt.Errorf("failed to rewrite %s/%s: %w", obj.GetNamespace(), obj.GetName(), err)
+			}
+			rewritten++
+		}
+		if next = list.GetContinue(); next == "" {
+			break
+		}
+	}
+	if dryRun {
+		fmt.Printf("would set status.storedVersions of %s to [%s]\n", crdName, storage)
+		return nil
+	}
+	fmt.Printf("rewrote %d objects as %s\n", rewritten, storage)
+
+	// Objects created while the old version was still the storage version could
+	// only show up before the rewrite, so it is now safe to forget that version.
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		if err := c.Get(ctx, client.ObjectKey{Name: crdName}, &crd); err != nil {
+			return err
+		}
+		crd.Status.StoredVersions = []string{storage}
+		if err := c.Status().Update(ctx, &crd); err != nil {
+			return err
+		}
+		fmt.Printf("set status.storedVersions of %s to [%s]\n", crdName, storage)
+		return nil
+	})
+}
+
+func storageVersion(crd *apiextensionsv1.CustomResourceDefinition) string {
+	for _, v := range crd.Spec.Versions {
+		if v.Storage {
+			return v.Name
+		}
+	}
+	return ""
+}
+
+// rewrite updates obj without changes, which makes the kube-apiserver encode it in
+// the storage version. Conflicts are retried with the latest copy of the object.
+func rewrite(ctx context.Context, c client.Client, obj *unstructured.Unstructured) error {
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		err := c.Update(ctx, obj)
+		if apierrors.IsConflict(err) {
+			if getErr := c.Get(ctx, client.ObjectKeyFromObject(obj), obj); getErr != nil {
+				return getErr
+			}
+		}
+		return err
+	})
+	if apierrors.IsNotFound(err) {
+		return nil // deleted in the meantime
+	}
+	return err
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// pss-check checks manifests offline against the baseline or restricted Pod
+// Security Standard, the same way the validating webhook of the manager does.
+// It exits with 1 when any object violates the standard.
+//
+//	go run ./cmd/pss-check sidekick.yaml
+//	kubectl get petsets -A -o yaml | go run ./cmd/pss-check --level baseline -
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+
+	"github.com/ArnobKumarSaha/k8s/internal/pss"
+)
+
+func main() {
+	var levelName string
+	flag.StringVar(&levelName, "level", string(pss.LevelRestricted), "The standard to check against, baseline or restricted.")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "Usage: pss-check [--level baseline|restricted] <file>... (- reads stdin)")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	level, err := pss.ParseLevel(levelName)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(2)
+	}
+
+	violating := 0
+	for _, name := range flag.Args() {
+		objs, err := readObjects(name)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "error:", err)
+			os.Exit(2)
+		}
+		for _, obj := range objs {
+			violations, ok, err := pss.EvaluateObject(level, obj)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "error:", err)
+				os.Exit(2)
+			}
+			if !ok || len(violations) == 0 {
+				continue
+			}
+			violating++
+			ref := obj.GetKind() + " " + obj.GetName()
+			if obj.GetNamespace() != "" {
+				ref = obj.GetKind() + " " + obj.GetNamespace() + "/" + obj.GetName()
+			}
+			fmt.Printf("%s violates PodSecurity %q:\n", ref, level)
+			for _, v := range violations {
+				fmt.Printf("  %s\n", v)
+			}
+		}
+	}
+	if violating > 0 {
+		os.Exit(1)
+	}
+}
+
+// readObjects reads every document of a YAML or JSON file, expanding Lists.
+func readObjects(name string) ([]*unstructured.Unstructured, error) {
+	var r io.Reader = os.Stdin
+	if name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	var out []*unstructured.Unstructured
+	dec := utilyaml.NewYAMLOrJSONDecoder(r, 4096)
+	for {
+		obj := &unstructured.Unstructured{}
+		if err := dec.Decode(&obj.Object); err != nil {
+			if errors.Is(err, io.EOF) {
+				return out, nil
+			}
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		if len(obj.Object) == 0 {
+			continue
+		}
+		if !obj.IsList() {
+			out = append(out, obj)
+			continue
+		}
+		list, err := obj.ToList()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		for i := range list.Items {
+			out = append(out, &list.Items[i])
+		}
+	}
+}
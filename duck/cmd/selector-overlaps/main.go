@@ -0,0 +1,228 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// selector-overlaps reports label selectors that can match the same pods where
+// that is a problem: two workloads fighting over pods, two PDBs blocking every
+// eviction of the pods they share, or two MyPods counting the same pods.
+//
+// Deployments, StatefulSets, PetSets, DaemonSets, PodDisruptionBudgets, Services
+// and MyPods are compared pairwise per namespace, symbolically with the
+// matchLabels and matchExpressions of their selectors and against the existing
+// pods. Overlaps found only symbolically are conflicts waiting for the first pod
+// with the right labels.
+//
+//	go run ./cmd/selector-overlaps -n demo
+//	go run ./cmd/selector-overlaps --all
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
+	_ "k8s.io/client-go/plugin/pkg/client/auth"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	corev1alpha1 "github.com/ArnobKumarSaha/k8s/api/v1alpha1"
+)
+
+var scheme = runtime.NewScheme()
+
+func init() {
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+
+	utilruntime.Must(corev1alpha1.AddToScheme(scheme))
+}
+
+func main() {
+	var kubeconfig, kubecontext, namespace string
+	var all bool
+	flag.StringVar(&kubeconfig, "kubeconfig", "", "Path to the kubeconfig file. Defaults to $KUBECONFIG or ~/.kube/config.")
+	flag.StringVar(&kubecontext, "context", "", "The name of the kubeconfig context to use.")
+	flag.StringVar(&namespace, "n", "", "Only check this namespace. Defaults to all namespaces.")
+	flag.BoolVar(&all, "all", false, "Also report expected overlaps, like a Service selecting the pods of a Deployment.")
+	flag.Parse()
+
+	loader := clientcmd.NewDefaultClientConfigLoadingRules()
+	loader.ExplicitPath = kubeconfig
+	cfg, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loader, &clientcmd.ConfigOverrides{
+		CurrentContext: kubecontext,
+	}).ClientConfig()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+	c, err := client.New(cfg, client.Options{Scheme: scheme})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+
+	ctx := context.Background()
+	subjects, err := loadSubjects(ctx, c, namespace)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+	var pods corev1.PodList
+	if err := c.List(ctx, &pods, client.InNamespace(namespace)); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+
+	overlaps := findOverlaps(subjects, pods.Items, all)
+	printOverlaps(os.Stdout, overlaps)
+	for _, o := range overlaps {
+		if o.Conflict() {
+			os.Exit(3)
+		}
+	}
+}
+
+// loadSubjects lists every selector-bearing object. Kinds whose API is not
+// installed, like PetSets or MyPods, are skipped.
+func loadSubjects(ctx context.Context, c client.Client, namespace string) ([]subject, error) {
+	var out []subject
+	add := func(kind, role string, obj metav1.Object, sel *metav1.LabelSelector) error {
+		// A nil selector matches nothing, on workloads it is rejected by validation.
+		if sel == nil {
+			return nil
+		}
+		s, err := metav1.LabelSelectorAsSelector(sel)
+		if err != nil {
+			return fmt.Errorf("%s %s/%s: %w", kind, obj.GetNamespace(), obj.GetName(), err)
+		}
+		out = append(out, subject{
+			Kind: kind, Namespace: obj.GetNamespace(), Name: obj.GetName(), Role: role,
+			Selector: s, Text: metav1.FormatLabelSelector(sel),
+		})
+		return nil
+	}
+	opts := client.InNamespace(namespace)
+
+	var deployments appsv1.DeploymentList
+	if err := c.List(ctx, &deployments, opts); err != nil {
+		return nil, err
+	}
+	for i := range deployments.Items {
+		if err := add("Deployment", roleWorkload, &deployments.Items[i], deployments.Items[i].Spec.Selector); err != nil {
+			return nil, err
+		}
+	}
+	var statefulSets appsv1.StatefulSetList
+	if err := c.List(ctx, &statefulSets, opts); err != nil {
+		return nil, err
+	}
+	for i := range statefulSets.Items {
+		if err := add("StatefulSet", roleWorkload, &statefulSets.Items[i], statefulSets.Items[i].Spec.Selector); err != nil {
+			return nil, err
+		}
+	}
+	var daemonSets appsv1.DaemonSetList
+	if err := c.List(ctx, &daemonSets, opts); err != nil {
+		return nil, err
+	}
+	for i := range daemonSets.Items {
+		if err := add("DaemonSet", roleWorkload, &daemonSets.Items[i], daemonSets.Items[i].Spec.Selector); err != nil {
+			return nil, err
+		}
+	}
+	var pdbs policyv1.PodDisruptionBudgetList
+	if err := c.List(ctx, &pdbs, opts); err != nil {
+		return nil, err
+	}
+	for i := range pdbs.Items {
+		if err := add("PodDisruptionBudget", rolePDB, &pdbs.Items[i], pdbs.Items[i].Spec.Selector); err != nil {
+			return nil, err
+		}
+	}
+	var services corev1.ServiceList
+	if err := c.List(ctx, &services, opts); err != nil {
+		return nil, err
+	}
+	for i := range services.Items {
+		svc := &services.Items[i]
+		// Without a selector the endpoints are managed by hand.
+		if len(svc.Spec.Selector) == 0 {
+			continue
+		}
+		if err := add("Service", roleService, svc, metav1.SetAsLabelSelector(labels.Set(svc.Spec.Selector))); err != nil {
+			return nil, err
+		}
+	}
+
+	// PetSets are not part of this module's scheme, read them as unstructured.
+	petSets := &unstructured.UnstructuredList{}
+	petSets.SetAPIVersion("apps.k8s.appscode.com/v1")
+	petSets.SetKind("PetSetList")
+	if err := c.List(ctx, petSets, opts); err != nil && !meta.IsNoMatchError(err) {
+		return nil, err
+	}
+	for i := range petSets.Items {
+		ps := &petSets.Items[i]
+		m, found, err := unstructured.NestedMap(ps.Object, "spec", "selector")
+		if err != nil || !found {
+			continue
+		}
+		var sel metav1.LabelSelector
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(m, &sel); err != nil {
+			return nil, fmt.Errorf("PetSet %s/%s: %w", ps.GetNamespace(), ps.GetName(), err)
+		}
+		if err := add("PetSet", roleWorkload, ps, &sel); err != nil {
+			return nil, err
+		}
+	}
+
+	var mypods corev1alpha1.MyPodList
+	if err := c.List(ctx, &mypods, opts); err != nil && !meta.IsNoMatchError(err) {
+		return nil, err
+	}
+	for i := range mypods.Items {
+		if err := add("MyPod", roleMyPod, &mypods.Items[i], mypods.Items[i].Spec.Selector); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+func printOverlaps(w io.Writer, overlaps []overlap) {
+	if len(overlaps) == 0 {
+		fmt.Fprintln(w, "No overlapping selectors found")
+		return
+	}
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAMESPACE\tOBJECT\tSELECTOR\tOBJECT\tSELECTOR\tEVIDENCE\tREASON")
+	for _, o := range overlaps {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", o.Namespace, o.A, o.A.Text, o.B, o.B.Text, o.evidence(), o.Reason)
+	}
+	tw.Flush()
+}
@@ -0,0 +1,154 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/ArnobKumarSaha/k8s/internal/selector"
+)
+
+// Roles of selector-bearing objects. Overlaps are only a conflict between
+// certain roles, a Service or PDB selecting the pods of a Deployment is the point.
+const (
+	roleWorkload = "workload"
+	rolePDB      = "pdb"
+	roleService  = "service"
+	roleMyPod    = "mypod"
+)
+
+// conflicts explains why two selectors of the given roles must not overlap.
+var conflicts = map[[2]string]string{
+	{roleWorkload, roleWorkload}: "both controllers manage the same pods and fight over them",
+	{rolePDB, rolePDB}:           "evicting a pod covered by more than one PDB always fails",
+	{roleMyPod, roleMyPod}:       "the same pods are counted by both MyPods",
+}
+
+// expected are overlaps reported only with --all.
+const expectedOverlap = "expected overlap"
+
+type subject struct {
+	Kind      string
+	Namespace string
+	Name      string
+	Role      string
+	Selector  labels.Selector
+	// Text is the selector as written on the object.
+	Text string
+}
+
+func (s subject) String() string {
+	return s.Kind + "/" + s.Name
+}
+
+type overlap struct {
+	Namespace string
+	A, B      subject
+	// SharedPods are the existing pods both select. Empty when the selectors
+	// can only match the same pods in theory.
+	SharedPods []string
+	Reason     string
+}
+
+func (o overlap) Conflict() bool {
+	return o.Reason != expectedOverlap
+}
+
+// findOverlaps returns every pair of subjects in the same namespace whose
+// selectors can match the same pod, the conflicting ones unless all is set.
+func findOverlaps(subjects []subject, pods []corev1.Pod, all bool) []overlap {
+	byNamespace := map[string][]subject{}
+	for _, s := range subjects {
+		byNamespace[s.Namespace] = append(byNamespace[s.Namespace], s)
+	}
+	podsByNamespace := map[string][]corev1.Pod{}
+	for _, p := range pods {
+		podsByNamespace[p.Namespace] = append(podsByNamespace[p.Namespace], p)
+	}
+
+	var out []overlap
+	for ns, subs := range byNamespace {
+		sort.Slice(subs, func(i, j int) bool { return subs[i].String() < subs[j].String() })
+		for i := range subs {
+			for j := i + 1; j < len(subs); j++ {
+				a, b := subs[i], subs[j]
+				reason, ok := conflictReason(a.Role, b.Role)
+				if !ok && !all {
+					continue
+				}
+				if !selector.Overlaps(a.Selector, b.Selector) {
+					continue
+				}
+				out = append(out, overlap{
+					Namespace:  ns,
+					A:          a,
+					B:          b,
+					SharedPods: sharedPods(podsByNamespace[ns], a.Selector, b.Selector),
+					Reason:     reason,
+				})
+			}
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Namespace != out[j].Namespace {
+			return out[i].Namespace < out[j].Namespace
+		}
+		if out[i].A.String() != out[j].A.String() {
+			return out[i].A.String() < out[j].A.String()
+		}
+		return out[i].B.String() < out[j].B.String()
+	})
+	return out
+}
+
+func conflictReason(a, b string) (string, bool) {
+	if reason, ok := conflicts[[2]string{a, b}]; ok {
+		return reason, true
+	}
+	if reason, ok := conflicts[[2]string{b, a}]; ok {
+		return reason, true
+	}
+	return expectedOverlap, false
+}
+
+func sharedPods(pods []corev1.Pod, a, b labels.Selector) []string {
+	var out []string
+	for _, p := range pods {
+		set := labels.Set(p.Labels)
+		if a.Matches(set) && b.Matches(set) {
+			out = append(out, p.Name)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// evidence describes how sure an overlap is, for the report.
+func (o overlap) evidence() string {
+	switch n := len(o.SharedPods); {
+	case n == 0:
+		return "selectors can match the same pods"
+	case n <= 3:
+		return fmt.Sprintf("%d pods match both: %v", n, o.SharedPods)
+	default:
+		return fmt.Sprintf("%d pods match both: %v ...", n, o.SharedPods[:3])
+	}
+}
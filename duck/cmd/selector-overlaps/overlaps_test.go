@@ -0,0 +1,79 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+func newSubject(t *testing.T, kind, ns, name, role, sel string) subject {
+	t.Helper()
+	s, err := labels.Parse(sel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return subject{Kind: kind, Namespace: ns, Name: name, Role: role, Selector: s, Text: sel}
+}
+
+func TestFindOverlaps(t *testing.T) {
+	subjects := []subject{
+		newSubject(t, "Deployment", "demo", "web", roleWorkload, "app=web"),
+		newSubject(t, "StatefulSet", "demo", "web-db", roleWorkload, "app=web,tier=db"),
+		newSubject(t, "Deployment", "demo", "api", roleWorkload, "app=api"),
+		newSubject(t, "Deployment", "other", "web", roleWorkload, "app=web"),
+		newSubject(t, "PodDisruptionBudget", "demo", "web", rolePDB, "app=web"),
+		newSubject(t, "PodDisruptionBudget", "demo", "all", rolePDB, ""),
+		newSubject(t, "Service", "demo", "web", roleService, "app=web"),
+	}
+	pods := []corev1.Pod{
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "demo", Name: "web-0", Labels: map[string]string{"app": "web", "tier": "db"}}},
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "demo", Name: "web-1", Labels: map[string]string{"app": "web"}}},
+	}
+
+	got := findOverlaps(subjects, pods, false)
+	want := []struct {
+		a, b   string
+		shared int
+	}{
+		{"Deployment/web", "StatefulSet/web-db", 1},
+		{"PodDisruptionBudget/all", "PodDisruptionBudget/web", 2},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("findOverlaps() = %v, want %d overlaps", got, len(want))
+	}
+	for i, w := range want {
+		if got[i].A.String() != w.a || got[i].B.String() != w.b || len(got[i].SharedPods) != w.shared || !got[i].Conflict() {
+			t.Errorf("findOverlaps()[%d] = %s %s %v, want %s %s with %d shared pods", i, got[i].A, got[i].B, got[i].SharedPods, w.a, w.b, w.shared)
+		}
+	}
+
+	var expected int
+	for _, o := range findOverlaps(subjects, pods, true) {
+		if !o.Conflict() {
+			expected++
+		}
+	}
+	// The Service overlaps both app=web workloads and both PDBs, the app=web PDB
+	// both app=web workloads and the empty PDB every workload in demo.
+	if expected != 9 {
+		t.Errorf("findOverlaps() with all reported %d expected overlaps, want 9", expected)
+	}
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+
+	corev1alpha1 "github.com/ArnobKumarSaha/k8s/api/v1alpha1"
+)
+
+// request holds the query parameters and content negotiation of a get/list/watch call.
+type request struct {
+	namespace       string
+	labelSelector   labels.Selector
+	fieldSelector   fields.Selector
+	watch           bool
+	resourceVersion string
+	timeout         time.Duration
+	table           bool
+	includeObject   metav1.IncludeObjectPolicy
+}
+
+func parseRequest(r *http.Request) (*request, error) {
+	q := r.URL.Query()
+	req := &request{
+		namespace:       r.PathValue("namespace"),
+		labelSelector:   labels.Everything(),
+		fieldSelector:   fields.Everything(),
+		resourceVersion: q.Get("resourceVersion"),
+		table:           wantsTable(r.Header.Get("Accept")),
+		includeObject:   metav1.IncludeMetadata,
+	}
+
+	var err error
+	if v := q.Get("labelSelector"); v != "" {
+		if req.labelSelector, err = labels.Parse(v); err != nil {
+			return nil, apierrors.NewBadRequest(err.Error())
+		}
+	}
+	if v := q.Get("fieldSelector"); v != "" {
+		if req.fieldSelector, err = fields.ParseSelector(v); err != nil {
+			return nil, apierrors.NewBadRequest(err.Error())
+		}
+	}
+	if v := q.Get("watch"); v != "" {
+		if req.watch, err = strconv.ParseBool(v); err != nil {
+			return nil, apierrors.NewBadRequest("invalid watch parameter: " + v)
+		}
+	}
+	if v := q.Get("timeoutSeconds"); v != "" {
+		sec, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || sec < 0 {
+			return nil, apierrors.NewBadRequest("invalid timeoutSeconds parameter: " + v)
+		}
+		req.timeout = time.Duration(sec) * time.Second
+	}
+	if v := q.Get("includeObject"); v != "" {
+		switch p := metav1.IncludeObjectPolicy(v); p {
+		case metav1.IncludeNone, metav1.IncludeMetadata, metav1.IncludeObject:
+			req.includeObject = p
+		default:
+			return nil, apierrors.NewBadRequest("invalid includeObject parameter: " + v)
+		}
+	}
+	return req, nil
+}
+
+// wantsTable reports whether the client, typically kubectl, asked for server side printing.
+func wantsTable(accept string) bool {
+	for _, part := range strings.Split(accept, ",") {
+		if strings.Contains(part, "as=Table") && strings.Contains(part, "g=meta.k8s.io") {
+			return true
+		}
+	}
+	return false
+}
+
+// matches applies the namespace, label and field selectors to a duck view. Watch
+// events are not filtered by anything else, and lists leave the field selector to it.
+func (req *request) matches(mp *corev1alpha1.MyPod) bool {
+	if req.namespace != "" && mp.Namespace != req.namespace {
+		return false
+	}
+	if !req.labelSelector.Matches(labels.Set(mp.Labels)) {
+		return false
+	}
+	return req.fieldSelector.Matches(fields.Set{
+		"metadata.name":      mp.Name,
+		"metadata.namespace": mp.Namespace,
+	})
+}
@@ -0,0 +1,425 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package apiserver serves core.duck.dev/v1alpha1 mypods as a read-only virtual
+// resource behind an APIService. Nothing is stored in etcd: every request is
+// answered from the duck cache over the informers of the underlying workloads.
+package apiserver
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"time"
+
+	core "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"sigs.k8s.io/controller-runtime/pkg/certwatcher"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	corev1alpha1 "github.com/ArnobKumarSaha/k8s/api/v1alpha1"
+	"github.com/ArnobKumarSaha/k8s/internal/duckcache"
+)
+
+var log = logf.Log.WithName("apiserver")
+
+const (
+	resourceName = "mypods"
+
+	authConfigMapNamespace = metav1.NamespaceSystem
+	authConfigMapName      = "extension-apiserver-authentication"
+	requestHeaderCAKey     = "requestheader-client-ca-file"
+	requestHeaderNamesKey  = "requestheader-allowed-names"
+)
+
+var groupResource = schema.GroupResource{Group: corev1alpha1.GroupVersion.Group, Resource: resourceName}
+
+// Options configures the aggregated API server.
+type Options struct {
+	// BindAddress is the host:port the server listens on. Defaults to :8443.
+	BindAddress string
+
+	// CertDir holds the serving certificate and key. Defaults to
+	// /tmp/k8s-apiserver/serving-certs.
+	CertDir string
+
+	// CertName and KeyName default to tls.crt and tls.key.
+	CertName string
+	KeyName  string
+
+	// TLSOpts is used to customize the TLS configuration of the server.
+	TLSOpts []func(*tls.Config)
+}
+
+func (o *Options) setDefaults() {
+	if o.BindAddress == "" {
+		o.BindAddress = ":8443"
+	}
+	if o.CertDir == "" {
+		o.CertDir = filepath.Join("/tmp", "k8s-apiserver", "serving-certs")
+	}
+	if o.CertName == "" {
+		o.CertName = "tls.crt"
+	}
+	if o.KeyName == "" {
+		o.KeyName = "tls.key"
+	}
+}
+
+// +kubebuilder:rbac:groups=apps,resources=deployments;statefulsets;daemonsets,verbs=get;list;watch
+
+// Server is a manager.Runnable serving the MyPod virtual resource.
+type Server struct {
+	opts        Options
+	mgr         manager.Manager
+	reader      client.Reader
+	rawObjs     []client.Object
+	informers   []syncedVersion
+	broadcaster *watch.Broadcaster
+}
+
+var (
+	_ manager.Runnable               = &Server{}
+	_ manager.LeaderElectionRunnable = &Server{}
+)
+
+// New returns a Server listing MyPods from the given underlying types.
+func New(mgr manager.Manager, rawObjs []client.Object, opts Options) (*Server, error) {
+	if len(rawObjs) == 0 {
+		return nil, fmt.Errorf("must provide underlying types for %s", groupResource)
+	}
+	opts.setDefaults()
+	return &Server{
+		opts:        opts,
+		mgr:         mgr,
+		rawObjs:     rawObjs,
+		broadcaster: watch.NewLongQueueBroadcaster(watchQueueLength, watch.DropIfChannelFull),
+	}, nil
+}
+
+// NeedLeaderElection returns false so that every replica behind the APIService serves requests.
+func (s *Server) NeedLeaderElection() bool {
+	return false
+}
+
+// Start registers the duck cache and the watch event handlers on the informers
+// of the underlying types, and serves until ctx is done.
+func (s *Server) Start(ctx context.Context) error {
+	defer s.broadcaster.Shutdown()
+
+	reader, err := duckcache.NewReader().
+		ForDuckType(&corev1alpha1.MyPod{}).
+		WithUnderlyingTypes(s.rawObjs[0], s.rawObjs[1:]...).
+		Build(ctx, s.mgr.GetCache(), s.mgr.GetScheme())
+	if err != nil {
+		return err
+	}
+	s.reader = reader
+	if err := s.watchUnderlyingTypes(ctx); err != nil {
+		return err
+	}
+
+	cfg, err := s.tlsConfig(ctx)
+	if err != nil {
+		return err
+	}
+	listener, err := tls.Listen("tcp", s.opts.BindAddress, cfg)
+	if err != nil {
+		return err
+	}
+
+	srv := &http.Server{
+		Handler:           s.handler(),
+		ReadHeaderTimeout: 30 * time.Second,
+		BaseContext: func(net.Listener) context.Context {
+			return ctx
+		},
+	}
+
+	idleConnsClosed := make(chan struct{})
+	go func() {
+		<-ctx.Done()
+		log.Info("Shutting down aggregated API server")
+
+		ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+		defer cancel()
+		if err := srv.Shutdown(ctx); err != nil {
+			log.Error(err, "error shutting down the HTTP server")
+		}
+		close(idleConnsClosed)
+	}()
+
+	log.Info("Serving aggregated API server", "address", s.opts.BindAddress, "resource", groupResource.String())
+	if err := srv.Serve(listener); err != nil && err != http.ErrServerClosed {
+		return err
+	}
+	<-idleConnsClosed
+	return nil
+}
+
+// tlsConfig serves the certificate from CertDir and only accepts clients presenting a
+// certificate signed by the kube-apiserver front-proxy CA, as published in the
+// extension-apiserver-authentication ConfigMap. Authorization has already been done
+// by the kube-apiserver before it proxies the request.
+func (s *Server) tlsConfig(ctx context.Context) (*tls.Config, error) {
+	cfg := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		NextProtos: []string{"h2"},
+	}
+	for _, op := range s.opts.TLSOpts {
+		op(cfg)
+	}
+
+	certWatcher, err := certwatcher.New(
+		filepath.Join(s.opts.CertDir, s.opts.CertName),
+		filepath.Join(s.opts.CertDir, s.opts.KeyName),
+	)
+	if err != nil {
+		return nil, err
+	}
+	cfg.GetCertificate = certWatcher.GetCertificate
+	go func() {
+		if err := certWatcher.Start(ctx); err != nil {
+			log.Error(err, "certificate watcher error")
+		}
+	}()
+
+	var cm core.ConfigMap
+	err = s.mgr.GetAPIReader().Get(ctx, client.ObjectKey{Namespace: authConfigMapNamespace, Name: authConfigMapName}, &cm)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s/%s: %w", authConfigMapNamespace, authConfigMapName, err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM([]byte(cm.Data[requestHeaderCAKey])) {
+		return nil, fmt.Errorf("no %s found in %s/%s", requestHeaderCAKey, authConfigMapNamespace, authConfigMapName)
+	}
+	var allowedNames []string
+	if v, ok := cm.Data[requestHeaderNamesKey]; ok && v != "" {
+		if err := json.Unmarshal([]byte(v), &allowedNames); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", requestHeaderNamesKey, err)
+		}
+	}
+
+	cfg.ClientCAs = pool
+	cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	cfg.VerifyPeerCertificate = func(_ [][]byte, chains [][]*x509.Certificate) error {
+		if len(allowedNames) == 0 {
+			return nil
+		}
+		for _, chain := range chains {
+			if len(chain) > 0 && slices.Contains(allowedNames, chain[0].Subject.CommonName) {
+				return nil
+			}
+		}
+		return fmt.Errorf("client certificate common name is not one of %v", allowedNames)
+	}
+	return cfg, nil
+}
+
+func (s *Server) handler() http.Handler {
+	gv := corev1alpha1.GroupVersion
+	prefix := "/apis/" + gv.Group + "/" + gv.Version
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /apis", s.serveGroupList)
+	mux.HandleFunc("GET /apis/"+gv.Group, s.serveGroup)
+	mux.HandleFunc("GET "+prefix, s.serveResourceList)
+	mux.HandleFunc("GET "+prefix+"/"+resourceName, s.serveList)
+	mux.HandleFunc("GET "+prefix+"/namespaces/{namespace}/"+resourceName, s.serveList)
+	mux.HandleFunc("GET "+prefix+"/namespaces/{namespace}/"+resourceName+"/{name}", s.serveGet)
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeError(w, apierrors.NewMethodNotSupported(groupResource, r.Method))
+			return
+		}
+		writeError(w, apierrors.NewNotFound(schema.GroupResource{}, r.URL.Path))
+	})
+	return mux
+}
+
+func (s *Server) serveGroupList(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, &metav1.APIGroupList{
+		TypeMeta: metav1.TypeMeta{Kind: "APIGroupList", APIVersion: "v1"},
+		Groups:   []metav1.APIGroup{apiGroup()},
+	})
+}
+
+func (s *Server) serveGroup(w http.ResponseWriter, _ *http.Request) {
+	g := apiGroup()
+	g.TypeMeta = metav1.TypeMeta{Kind: "APIGroup", APIVersion: "v1"}
+	writeJSON(w, http.StatusOK, &g)
+}
+
+func (s *Server) serveResourceList(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, &metav1.APIResourceList{
+		TypeMeta:     metav1.TypeMeta{Kind: "APIResourceList", APIVersion: "v1"},
+		GroupVersion: corev1alpha1.GroupVersion.String(),
+		APIResources: []metav1.APIResource{
+			{
+				Name:         resourceName,
+				SingularName: "mypod",
+				Namespaced:   true,
+				Kind:         "MyPod",
+				Verbs:        metav1.Verbs{"get", "list", "watch"},
+			},
+		},
+	})
+}
+
+func apiGroup() metav1.APIGroup {
+	gv := corev1alpha1.GroupVersion
+	version := metav1.GroupVersionForDiscovery{GroupVersion: gv.String(), Version: gv.Version}
+	return metav1.APIGroup{
+		Name:             gv.Group,
+		Versions:         []metav1.GroupVersionForDiscovery{version},
+		PreferredVersion: version,
+	}
+}
+
+func (s *Server) serveList(w http.ResponseWriter, r *http.Request) {
+	req, err := parseRequest(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if req.watch {
+		s.serveWatch(w, r, req)
+		return
+	}
+
+	items, rv, err := s.list(r.Context(), req)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if req.table {
+		table, err := toTable(items, req.includeObject)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		table.ResourceVersion = rv
+		writeJSON(w, http.StatusOK, table)
+		return
+	}
+
+	list := &corev1alpha1.MyPodList{
+		TypeMeta: metav1.TypeMeta{Kind: "MyPodList", APIVersion: corev1alpha1.GroupVersion.String()},
+		ListMeta: metav1.ListMeta{ResourceVersion: rv},
+		Items:    make([]corev1alpha1.MyPod, 0, len(items)),
+	}
+	for _, mp := range items {
+		list.Items = append(list.Items, *normalize(mp))
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+// list returns the duck views matching req and the resource version of the
+// list. The TypeMeta of every item still carries the underlying kind.
+func (s *Server) list(ctx context.Context, req *request) ([]*corev1alpha1.MyPod, string, error) {
+	opts := []client.ListOption{client.MatchingLabelsSelector{Selector: req.labelSelector}}
+	if req.namespace != "" {
+		opts = append(opts, client.InNamespace(req.namespace))
+	}
+
+	// Read before the list, so that the items are at least as new.
+	rv := s.listResourceVersion()
+	var list corev1alpha1.MyPodList
+	if err := s.reader.List(ctx, &list, opts...); err != nil {
+		return nil, "", apierrors.NewInternalError(err)
+	}
+	items := make([]*corev1alpha1.MyPod, 0, len(list.Items))
+	for i := range list.Items {
+		if req.matches(&list.Items[i]) {
+			items = append(items, &list.Items[i])
+		}
+	}
+	return items, rv, nil
+}
+
+func (s *Server) serveGet(w http.ResponseWriter, r *http.Request) {
+	req, err := parseRequest(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	key := client.ObjectKey{Namespace: r.PathValue("namespace"), Name: r.PathValue("name")}
+
+	mp, err := s.get(r.Context(), key)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if req.table {
+		table, err := toTable([]*corev1alpha1.MyPod{mp}, req.includeObject)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, table)
+		return
+	}
+	writeJSON(w, http.StatusOK, normalize(mp))
+}
+
+// get returns the duck view of the first underlying object named key.
+func (s *Server) get(ctx context.Context, key client.ObjectKey) (*corev1alpha1.MyPod, error) {
+	var mp corev1alpha1.MyPod
+	if err := s.reader.Get(ctx, key, &mp); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, apierrors.NewNotFound(groupResource, key.Name)
+		}
+		return nil, apierrors.NewInternalError(err)
+	}
+	return &mp, nil
+}
+
+// normalize returns a copy of mp reported as a MyPod instead of its underlying kind.
+func normalize(mp *corev1alpha1.MyPod) *corev1alpha1.MyPod {
+	out := mp.DeepCopy()
+	out.TypeMeta = metav1.TypeMeta{Kind: "MyPod", APIVersion: corev1alpha1.GroupVersion.String()}
+	return out
+}
+
+func writeJSON(w http.ResponseWriter, code int, obj runtime.Object) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(obj); err != nil {
+		log.Error(err, "failed to write response")
+	}
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	status, ok := err.(apierrors.APIStatus)
+	if !ok {
+		status = apierrors.NewInternalError(err)
+	}
+	st := status.Status()
+	st.TypeMeta = metav1.TypeMeta{Kind: "Status", APIVersion: "v1"}
+	writeJSON(w, int(st.Code), &st)
+}
@@ -0,0 +1,187 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	apps "k8s.io/api/apps/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	corev1alpha1 "github.com/ArnobKumarSaha/k8s/api/v1alpha1"
+)
+
+// stubReader serves duckified fixtures in place of the informer backed duck cache.
+type stubReader struct {
+	items []corev1alpha1.MyPod
+}
+
+func (r *stubReader) List(_ context.Context, list client.ObjectList, _ ...client.ListOption) error {
+	objs := make([]runtime.Object, 0, len(r.items))
+	for i := range r.items {
+		objs = append(objs, r.items[i].DeepCopy())
+	}
+	return apimeta.SetList(list, objs)
+}
+
+func (r *stubReader) Get(_ context.Context, key client.ObjectKey, obj client.Object, _ ...client.GetOption) error {
+	for i := range r.items {
+		if client.ObjectKeyFromObject(&r.items[i]) == key {
+			r.items[i].DeepCopyInto(obj.(*corev1alpha1.MyPod))
+			return nil
+		}
+	}
+	return apierrors.NewNotFound(schema.GroupResource{Group: "core.duck.dev", Resource: "mypods"}, key.Name)
+}
+
+// stubVersion is the resource version an informer has seen last.
+type stubVersion string
+
+func (v stubVersion) LastSyncResourceVersion() string { return string(v) }
+
+func newTestServer(t *testing.T) *httptest.Server {
+	var items []corev1alpha1.MyPod
+	for _, name := range []string{"web", "api"} {
+		var mp corev1alpha1.MyPod
+		err := mp.Duckify(&apps.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "demo", Labels: map[string]string{"app": name}},
+			Spec: apps.DeploymentSpec{
+				Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": name}},
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		items = append(items, mp)
+	}
+
+	s := &Server{
+		reader:      &stubReader{items: items},
+		rawObjs:     []client.Object{&apps.Deployment{}},
+		informers:   []syncedVersion{stubVersion("42"), stubVersion("9"), stubVersion("")},
+		broadcaster: watch.NewLongQueueBroadcaster(watchQueueLength, watch.DropIfChannelFull),
+	}
+	t.Cleanup(s.broadcaster.Shutdown)
+
+	srv := httptest.NewServer(s.handler())
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func get(t *testing.T, url, accept string, into any) int {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(into); err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode
+}
+
+const tableAccept = "application/json;as=Table;v=v1;g=meta.k8s.io,application/json"
+
+func TestDiscovery(t *testing.T) {
+	g := NewWithT(t)
+	srv := newTestServer(t)
+
+	var resources metav1.APIResourceList
+	g.Expect(get(t, srv.URL+"/apis/core.duck.dev/v1alpha1", "", &resources)).To(Equal(http.StatusOK))
+	g.Expect(resources.APIResources).To(HaveLen(1))
+	g.Expect(resources.APIResources[0].Verbs).To(ConsistOf("get", "list", "watch"))
+}
+
+func TestListAndGet(t *testing.T) {
+	g := NewWithT(t)
+	srv := newTestServer(t)
+
+	var list corev1alpha1.MyPodList
+	g.Expect(get(t, srv.URL+"/apis/core.duck.dev/v1alpha1/mypods?labelSelector=app%3Dweb", "", &list)).To(Equal(http.StatusOK))
+	g.Expect(list.Kind).To(Equal("MyPodList"))
+	g.Expect(list.Items).To(HaveLen(1))
+	g.Expect(list.Items[0].Kind).To(Equal("MyPod"))
+	g.Expect(list.ResourceVersion).To(Equal("42"))
+
+	var table metav1.Table
+	g.Expect(get(t, srv.URL+"/apis/core.duck.dev/v1alpha1/namespaces/demo/mypods", tableAccept, &table)).To(Equal(http.StatusOK))
+	g.Expect(table.Rows).To(HaveLen(2))
+	g.Expect(table.Rows[0].Cells[1]).To(Equal("Deployment"))
+	g.Expect(table.ResourceVersion).To(Equal("42"))
+
+	var mp corev1alpha1.MyPod
+	g.Expect(get(t, srv.URL+"/apis/core.duck.dev/v1alpha1/namespaces/demo/mypods/api", "", &mp)).To(Equal(http.StatusOK))
+	g.Expect(mp.Spec.Selector.MatchLabels).To(HaveKeyWithValue("app", "api"))
+
+	var status metav1.Status
+	g.Expect(get(t, srv.URL+"/apis/core.duck.dev/v1alpha1/namespaces/demo/mypods/missing", "", &status)).To(Equal(http.StatusNotFound))
+	g.Expect(status.Reason).To(Equal(metav1.StatusReasonNotFound))
+}
+
+func TestWatchSendsCurrentState(t *testing.T) {
+	g := NewWithT(t)
+	srv := newTestServer(t)
+
+	resp, err := http.Get(srv.URL + "/apis/core.duck.dev/v1alpha1/namespaces/demo/mypods?watch=true&timeoutSeconds=1")
+	g.Expect(err).NotTo(HaveOccurred())
+	defer resp.Body.Close()
+
+	var events []metav1.WatchEvent
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		var ev metav1.WatchEvent
+		g.Expect(json.Unmarshal(scanner.Bytes(), &ev)).To(Succeed())
+		events = append(events, ev)
+	}
+	g.Expect(events).To(HaveLen(2))
+	g.Expect(events[0].Type).To(Equal(string(watch.Added)))
+}
+
+// TestWatchFromListVersion is what kubectl get -w does: a watch from the version
+// of the list it printed must not send the listed objects again.
+func TestWatchFromListVersion(t *testing.T) {
+	g := NewWithT(t)
+	srv := newTestServer(t)
+
+	resp, err := http.Get(srv.URL + "/apis/core.duck.dev/v1alpha1/namespaces/demo/mypods?watch=true&timeoutSeconds=1&resourceVersion=42")
+	g.Expect(err).NotTo(HaveOccurred())
+	defer resp.Body.Close()
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		t.Errorf("unexpected watch event %s", scanner.Text())
+	}
+}
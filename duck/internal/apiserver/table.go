@@ -0,0 +1,91 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"encoding/json"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/duration"
+
+	corev1alpha1 "github.com/ArnobKumarSaha/k8s/api/v1alpha1"
+)
+
+var columns = []metav1.TableColumnDefinition{
+	{Name: "Name", Type: "string", Format: "name", Description: "Name of the underlying workload."},
+	{Name: "Kind", Type: "string", Description: "Kind of the underlying workload."},
+	{Name: "Selector", Type: "string", Priority: 1, Description: "Label selector of the pods."},
+	{Name: "Age", Type: "string", Description: "Time since the underlying workload was created."},
+}
+
+// toTable renders items for server side printing. The items must still carry the
+// kind of their underlying workload.
+func toTable(items []*corev1alpha1.MyPod, policy metav1.IncludeObjectPolicy) (*metav1.Table, error) {
+	table := &metav1.Table{
+		TypeMeta:          metav1.TypeMeta{Kind: "Table", APIVersion: metav1.SchemeGroupVersion.String()},
+		ColumnDefinitions: columns,
+		Rows:              make([]metav1.TableRow, 0, len(items)),
+	}
+	for _, mp := range items {
+		row, err := toTableRow(mp, policy)
+		if err != nil {
+			return nil, err
+		}
+		table.Rows = append(table.Rows, row)
+	}
+	return table, nil
+}
+
+func toTableRow(mp *corev1alpha1.MyPod, policy metav1.IncludeObjectPolicy) (metav1.TableRow, error) {
+	row := metav1.TableRow{
+		Cells: []interface{}{
+			mp.Name,
+			mp.Kind,
+			metav1.FormatLabelSelector(mp.Spec.Selector),
+			age(mp.CreationTimestamp),
+		},
+	}
+
+	var obj runtime.Object
+	switch policy {
+	case metav1.IncludeNone:
+		return row, nil
+	case metav1.IncludeObject:
+		obj = normalize(mp)
+	default:
+		obj = &metav1.PartialObjectMetadata{
+			TypeMeta:   metav1.TypeMeta{Kind: "PartialObjectMetadata", APIVersion: metav1.SchemeGroupVersion.String()},
+			ObjectMeta: *mp.ObjectMeta.DeepCopy(),
+		}
+	}
+	raw, err := json.Marshal(obj)
+	if err != nil {
+		return row, err
+	}
+	row.Object = runtime.RawExtension{Raw: raw}
+	return row, nil
+}
+
+// age formats the time since t the way kubectl prints the AGE column.
+func age(t metav1.Time) string {
+	if t.IsZero() {
+		return "<unknown>"
+	}
+	return duration.HumanDuration(time.Since(t.Time))
+}
@@ -0,0 +1,203 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	toolscache "k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	corev1alpha1 "github.com/ArnobKumarSaha/k8s/api/v1alpha1"
+)
+
+const watchQueueLength = 1000
+
+// watchUnderlyingTypes duckifies every informer event of the underlying types and
+// fans it out to the open watch requests.
+func (s *Server) watchUnderlyingTypes(ctx context.Context) error {
+	for _, rawObj := range s.rawObjs {
+		ll, err := s.mgr.GetScheme().New(rawObj.GetObjectKind().GroupVersionKind())
+		if err != nil {
+			return err
+		}
+		informer, err := s.mgr.GetCache().GetInformer(ctx, ll.(client.Object))
+		if err != nil {
+			return err
+		}
+		v, ok := informer.(syncedVersion)
+		if !ok {
+			return fmt.Errorf("informer of %s does not report its resource version", rawObj.GetObjectKind().GroupVersionKind())
+		}
+		s.informers = append(s.informers, v)
+		_, err = informer.AddEventHandler(toolscache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj interface{}) {
+				s.broadcast(watch.Added, obj)
+			},
+			UpdateFunc: func(oldObj, newObj interface{}) {
+				if resourceVersion(oldObj) == resourceVersion(newObj) {
+					return // periodic resync
+				}
+				s.broadcast(watch.Modified, newObj)
+			},
+			DeleteFunc: func(obj interface{}) {
+				if tombstone, ok := obj.(toolscache.DeletedFinalStateUnknown); ok {
+					obj = tombstone.Obj
+				}
+				s.broadcast(watch.Deleted, obj)
+			},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// syncedVersion is the part of a client-go SharedIndexInformer that reports the
+// resource version it has seen last.
+type syncedVersion interface {
+	LastSyncResourceVersion() string
+}
+
+// listResourceVersion returns the newest resource version seen by the informers
+// of the underlying types. The kinds share the resource versions of etcd, so a
+// list is at least as new as this, and a watch from it starts without the
+// ADDED events of the list.
+func (s *Server) listResourceVersion() string {
+	var newest uint64
+	for _, inf := range s.informers {
+		if v, err := strconv.ParseUint(inf.LastSyncResourceVersion(), 10, 64); err == nil && v > newest {
+			newest = v
+		}
+	}
+	if newest == 0 {
+		return ""
+	}
+	return strconv.FormatUint(newest, 10)
+}
+
+func resourceVersion(obj interface{}) string {
+	m, err := meta.Accessor(obj)
+	if err != nil {
+		return ""
+	}
+	return m.GetResourceVersion()
+}
+
+func (s *Server) broadcast(t watch.EventType, obj interface{}) {
+	raw, ok := obj.(runtime.Object)
+	if !ok {
+		return
+	}
+	var mp corev1alpha1.MyPod
+	if err := mp.Duckify(raw); err != nil {
+		log.Error(err, "failed to duckify watch event", "type", t)
+		return
+	}
+	if _, err := s.broadcaster.ActionOrDrop(t, &mp); err != nil {
+		log.Error(err, "failed to broadcast watch event", "type", t)
+	}
+}
+
+func (s *Server) serveWatch(w http.ResponseWriter, r *http.Request, req *request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, apierrors.NewInternalError(fmt.Errorf("streaming is not supported by the response writer")))
+		return
+	}
+
+	// Like the kube-apiserver, an empty or "0" resourceVersion starts the watch with
+	// synthetic ADDED events for the current state.
+	var initial []watch.Event
+	if req.resourceVersion == "" || req.resourceVersion == "0" {
+		items, _, err := s.list(r.Context(), req)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		initial = make([]watch.Event, 0, len(items))
+		for _, mp := range items {
+			initial = append(initial, watch.Event{Type: watch.Added, Object: mp})
+		}
+	}
+
+	wi, err := s.broadcaster.WatchWithPrefix(initial)
+	if err != nil {
+		writeError(w, apierrors.NewServiceUnavailable(err.Error()))
+		return
+	}
+	defer wi.Stop()
+
+	ctx := r.Context()
+	if req.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, req.timeout)
+		defer cancel()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Transfer-Encoding", "chunked")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	enc := json.NewEncoder(w)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev, ok := <-wi.ResultChan():
+			if !ok {
+				return
+			}
+			mp, ok := ev.Object.(*corev1alpha1.MyPod)
+			if !ok || !req.matches(mp) {
+				continue
+			}
+			out, err := req.encode(mp)
+			if err != nil {
+				log.Error(err, "failed to encode watch event")
+				return
+			}
+			if err := enc.Encode(&metav1.WatchEvent{Type: string(ev.Type), Object: runtime.RawExtension{Raw: out}}); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// encode serializes a watched object either as a single row table or as a MyPod.
+func (req *request) encode(mp *corev1alpha1.MyPod) ([]byte, error) {
+	if req.table {
+		table, err := toTable([]*corev1alpha1.MyPod{mp}, req.includeObject)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(table)
+	}
+	return json.Marshal(normalize(mp))
+}
@@ -19,6 +19,7 @@ package controller
 import (
 	"context"
 	corev1alpha1 "github.com/ArnobKumarSaha/k8s/api/v1alpha1"
+	"github.com/ArnobKumarSaha/k8s/internal/monitor"
 	apps "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -37,11 +38,17 @@ import (
 type MyPodReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
+
+	// GenerateMonitors makes the reconciler generate a ServiceMonitor or
+	// PodMonitor for the workloads annotated with monitor.Annotation.
+	GenerateMonitors bool
 }
 
 // +kubebuilder:rbac:groups=core.duck.dev,resources=mypods,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=core.duck.dev,resources=mypods/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=core.duck.dev,resources=mypods/finalizers,verbs=update
+// +kubebuilder:rbac:groups="",resources=services,verbs=get;list;watch
+// +kubebuilder:rbac:groups=monitoring.coreos.com,resources=servicemonitors;podmonitors,verbs=get;list;watch;create;update;patch;delete
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -78,6 +85,12 @@ func (r *MyPodReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl
 		return ctrl.Result{}, err
 	}
 
+	if r.GenerateMonitors {
+		if err := monitor.New(r.Client).Sync(ctx, &mypod, pods.Items); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
 	if req.Namespace == "kube-system" && req.Name == "coredns" {
 		r.tryPatch(&mypod)
 		return ctrl.Result{}, nil
@@ -143,18 +156,31 @@ func (r *MyPodReconciler) InjectClient(c client.Client) error {
 	return nil
 }
 
-// SetupWithManager sets up the controller with the Manager.
+// SetupWithManager sets up the controller with the Manager. With
+// GenerateMonitors, the ServiceMonitor and PodMonitor CRDs of the Prometheus
+// operator must be installed.
 func (r *MyPodReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return duck.ControllerManagedBy(mgr).
+	objs := UnderlyingTypes()
+	b := duck.ControllerManagedBy(mgr).
 		For(&corev1alpha1.MyPod{}).
-		WithUnderlyingTypes(
-			ObjectOf(apps.SchemeGroupVersion.WithKind("Deployment")),
-			ObjectOf(apps.SchemeGroupVersion.WithKind("StatefulSet")),
-			ObjectOf(apps.SchemeGroupVersion.WithKind("DaemonSet")),
-		).
-		Complete(func() duck.Reconciler {
-			return new(MyPodReconciler)
-		})
+		WithUnderlyingTypes(objs[0], objs[1:]...)
+	if r.GenerateMonitors {
+		for _, obj := range monitor.Objects() {
+			b = b.Owns(obj)
+		}
+	}
+	return b.Complete(func() duck.Reconciler {
+		return &MyPodReconciler{Scheme: r.Scheme, GenerateMonitors: r.GenerateMonitors}
+	})
+}
+
+// UnderlyingTypes returns the workload kinds that are duckified into MyPod.
+func UnderlyingTypes() []client.Object {
+	return []client.Object{
+		ObjectOf(apps.SchemeGroupVersion.WithKind("Deployment")),
+		ObjectOf(apps.SchemeGroupVersion.WithKind("StatefulSet")),
+		ObjectOf(apps.SchemeGroupVersion.WithKind("DaemonSet")),
+	}
 }
 
 func ObjectOf(gvk schema.GroupVersionKind) client.Object {
@@ -51,7 +51,11 @@ var _ = Describe("MyPod Controller", func() {
 						Name:      resourceName,
 						Namespace: "default",
 					},
-					// TODO(user): Specify other spec details if needed.
+					Spec: corev1alpha1.MyPodSpec{
+						Selector: &metav1.LabelSelector{
+							MatchLabels: map[string]string{"app": resourceName},
+						},
+					},
 				}
 				Expect(k8sClient.Create(ctx, resource)).To(Succeed())
 			}
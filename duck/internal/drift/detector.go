@@ -0,0 +1,220 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package drift
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var log = logf.Log.WithName("drift")
+
+// Object states counted by the duck_drift_objects metric.
+const (
+	stateInSync  = "in_sync"
+	stateDrifted = "drifted"
+	stateMissing = "missing"
+	stateError   = "error"
+)
+
+var (
+	driftedFields = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "duck_drift_fields",
+		Help: "Number of fields of a desired manifest whose live value differs.",
+	}, []string{"group", "kind", "namespace", "name"})
+	objects = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "duck_drift_objects",
+		Help: "Number of desired objects by state of the last check: in_sync, drifted, missing or error.",
+	}, []string{"state"})
+	lastCheck = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "duck_drift_last_check_timestamp_seconds",
+		Help: "Unix time the last drift check finished.",
+	})
+	checkErrors = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "duck_drift_check_errors_total",
+		Help: "Number of drift checks that could not read the manifests directory.",
+	})
+)
+
+func init() {
+	metrics.Registry.MustRegister(driftedFields, objects, lastCheck, checkErrors)
+}
+
+type Options struct {
+	// Dir holds the desired manifests. It is read again on every check.
+	Dir string
+
+	// Interval between checks. Defaults to 5m.
+	Interval time.Duration
+}
+
+func (o *Options) setDefaults() {
+	if o.Interval <= 0 {
+		o.Interval = 5 * time.Minute
+	}
+}
+
+// Detector is a manager.Runnable checking the desired manifests against the live
+// objects. It reads through the API reader, so the manager does not start an
+// informer for every kind in the directory, and it needs get permission on them.
+type Detector struct {
+	opts   Options
+	reader client.Reader
+	mapper meta.RESTMapper
+	scheme *runtime.Scheme
+}
+
+var (
+	_ manager.Runnable               = &Detector{}
+	_ manager.LeaderElectionRunnable = &Detector{}
+)
+
+func New(mgr manager.Manager, opts Options) *Detector {
+	opts.setDefaults()
+	return &Detector{
+		opts:   opts,
+		reader: mgr.GetAPIReader(),
+		mapper: mgr.GetRESTMapper(),
+		scheme: mgr.GetScheme(),
+	}
+}
+
+// NeedLeaderElection returns false so that every replica exports the metrics.
+func (d *Detector) NeedLeaderElection() bool {
+	return false
+}
+
+// Start checks once right away, then every Interval until ctx is done.
+func (d *Detector) Start(ctx context.Context) error {
+	log.Info("Starting drift detector", "dir", d.opts.Dir, "interval", d.opts.Interval)
+	ticker := time.NewTicker(d.opts.Interval)
+	defer ticker.Stop()
+	for {
+		d.check(ctx)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Result is the outcome of checking one desired object.
+type Result struct {
+	Desired *unstructured.Unstructured
+	State   string
+	Fields  []Field
+	Err     error
+}
+
+func (d *Detector) check(ctx context.Context) {
+	desired, err := LoadManifests(d.opts.Dir)
+	if err != nil {
+		checkErrors.Inc()
+		log.Error(err, "unable to load desired manifests", "dir", d.opts.Dir)
+		return
+	}
+
+	counts := map[string]int{stateInSync: 0, stateDrifted: 0, stateMissing: 0, stateError: 0}
+	// Objects removed from the directory must not keep reporting drift.
+	driftedFields.Reset()
+	for _, obj := range desired {
+		res := d.Check(ctx, obj)
+		counts[res.State]++
+
+		gvk := obj.GroupVersionKind()
+		keys := []interface{}{"kind", gvk.GroupKind().String(), "namespace", obj.GetNamespace(), "name", obj.GetName()}
+		switch res.State {
+		case stateDrifted:
+			driftedFields.WithLabelValues(gvk.Group, gvk.Kind, obj.GetNamespace(), obj.GetName()).Set(float64(len(res.Fields)))
+			log.Info("Object drifted from its manifest", append(keys, "fields", paths(res.Fields))...)
+			for _, f := range res.Fields {
+				log.V(1).Info("Drifted field", append(keys, "path", f.Path, "desired", f.Desired, "live", f.Live)...)
+			}
+		case stateMissing:
+			log.Info("Object of a manifest does not exist", keys...)
+		case stateError:
+			log.Error(res.Err, "unable to check object for drift", keys...)
+		default:
+			driftedFields.WithLabelValues(gvk.Group, gvk.Kind, obj.GetNamespace(), obj.GetName()).Set(0)
+		}
+	}
+	for state, n := range counts {
+		objects.WithLabelValues(state).Set(float64(n))
+	}
+	lastCheck.SetToCurrentTime()
+}
+
+// Check compares one desired object with its live version. Namespaced objects
+// without a namespace are looked up in default, like kubectl apply does.
+func (d *Detector) Check(ctx context.Context, desired *unstructured.Unstructured) Result {
+	res := Result{Desired: desired}
+	want, err := Normalize(d.scheme, desired)
+	if err != nil {
+		res.State, res.Err = stateError, err
+		return res
+	}
+
+	live := &unstructured.Unstructured{}
+	live.SetGroupVersionKind(desired.GroupVersionKind())
+	key := client.ObjectKeyFromObject(desired)
+	if key.Namespace == "" {
+		namespaced, err := apiutil.IsObjectNamespaced(live, d.scheme, d.mapper)
+		if err != nil {
+			res.State, res.Err = stateError, err
+			return res
+		}
+		if namespaced {
+			key.Namespace = metav1.NamespaceDefault
+		}
+	}
+	err = d.reader.Get(ctx, key, live)
+	switch {
+	case apierrors.IsNotFound(err):
+		res.State = stateMissing
+		return res
+	case err != nil:
+		res.State, res.Err = stateError, err
+		return res
+	}
+	got, err := Normalize(d.scheme, live)
+	if err != nil {
+		res.State, res.Err = stateError, err
+		return res
+	}
+
+	// The key already matched, do not report a missing namespace as drift.
+	unstructured.RemoveNestedField(want.Object, "metadata", "namespace")
+	res.Fields = Diff(want, got)
+	res.State = stateInSync
+	if len(res.Fields) > 0 {
+		res.State = stateDrifted
+	}
+	return res
+}
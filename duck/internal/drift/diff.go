@@ -0,0 +1,258 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package drift compares desired manifests from a directory with the live objects
+// and reports the fields that differ, on an interval and as Prometheus metrics.
+package drift
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// Field is one field whose live value differs from the desired one. Live is nil
+// when the field is missing from the live object.
+type Field struct {
+	Path    string
+	Desired interface{}
+	Live    interface{}
+}
+
+func (f Field) String() string {
+	return fmt.Sprintf("%s: desired %v, live %v", f.Path, f.Desired, f.Live)
+}
+
+// serverFields are populated by the API server and never part of desired state.
+var serverFields = [][]string{
+	{"status"},
+	{"metadata", "managedFields"},
+	{"metadata", "resourceVersion"},
+	{"metadata", "uid"},
+	{"metadata", "creationTimestamp"},
+	{"metadata", "generation"},
+	{"metadata", "selfLink"},
+	{"metadata", "deletionTimestamp"},
+	{"metadata", "deletionGracePeriodSeconds"},
+	{"metadata", "annotations", "kubectl.kubernetes.io/last-applied-configuration"},
+}
+
+// Normalize returns a copy of obj without server populated fields. Kinds known to
+// scheme are round tripped through their Go type, so that both sides spell
+// quantities and durations the same way. Only the fields obj sets are kept, the
+// zero values the Go type adds are dropped.
+//
+// Nothing is defaulted: the server defaults, like strategy or
+// terminationMessagePath, are absent from a manifest and Diff only compares what
+// the manifest sets, the way kubectl apply does.
+func Normalize(scheme *runtime.Scheme, obj *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	out := obj.DeepCopy()
+	if typed, err := scheme.New(obj.GroupVersionKind()); err == nil {
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, typed); err != nil {
+			return nil, err
+		}
+		content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(typed)
+		if err != nil {
+			return nil, err
+		}
+		out.Object = prune(content, obj.Object).(map[string]interface{})
+		out.SetGroupVersionKind(obj.GroupVersionKind())
+	}
+	for _, path := range serverFields {
+		unstructured.RemoveNestedField(out.Object, path...)
+	}
+	return out, nil
+}
+
+// prune drops the fields of v that orig does not have. The round trip keeps the
+// order of list items, so they are matched by index.
+func prune(v, orig interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		o, ok := orig.(map[string]interface{})
+		if !ok {
+			return v
+		}
+		for k, item := range t {
+			ov, found := o[k]
+			if !found {
+				delete(t, k)
+				continue
+			}
+			t[k] = prune(item, ov)
+		}
+	case []interface{}:
+		o, ok := orig.([]interface{})
+		if !ok || len(o) != len(t) {
+			return v
+		}
+		for i := range t {
+			t[i] = prune(t[i], o[i])
+		}
+	}
+	return v
+}
+
+// Diff returns the fields set in desired whose live value differs. Fields only
+// present in live are not drift, they are defaulted by the server or owned by
+// someone else. A zero desired value matches a missing live field, since a round
+// trip through the Go type cannot tell them apart.
+func Diff(desired, live *unstructured.Unstructured) []Field {
+	var out []Field
+	diffValue("", desired.Object, live.Object, true, &out)
+	sort.Slice(out, func(i, j int) bool { return out[i].Path < out[j].Path })
+	return out
+}
+
+func diffValue(path string, desired, live interface{}, found bool, out *[]Field) {
+	if !found {
+		if !isZero(desired) {
+			*out = append(*out, Field{Path: path, Desired: desired})
+		}
+		return
+	}
+	switch d := desired.(type) {
+	case map[string]interface{}:
+		l, ok := live.(map[string]interface{})
+		if !ok {
+			*out = append(*out, Field{Path: path, Desired: desired, Live: live})
+			return
+		}
+		for k, dv := range d {
+			lv, found := l[k]
+			diffValue(path+"."+k, dv, lv, found, out)
+		}
+	case []interface{}:
+		l, ok := live.([]interface{})
+		if !ok {
+			*out = append(*out, Field{Path: path, Desired: desired, Live: live})
+			return
+		}
+		diffList(path, d, l, out)
+	default:
+		if !equalScalar(desired, live) {
+			*out = append(*out, Field{Path: path, Desired: desired, Live: live})
+		}
+	}
+}
+
+// diffList matches items by name when every item has one, like containers, env
+// and ports, and by index otherwise.
+func diffList(path string, desired, live []interface{}, out *[]Field) {
+	if names, ok := itemNames(desired); ok {
+		if liveNames, ok := itemNames(live); ok {
+			byName := make(map[string]interface{}, len(live))
+			for i, name := range liveNames {
+				byName[name] = live[i]
+			}
+			for i, name := range names {
+				lv, found := byName[name]
+				diffValue(fmt.Sprintf("%s[name=%s]", path, name), desired[i], lv, found, out)
+			}
+			return
+		}
+	}
+	if len(desired) != len(live) {
+		*out = append(*out, Field{Path: path, Desired: desired, Live: live})
+		return
+	}
+	for i := range desired {
+		diffValue(fmt.Sprintf("%s[%d]", path, i), desired[i], live[i], true, out)
+	}
+}
+
+func itemNames(items []interface{}) ([]string, bool) {
+	names := make([]string, 0, len(items))
+	for _, item := range items {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		name, ok := m["name"].(string)
+		if !ok {
+			return nil, false
+		}
+		names = append(names, name)
+	}
+	return names, len(names) > 0
+}
+
+func equalScalar(desired, live interface{}) bool {
+	if reflect.DeepEqual(desired, live) {
+		return true
+	}
+	if live == nil {
+		return isZero(desired)
+	}
+	// JSON numbers decode as int64 or float64 depending on the side.
+	if df, ok := toFloat(desired); ok {
+		if lf, ok := toFloat(live); ok {
+			return df == lf
+		}
+	}
+	return false
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int64:
+		return float64(n), true
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+func isZero(v interface{}) bool {
+	switch t := v.(type) {
+	case nil:
+		return true
+	case map[string]interface{}:
+		for _, item := range t {
+			if !isZero(item) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		return len(t) == 0
+	case string:
+		return t == ""
+	case bool:
+		return !t
+	}
+	if f, ok := toFloat(v); ok {
+		return f == 0
+	}
+	return false
+}
+
+// paths joins the paths of fields for logging.
+func paths(fields []Field) string {
+	out := make([]string, 0, len(fields))
+	for _, f := range fields {
+		out = append(out, f.Path)
+	}
+	return strings.Join(out, ", ")
+}
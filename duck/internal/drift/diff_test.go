@@ -0,0 +1,277 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package drift
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/yaml"
+)
+
+const desiredDeployment = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: default
+  labels:
+    app: web
+spec:
+  selector:
+    matchLabels:
+      app: web
+  template:
+    metadata:
+      labels:
+        app: web
+    spec:
+      containers:
+      - name: nginx
+        image: nginx:1.27
+        ports:
+        - containerPort: 80
+          name: http
+        resources:
+          requests:
+            cpu: 1000m
+`
+
+const liveDeployment = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: default
+  uid: 3a1f
+  resourceVersion: "812"
+  generation: 4
+  creationTimestamp: "2024-10-08T14:55:23Z"
+  labels:
+    app: web
+  managedFields:
+  - manager: kubectl
+    operation: Update
+spec:
+  replicas: 1
+  selector:
+    matchLabels:
+      app: web
+  template:
+    metadata:
+      labels:
+        app: web
+    spec:
+      containers:
+      - name: sidecar
+        image: busybox
+      - name: nginx
+        image: nginx:1.26
+        imagePullPolicy: IfNotPresent
+        ports:
+        - containerPort: 80
+          name: http
+          protocol: TCP
+        resources:
+          requests:
+            cpu: "1"
+status:
+  replicas: 1
+`
+
+func decode(t *testing.T, s string) *unstructured.Unstructured {
+	t.Helper()
+	obj := &unstructured.Unstructured{}
+	if err := yaml.Unmarshal([]byte(s), &obj.Object); err != nil {
+		t.Fatal(err)
+	}
+	return obj
+}
+
+func TestDiff(t *testing.T) {
+	desired, err := Normalize(clientgoscheme.Scheme, decode(t, desiredDeployment))
+	if err != nil {
+		t.Fatal(err)
+	}
+	live, err := Normalize(clientgoscheme.Scheme, decode(t, liveDeployment))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := Diff(desired, live)
+	want := []Field{{
+		Path:    ".spec.template.spec.containers[name=nginx].image",
+		Desired: "nginx:1.27",
+		Live:    "nginx:1.26",
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Diff() = %v, want %v", got, want)
+	}
+
+	if fields := Diff(live, live); len(fields) != 0 {
+		t.Errorf("Diff() of an object with itself = %v, want none", fields)
+	}
+	if _, found, _ := unstructured.NestedFieldNoCopy(live.Object, "status"); found {
+		t.Error("Normalize() kept status")
+	}
+}
+
+// defaultedDeployment is desiredDeployment as the API server returns it, with
+// every default filled in.
+const defaultedDeployment = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: default
+  uid: 3a1f
+  resourceVersion: "901"
+  generation: 1
+  labels:
+    app: web
+spec:
+  progressDeadlineSeconds: 600
+  replicas: 1
+  revisionHistoryLimit: 10
+  selector:
+    matchLabels:
+      app: web
+  strategy:
+    type: RollingUpdate
+    rollingUpdate:
+      maxSurge: 25%
+      maxUnavailable: 25%
+  template:
+    metadata:
+      creationTimestamp: null
+      labels:
+        app: web
+    spec:
+      containers:
+      - name: nginx
+        image: nginx:1.27
+        imagePullPolicy: IfNotPresent
+        ports:
+        - containerPort: 80
+          name: http
+          protocol: TCP
+        resources:
+          requests:
+            cpu: "1"
+        terminationMessagePath: /dev/termination-log
+        terminationMessagePolicy: File
+      dnsPolicy: ClusterFirst
+      restartPolicy: Always
+      schedulerName: default-scheduler
+      securityContext: {}
+      terminationGracePeriodSeconds: 30
+status:
+  observedGeneration: 1
+`
+
+func TestDiffDefaulted(t *testing.T) {
+	desired, err := Normalize(clientgoscheme.Scheme, decode(t, desiredDeployment))
+	if err != nil {
+		t.Fatal(err)
+	}
+	live, err := Normalize(clientgoscheme.Scheme, decode(t, defaultedDeployment))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fields := Diff(desired, live); len(fields) != 0 {
+		t.Errorf("Diff() against the defaulted live object = %v, want none", fields)
+	}
+	for _, path := range [][]string{{"spec", "strategy"}, {"spec", "replicas"}, {"spec", "template", "spec", "dnsPolicy"}} {
+		if _, found, _ := unstructured.NestedFieldNoCopy(desired.Object, path...); found {
+			t.Errorf("Normalize() added %v, which the manifest does not set", path)
+		}
+	}
+
+	// A defaulted field the manifest sets is compared.
+	manifest := decode(t, desiredDeployment)
+	if err := unstructured.SetNestedField(manifest.Object, int64(3), "spec", "revisionHistoryLimit"); err != nil {
+		t.Fatal(err)
+	}
+	if desired, err = Normalize(clientgoscheme.Scheme, manifest); err != nil {
+		t.Fatal(err)
+	}
+	want := []Field{{Path: ".spec.revisionHistoryLimit", Desired: int64(3), Live: int64(10)}}
+	if got := Diff(desired, live); !reflect.DeepEqual(got, want) {
+		t.Errorf("Diff() = %v, want %v", got, want)
+	}
+}
+
+func TestDiffMissingAndLists(t *testing.T) {
+	desired := decode(t, `spec:
+  args: [a, b]
+  replicas: 3
+  paused: false
+  extra:
+    key: value
+`)
+	live := decode(t, `spec:
+  args: [a]
+  replicas: 2
+`)
+	got := Diff(desired, live)
+	want := []string{".spec.args", ".spec.extra", ".spec.replicas"}
+	if len(got) != len(want) {
+		t.Fatalf("Diff() = %v, want paths %v", got, want)
+	}
+	for i := range want {
+		if got[i].Path != want[i] {
+			t.Errorf("Diff()[%d].Path = %s, want %s", i, got[i].Path, want[i])
+		}
+	}
+}
+
+func TestLoadManifests(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"deploy.yaml": desiredDeployment + "---\n" + `apiVersion: v1
+kind: List
+items:
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: a
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: b
+`,
+		"notes.txt": "not a manifest",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	objs, err := LoadManifests(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, obj := range objs {
+		names = append(names, obj.GetKind()+"/"+obj.GetName())
+	}
+	if want := []string{"Deployment/web", "ConfigMap/a", "ConfigMap/b"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("LoadManifests() = %v, want %v", names, want)
+	}
+}
@@ -0,0 +1,357 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package duckcache keeps already duckified objects in memory so that repeated
+// Get/List calls for a duck type do not redo the conversion from the underlying
+// types. Entries are fed and invalidated by the informers of the underlying types.
+package duckcache
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	toolscache "k8s.io/client-go/tools/cache"
+	"kmodules.xyz/client-go/client/duck"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+const listType = "List"
+
+// entry holds the latest underlying object seen by the informer and its duck view.
+// duckObj is computed lazily and dropped whenever the informer reports a new resourceVersion.
+type entry struct {
+	raw     runtime.Object
+	rv      string
+	duckObj duck.Object
+}
+
+type store struct {
+	gvk     schema.GroupVersionKind
+	reg     toolscache.ResourceEventHandlerRegistration
+	entries map[types.NamespacedName]*entry
+}
+
+type cachedReader struct {
+	c       cache.Cache
+	scheme  *runtime.Scheme
+	duckGVK schema.GroupVersionKind
+
+	mu     sync.RWMutex
+	stores []*store
+}
+
+var _ client.Reader = &cachedReader{}
+
+type ReaderBuilder struct {
+	duckObj duck.Object
+	rawObjs []client.Object
+}
+
+func NewReader() *ReaderBuilder {
+	return &ReaderBuilder{}
+}
+
+func (b *ReaderBuilder) ForDuckType(obj duck.Object) *ReaderBuilder {
+	b.duckObj = obj
+	return b
+}
+
+func (b *ReaderBuilder) WithUnderlyingTypes(obj client.Object, rest ...client.Object) *ReaderBuilder {
+	b.rawObjs = make([]client.Object, 0, len(rest)+1)
+	b.rawObjs = append(b.rawObjs, obj)
+	b.rawObjs = append(b.rawObjs, rest...)
+	return b
+}
+
+// Build registers event handlers on the informers of every underlying type and returns
+// a client.Reader serving the duck type from memory. Requests for any other type are
+// passed through to c.
+func (b *ReaderBuilder) Build(ctx context.Context, c cache.Cache, scheme *runtime.Scheme) (client.Reader, error) {
+	if b.duckObj == nil {
+		return nil, fmt.Errorf("must provide a duck type")
+	}
+	if len(b.rawObjs) == 0 {
+		return nil, fmt.Errorf("must provide underlying types for duck type %T", b.duckObj)
+	}
+	duckGVK, err := apiutil.GVKForObject(b.duckObj, scheme)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &cachedReader{
+		c:       c,
+		scheme:  scheme,
+		duckGVK: duckGVK,
+		stores:  make([]*store, 0, len(b.rawObjs)),
+	}
+	for _, rawObj := range b.rawObjs {
+		rawGVK := rawObj.GetObjectKind().GroupVersionKind()
+
+		var llo client.Object
+		if _, isUnstructured := rawObj.(*unstructured.Unstructured); isUnstructured {
+			var u unstructured.Unstructured
+			u.GetObjectKind().SetGroupVersionKind(rawGVK)
+			llo = &u
+		} else {
+			ll, err := scheme.New(rawGVK)
+			if err != nil {
+				return nil, err
+			}
+			llo = ll.(client.Object)
+		}
+
+		informer, err := c.GetInformer(ctx, llo)
+		if err != nil {
+			return nil, err
+		}
+		s := &store{
+			gvk:     rawGVK,
+			entries: map[types.NamespacedName]*entry{},
+		}
+		s.reg, err = informer.AddEventHandler(toolscache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj interface{}) {
+				r.upsert(s, obj)
+			},
+			UpdateFunc: func(_, newObj interface{}) {
+				r.upsert(s, newObj)
+			},
+			DeleteFunc: func(obj interface{}) {
+				r.remove(s, obj)
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+		r.stores = append(r.stores, s)
+	}
+	return r, nil
+}
+
+func (r *cachedReader) upsert(s *store, obj interface{}) {
+	raw, ok := obj.(runtime.Object)
+	if !ok {
+		return
+	}
+	m, err := apimeta.Accessor(raw)
+	if err != nil {
+		return
+	}
+	key := types.NamespacedName{Namespace: m.GetNamespace(), Name: m.GetName()}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if e, found := s.entries[key]; found && e.rv == m.GetResourceVersion() {
+		return
+	}
+	s.entries[key] = &entry{raw: raw, rv: m.GetResourceVersion()}
+}
+
+func (r *cachedReader) remove(s *store, obj interface{}) {
+	if tombstone, ok := obj.(toolscache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	m, err := apimeta.Accessor(obj)
+	if err != nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(s.entries, types.NamespacedName{Namespace: m.GetNamespace(), Name: m.GetName()})
+}
+
+// duckify returns the duck view of e, converting the underlying object only if the
+// entry has been invalidated since the last call.
+func (r *cachedReader) duckify(s *store, key types.NamespacedName, e *entry) (duck.Object, error) {
+	r.mu.RLock()
+	obj := e.duckObj
+	r.mu.RUnlock()
+	if obj != nil {
+		return obj, nil
+	}
+
+	d2, err := r.scheme.New(r.duckGVK)
+	if err != nil {
+		return nil, err
+	}
+	dd := d2.(duck.Object)
+	if err := dd.Duckify(e.raw); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// only keep the result if the informer has not replaced the entry meanwhile
+	if cur, found := s.entries[key]; found && cur == e {
+		e.duckObj = dd
+	}
+	return dd, nil
+}
+
+func (r *cachedReader) waitForSync(ctx context.Context) error {
+	for _, s := range r.stores {
+		if s.reg.HasSynced() {
+			continue
+		}
+		if !toolscache.WaitForCacheSync(ctx.Done(), s.reg.HasSynced) {
+			return fmt.Errorf("failed waiting for %v informer to sync", s.gvk)
+		}
+	}
+	return nil
+}
+
+func (r *cachedReader) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	gvk, err := apiutil.GVKForObject(obj, r.scheme)
+	if err != nil {
+		return err
+	}
+	if gvk != r.duckGVK {
+		return r.c.Get(ctx, key, obj, opts...)
+	}
+	if err := r.waitForSync(ctx); err != nil {
+		return err
+	}
+
+	for _, s := range r.stores {
+		r.mu.RLock()
+		e, found := s.entries[key]
+		r.mu.RUnlock()
+		if !found {
+			continue
+		}
+
+		dd, err := r.duckify(s, key, e)
+		if err != nil {
+			return err
+		}
+		return copyInto(dd.DeepCopyObject(), obj)
+	}
+
+	gvr, _ := apimeta.UnsafeGuessKindToResource(r.duckGVK)
+	return apierrors.NewNotFound(gvr.GroupResource(), key.Name)
+}
+
+func (r *cachedReader) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	gvk, err := apiutil.GVKForObject(list, r.scheme)
+	if err != nil {
+		return err
+	}
+	if strings.HasSuffix(gvk.Kind, listType) && apimeta.IsListType(list) {
+		gvk.Kind = gvk.Kind[:len(gvk.Kind)-4]
+	}
+	if gvk != r.duckGVK {
+		return r.c.List(ctx, list, opts...)
+	}
+
+	listOpts := client.ListOptions{}
+	listOpts.ApplyOptions(opts)
+	if listOpts.Continue != "" {
+		return fmt.Errorf("continue list option is not supported by the duck cache")
+	}
+	if err := r.waitForSync(ctx); err != nil {
+		return err
+	}
+	disableDeepCopy := listOpts.UnsafeDisableDeepCopy != nil && *listOpts.UnsafeDisableDeepCopy
+
+	type candidate struct {
+		s   *store
+		key types.NamespacedName
+		e   *entry
+	}
+	var found []candidate
+	r.mu.RLock()
+	for _, s := range r.stores {
+		for key, e := range s.entries {
+			if matches(key, e, listOpts) {
+				found = append(found, candidate{s: s, key: key, e: e})
+			}
+		}
+	}
+	r.mu.RUnlock()
+	// Sort like the apiserver does, so that a limit always returns the same items.
+	sort.Slice(found, func(i, j int) bool {
+		if found[i].key.Namespace != found[j].key.Namespace {
+			return found[i].key.Namespace < found[j].key.Namespace
+		}
+		if found[i].key.Name != found[j].key.Name {
+			return found[i].key.Name < found[j].key.Name
+		}
+		return found[i].s.gvk.Kind < found[j].s.gvk.Kind
+	})
+	if listOpts.Limit > 0 && int64(len(found)) > listOpts.Limit {
+		found = found[:listOpts.Limit]
+	}
+
+	items := make([]runtime.Object, 0, len(found))
+	for _, c := range found {
+		dd, err := r.duckify(c.s, c.key, c.e)
+		if err != nil {
+			return err
+		}
+		if disableDeepCopy {
+			items = append(items, dd)
+		} else {
+			items = append(items, dd.DeepCopyObject())
+		}
+	}
+	return apimeta.SetList(list, items)
+}
+
+func matches(key types.NamespacedName, e *entry, opts client.ListOptions) bool {
+	if opts.Namespace != "" && opts.Namespace != key.Namespace {
+		return false
+	}
+	if opts.LabelSelector != nil || opts.FieldSelector != nil {
+		m, err := apimeta.Accessor(e.raw)
+		if err != nil {
+			return false
+		}
+		if opts.LabelSelector != nil && !opts.LabelSelector.Matches(labels.Set(m.GetLabels())) {
+			return false
+		}
+		if opts.FieldSelector != nil && !opts.FieldSelector.Matches(fields.Set{
+			"metadata.name":      key.Name,
+			"metadata.namespace": key.Namespace,
+		}) {
+			return false
+		}
+	}
+	return true
+}
+
+func copyInto(in runtime.Object, out client.Object) error {
+	outVal := reflect.ValueOf(out)
+	inVal := reflect.ValueOf(in)
+	if !inVal.Type().AssignableTo(outVal.Type()) {
+		return fmt.Errorf("cache had type %s, but %s was asked for", inVal.Type(), outVal.Type())
+	}
+	reflect.Indirect(outVal).Set(reflect.Indirect(inVal))
+	return nil
+}
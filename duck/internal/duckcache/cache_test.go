@@ -0,0 +1,272 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package duckcache
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	apps "k8s.io/api/apps/v1"
+	core "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	toolscache "k8s.io/client-go/tools/cache"
+	"kmodules.xyz/client-go/client/duck"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	corev1alpha1 "github.com/ArnobKumarSaha/k8s/api/v1alpha1"
+)
+
+// fakeInformer hands the registered handler back to the test so that it can play
+// the role of the informer.
+type fakeInformer struct {
+	cache.Informer
+	handler toolscache.ResourceEventHandler
+}
+
+type syncedRegistration struct{}
+
+func (syncedRegistration) HasSynced() bool { return true }
+
+func (f *fakeInformer) AddEventHandler(h toolscache.ResourceEventHandler) (toolscache.ResourceEventHandlerRegistration, error) {
+	f.handler = h
+	return syncedRegistration{}, nil
+}
+
+type fakeCache struct {
+	cache.Cache
+	informers map[schema.GroupVersionKind]*fakeInformer
+	scheme    *runtime.Scheme
+}
+
+func (f *fakeCache) GetInformer(_ context.Context, obj client.Object, _ ...cache.InformerGetOption) (cache.Informer, error) {
+	gvks, _, err := f.scheme.ObjectKinds(obj)
+	if err != nil {
+		return nil, err
+	}
+	inf := &fakeInformer{}
+	f.informers[gvks[0]] = inf
+	return inf, nil
+}
+
+// memClient mimics the controller-runtime cache reader: every List deep copies the
+// stored objects before returning them.
+type memClient struct {
+	client.Client
+	scheme      *runtime.Scheme
+	deployments []*apps.Deployment
+}
+
+func (m *memClient) Scheme() *runtime.Scheme {
+	return m.scheme
+}
+
+func (m *memClient) List(_ context.Context, list client.ObjectList, _ ...client.ListOption) error {
+	dl := list.(*apps.DeploymentList)
+	dl.Items = make([]apps.Deployment, 0, len(m.deployments))
+	for _, d := range m.deployments {
+		dl.Items = append(dl.Items, *d.DeepCopy())
+	}
+	return nil
+}
+
+func newScheme() *runtime.Scheme {
+	scm := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scm)
+	_ = corev1alpha1.AddToScheme(scm)
+	return scm
+}
+
+func newDeployment(i int) *apps.Deployment {
+	labels := map[string]string{"app": fmt.Sprintf("app-%d", i%10)}
+	return &apps.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            fmt.Sprintf("dep-%d", i),
+			Namespace:       fmt.Sprintf("ns-%d", i%3),
+			ResourceVersion: "1",
+			Labels:          labels,
+		},
+		Spec: apps.DeploymentSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: labels},
+			Template: core.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec: core.PodSpec{
+					Containers: []core.Container{
+						{
+							Name:  "app",
+							Image: "nginx",
+							Args:  []string{"--port=8080", "--verbose"},
+							Env: []core.EnvVar{
+								{Name: "NAMESPACE", Value: "default"},
+								{Name: "MODE", Value: "standalone"},
+							},
+							Ports: []core.ContainerPort{
+								{Name: "http", ContainerPort: 8080, Protocol: core.ProtocolTCP},
+							},
+							Resources: core.ResourceRequirements{
+								Limits: core.ResourceList{
+									core.ResourceMemory: resource.MustParse("128Mi"),
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func newReader(t testing.TB, scm *runtime.Scheme) (client.Reader, *fakeInformer) {
+	fc := &fakeCache{scheme: scm, informers: map[schema.GroupVersionKind]*fakeInformer{}}
+	r, err := NewReader().
+		ForDuckType(&corev1alpha1.MyPod{}).
+		WithUnderlyingTypes(objectOf(apps.SchemeGroupVersion.WithKind("Deployment"))).
+		Build(context.TODO(), fc, scm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r, fc.informers[apps.SchemeGroupVersion.WithKind("Deployment")]
+}
+
+func objectOf(gvk schema.GroupVersionKind) client.Object {
+	var u corev1alpha1.MyPod
+	u.GetObjectKind().SetGroupVersionKind(gvk)
+	return &u
+}
+
+func TestReaderFollowsInformerEvents(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.TODO()
+
+	r, inf := newReader(t, newScheme())
+	dep := newDeployment(1)
+	inf.handler.OnAdd(dep, true)
+
+	var mp corev1alpha1.MyPod
+	g.Expect(r.Get(ctx, client.ObjectKeyFromObject(dep), &mp)).To(Succeed())
+	g.Expect(mp.Kind).To(Equal("Deployment"))
+	g.Expect(mp.Spec.Selector.MatchLabels).To(Equal(dep.Spec.Selector.MatchLabels))
+
+	updated := dep.DeepCopy()
+	updated.ResourceVersion = "2"
+	updated.Spec.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": "changed"}}
+	inf.handler.OnUpdate(dep, updated)
+	g.Expect(r.Get(ctx, client.ObjectKeyFromObject(dep), &mp)).To(Succeed())
+	g.Expect(mp.Spec.Selector.MatchLabels).To(HaveKeyWithValue("app", "changed"))
+
+	var list corev1alpha1.MyPodList
+	g.Expect(r.List(ctx, &list, client.InNamespace(dep.Namespace))).To(Succeed())
+	g.Expect(list.Items).To(HaveLen(1))
+	g.Expect(r.List(ctx, &list, client.MatchingLabels{"app": "missing"})).To(Succeed())
+	g.Expect(list.Items).To(BeEmpty())
+
+	inf.handler.OnDelete(toolscache.DeletedFinalStateUnknown{Key: "ns-1/dep-1", Obj: updated})
+	err := r.Get(ctx, client.ObjectKeyFromObject(dep), &mp)
+	g.Expect(apierrors.IsNotFound(err)).To(BeTrue())
+}
+
+func TestReaderReturnsCopies(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.TODO()
+
+	r, inf := newReader(t, newScheme())
+	inf.handler.OnAdd(newDeployment(1), true)
+
+	var list corev1alpha1.MyPodList
+	g.Expect(r.List(ctx, &list)).To(Succeed())
+	list.Items[0].Spec.Selector.MatchLabels["app"] = "mutated"
+
+	g.Expect(r.List(ctx, &list)).To(Succeed())
+	g.Expect(list.Items[0].Spec.Selector.MatchLabels).To(HaveKeyWithValue("app", "app-1"))
+}
+
+func TestReaderListIsSorted(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.TODO()
+
+	r, inf := newReader(t, newScheme())
+	for i := 0; i < 20; i++ {
+		inf.handler.OnAdd(newDeployment(i), true)
+	}
+
+	var list corev1alpha1.MyPodList
+	g.Expect(r.List(ctx, &list, client.InNamespace("ns-0"))).To(Succeed())
+	var names []string
+	for _, mp := range list.Items {
+		names = append(names, mp.Name)
+	}
+	g.Expect(names).To(Equal([]string{"dep-0", "dep-12", "dep-15", "dep-18", "dep-3", "dep-6", "dep-9"}))
+
+	// The limit cuts the sorted list, not whatever the map order gives.
+	for i := 0; i < 10; i++ {
+		g.Expect(r.List(ctx, &list, client.Limit(3))).To(Succeed())
+		g.Expect(list.Items).To(HaveLen(3))
+		g.Expect([]string{list.Items[0].Name, list.Items[1].Name, list.Items[2].Name}).To(Equal([]string{"dep-0", "dep-12", "dep-15"}))
+	}
+}
+
+const benchmarkDeployments = 5000
+
+// BenchmarkTypedClientList lists MyPods through the kmodules duck client, which
+// converts every Deployment on every call.
+func BenchmarkTypedClientList(b *testing.B) {
+	scm := newScheme()
+	mc := &memClient{scheme: scm}
+	for i := 0; i < benchmarkDeployments; i++ {
+		mc.deployments = append(mc.deployments, newDeployment(i))
+	}
+	dc, err := duck.NewClient().
+		ForDuckType(&corev1alpha1.MyPod{}).
+		WithUnderlyingType(objectOf(apps.SchemeGroupVersion.WithKind("Deployment"))).
+		Build(mc)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var list corev1alpha1.MyPodList
+		if err := dc.List(context.TODO(), &list); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkCachedReaderList lists the same MyPods through the duck cache.
+func BenchmarkCachedReaderList(b *testing.B) {
+	r, inf := newReader(b, newScheme())
+	for i := 0; i < benchmarkDeployments; i++ {
+		inf.handler.OnAdd(newDeployment(i), true)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var list corev1alpha1.MyPodList
+		if err := r.List(context.TODO(), &list); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package monitor
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	cu "kmodules.xyz/client-go/client"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	corev1alpha1 "github.com/ArnobKumarSaha/k8s/api/v1alpha1"
+)
+
+// Objects returns an empty object of each monitor kind, for the controller to
+// watch the monitors it owns.
+func Objects() []client.Object {
+	var out []client.Object
+	for _, gvk := range []schema.GroupVersionKind{ServiceMonitorGVK, PodMonitorGVK} {
+		u := &unstructured.Unstructured{}
+		u.SetGroupVersionKind(gvk)
+		out = append(out, u)
+	}
+	return out
+}
+
+// Generator keeps the monitor of a workload in sync with its annotation. It
+// only touches monitors the workload controls, and deletes them when the
+// annotation is removed; the garbage collector deletes them with the workload.
+type Generator struct {
+	c client.Client
+}
+
+func New(c client.Client) *Generator {
+	return &Generator{c: c}
+}
+
+// Sync creates, patches or deletes the monitor of the workload behind mp, whose
+// pods are pods. An annotation that cannot be parsed is logged and otherwise
+// ignored, as retrying does not help before the workload is edited.
+func (g *Generator) Sync(ctx context.Context, mp *corev1alpha1.MyPod, pods []corev1.Pod) error {
+	log := logf.FromContext(ctx).WithValues("kind", mp.Kind)
+
+	value, ok := mp.Annotations[Annotation]
+	if !ok {
+		return g.deleteExcept(ctx, mp, schema.GroupVersionKind{})
+	}
+	spec, err := Parse(value)
+	if err != nil {
+		log.Error(err, "ignoring annotation", "annotation", Annotation)
+		return nil
+	}
+
+	var services corev1.ServiceList
+	if err := g.c.List(ctx, &services, client.InNamespace(mp.Namespace)); err != nil {
+		return err
+	}
+	desired, err := Desired(mp, spec, pods, services.Items)
+	if err != nil {
+		log.Error(err, "cannot generate monitor")
+		return nil
+	}
+	if err := g.deleteExcept(ctx, mp, desired.GroupVersionKind()); err != nil {
+		return err
+	}
+
+	cur, err := g.get(ctx, desired.GroupVersionKind(), mp)
+	if err != nil {
+		return err
+	}
+	if cur != nil && !metav1.IsControlledBy(cur, mp) {
+		log.Info("monitor exists and is not controlled by the workload, leaving it alone", "monitor", desired.GetKind()+"/"+desired.GetName())
+		return nil
+	}
+
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(desired.GroupVersionKind())
+	obj.SetNamespace(desired.GetNamespace())
+	obj.SetName(desired.GetName())
+	vt, err := cu.CreateOrPatch(ctx, g.c, obj, func(o client.Object, createOp bool) client.Object {
+		in := o.(*unstructured.Unstructured)
+		lbls := in.GetLabels()
+		if lbls == nil {
+			lbls = map[string]string{}
+		}
+		for k, v := range desired.GetLabels() {
+			lbls[k] = v
+		}
+		in.SetLabels(lbls)
+		in.SetOwnerReferences(desired.GetOwnerReferences())
+		in.Object["spec"] = desired.Object["spec"]
+		return in
+	})
+	if err != nil {
+		return err
+	}
+	log.V(1).Info("monitor synced", "monitor", desired.GetKind()+"/"+desired.GetName(), "verb", vt)
+	return nil
+}
+
+// deleteExcept deletes the monitors of mp that are not of kind keep.
+func (g *Generator) deleteExcept(ctx context.Context, mp *corev1alpha1.MyPod, keep schema.GroupVersionKind) error {
+	for _, gvk := range []schema.GroupVersionKind{ServiceMonitorGVK, PodMonitorGVK} {
+		if gvk == keep {
+			continue
+		}
+		cur, err := g.get(ctx, gvk, mp)
+		if err != nil {
+			return err
+		}
+		if cur == nil || !metav1.IsControlledBy(cur, mp) {
+			continue
+		}
+		if err := g.c.Delete(ctx, cur); client.IgnoreNotFound(err) != nil {
+			return err
+		}
+		logf.FromContext(ctx).Info("monitor deleted", "monitor", gvk.Kind+"/"+cur.GetName())
+	}
+	return nil
+}
+
+// get returns the monitor of kind gvk named after mp, or nil if there is none
+// or the kind is not served.
+func (g *Generator) get(ctx context.Context, gvk schema.GroupVersionKind, mp *corev1alpha1.MyPod) (*unstructured.Unstructured, error) {
+	u := &unstructured.Unstructured{}
+	u.SetGroupVersionKind(gvk)
+	err := g.c.Get(ctx, types.NamespacedName{Namespace: mp.Namespace, Name: Name(mp)}, u)
+	switch {
+	case apierrors.IsNotFound(err), meta.IsNoMatchError(err):
+		return nil, nil
+	case err != nil:
+		return nil, err
+	}
+	return u, nil
+}
@@ -0,0 +1,219 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package monitor generates a Prometheus operator ServiceMonitor or PodMonitor
+// for a workload annotated with a monitoring agent spec, owned by the workload.
+package monitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	mona "kmodules.xyz/monitoring-agent-api/api/v1"
+
+	corev1alpha1 "github.com/ArnobKumarSaha/k8s/api/v1alpha1"
+)
+
+// Annotation on a Deployment, StatefulSet or DaemonSet asks for a monitor. Its
+// value is a monitoring-agent-api AgentSpec in JSON, as KubeDB takes it in the
+// spec.monitor of a database.
+const Annotation = "core.duck.dev/monitoring"
+
+const (
+	DefaultInterval = "30s"
+	DefaultPath     = "/metrics"
+)
+
+var (
+	ServiceMonitorGVK = schema.GroupVersionKind{Group: "monitoring.coreos.com", Version: "v1", Kind: "ServiceMonitor"}
+	PodMonitorGVK     = schema.GroupVersionKind{Group: "monitoring.coreos.com", Version: "v1", Kind: "PodMonitor"}
+)
+
+// Parse reads the value of Annotation and defaults it. An empty agent is the
+// Prometheus operator, the only agent that uses monitor objects.
+func Parse(value string) (*mona.AgentSpec, error) {
+	var spec mona.AgentSpec
+	if err := json.Unmarshal([]byte(value), &spec); err != nil {
+		return nil, fmt.Errorf("invalid agent spec: %w", err)
+	}
+	if spec.Agent == "" {
+		spec.Agent = mona.AgentPrometheusOperator
+	}
+	if spec.Agent != mona.AgentPrometheusOperator {
+		return nil, fmt.Errorf("agent %q is not supported, only %s uses monitor objects", spec.Agent, mona.AgentPrometheusOperator)
+	}
+	spec.SetDefaults()
+	if spec.Prometheus.ServiceMonitor == nil {
+		spec.Prometheus.ServiceMonitor = &mona.ServiceMonitorSpec{}
+	}
+	if spec.Prometheus.ServiceMonitor.Interval == "" {
+		spec.Prometheus.ServiceMonitor.Interval = DefaultInterval
+	}
+	return &spec, nil
+}
+
+// Name of the monitor of a workload. The kind is part of it, as a Deployment and
+// a StatefulSet may share a name.
+func Name(mp *corev1alpha1.MyPod) string {
+	return mp.Name + "-" + strings.ToLower(mp.Kind)
+}
+
+// Desired returns the monitor for the workload behind mp, which keeps the kind
+// of the workload as Duckify sets it. It is a ServiceMonitor if one of services
+// selects the pods of the workload and forwards to the exporter port, with
+// labels to select it by, and a PodMonitor otherwise.
+func Desired(mp *corev1alpha1.MyPod, spec *mona.AgentSpec, pods []corev1.Pod, services []corev1.Service) (*unstructured.Unstructured, error) {
+	port := spec.Prometheus.Exporter.Port
+	portName := containerPortName(pods, port)
+	endpoint := map[string]interface{}{
+		"interval": spec.Prometheus.ServiceMonitor.Interval,
+		"path":     DefaultPath,
+	}
+
+	obj := &unstructured.Unstructured{}
+	if svc, svcPort := selectingService(mp, pods, services, port, portName); svc != nil {
+		obj.SetGroupVersionKind(ServiceMonitorGVK)
+		if svcPort.Name != "" {
+			endpoint["port"] = svcPort.Name
+		} else {
+			endpoint["targetPort"] = targetPort(*svcPort)
+		}
+		obj.Object["spec"] = map[string]interface{}{
+			"selector":          map[string]interface{}{"matchLabels": toInterfaceMap(svc.Labels)},
+			"namespaceSelector": map[string]interface{}{"matchNames": []interface{}{mp.Namespace}},
+			"endpoints":         []interface{}{endpoint},
+		}
+	} else {
+		obj.SetGroupVersionKind(PodMonitorGVK)
+		if mp.Spec.Selector == nil {
+			return nil, fmt.Errorf("%s %s/%s has no selector", mp.Kind, mp.Namespace, mp.Name)
+		}
+		sel, err := runtime.DefaultUnstructuredConverter.ToUnstructured(mp.Spec.Selector)
+		if err != nil {
+			return nil, err
+		}
+		if portName != "" {
+			endpoint["port"] = portName
+		} else {
+			endpoint["targetPort"] = int64(port)
+		}
+		obj.Object["spec"] = map[string]interface{}{
+			"selector":            sel,
+			"namespaceSelector":   map[string]interface{}{"matchNames": []interface{}{mp.Namespace}},
+			"podMetricsEndpoints": []interface{}{endpoint},
+		}
+	}
+	obj.SetNamespace(mp.Namespace)
+	obj.SetName(Name(mp))
+	obj.SetLabels(spec.Prometheus.ServiceMonitor.Labels)
+	obj.SetOwnerReferences([]metav1.OwnerReference{*metav1.NewControllerRef(mp, mp.GroupVersionKind())})
+	return obj, nil
+}
+
+// containerPortName returns the name the pods give to port, or "".
+func containerPortName(pods []corev1.Pod, port int32) string {
+	for _, pod := range pods {
+		for _, c := range pod.Spec.Containers {
+			for _, p := range c.Ports {
+				if p.ContainerPort == port && p.Name != "" {
+					return p.Name
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// selectingService returns the first service, by name, that selects the pods of
+// the workload, or the labels of its selector if it has no pods yet, and has a
+// port that forwards to the exporter port.
+func selectingService(mp *corev1alpha1.MyPod, pods []corev1.Pod, services []corev1.Service, port int32, portName string) (*corev1.Service, *corev1.ServicePort) {
+	podLabels := []labels.Set{}
+	for _, pod := range pods {
+		podLabels = append(podLabels, pod.Labels)
+	}
+	if len(podLabels) == 0 && mp.Spec.Selector != nil {
+		podLabels = append(podLabels, mp.Spec.Selector.MatchLabels)
+	}
+
+	var found *corev1.Service
+	var foundPort *corev1.ServicePort
+	for i := range services {
+		svc := &services[i]
+		if len(svc.Spec.Selector) == 0 || len(svc.Labels) == 0 {
+			continue
+		}
+		if found != nil && found.Name < svc.Name {
+			continue
+		}
+		sel := labels.SelectorFromSet(svc.Spec.Selector)
+		selects := false
+		for _, l := range podLabels {
+			if sel.Matches(l) {
+				selects = true
+				break
+			}
+		}
+		if !selects {
+			continue
+		}
+		for j := range svc.Spec.Ports {
+			if forwardsTo(svc.Spec.Ports[j], port, portName) {
+				found, foundPort = svc, &svc.Spec.Ports[j]
+				break
+			}
+		}
+	}
+	return found, foundPort
+}
+
+func forwardsTo(p corev1.ServicePort, port int32, portName string) bool {
+	switch p.TargetPort.Type {
+	case intstr.String:
+		return portName != "" && p.TargetPort.StrVal == portName
+	default:
+		if p.TargetPort.IntVal == 0 {
+			return p.Port == port
+		}
+		return p.TargetPort.IntVal == port
+	}
+}
+
+func targetPort(p corev1.ServicePort) interface{} {
+	if p.TargetPort.Type == intstr.String {
+		return p.TargetPort.StrVal
+	}
+	if p.TargetPort.IntVal == 0 {
+		return int64(p.Port)
+	}
+	return int64(p.TargetPort.IntVal)
+}
+
+func toInterfaceMap(m map[string]string) map[string]interface{} {
+	out := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
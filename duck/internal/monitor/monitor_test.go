@@ -0,0 +1,154 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package monitor
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	mona "kmodules.xyz/monitoring-agent-api/api/v1"
+
+	corev1alpha1 "github.com/ArnobKumarSaha/k8s/api/v1alpha1"
+)
+
+func TestParse(t *testing.T) {
+	g := NewWithT(t)
+
+	spec, err := Parse(`{"agent":"prometheus.io/operator","prometheus":{"serviceMonitor":{"interval":"10s"}}}`)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(spec.Agent).To(Equal(mona.AgentPrometheusOperator))
+	g.Expect(spec.Prometheus.Exporter.Port).To(BeEquivalentTo(mona.PrometheusExporterPortNumber))
+	g.Expect(spec.Prometheus.ServiceMonitor.Interval).To(Equal("10s"))
+
+	spec, err = Parse(`{"prometheus":{"exporter":{"port":9104},"serviceMonitor":{"labels":{"release":"prometheus"}}}}`)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(spec.Agent).To(Equal(mona.AgentPrometheusOperator))
+	g.Expect(spec.Prometheus.Exporter.Port).To(BeEquivalentTo(9104))
+	g.Expect(spec.Prometheus.ServiceMonitor.Interval).To(Equal(DefaultInterval))
+	g.Expect(spec.Prometheus.ServiceMonitor.Labels).To(Equal(map[string]string{"release": "prometheus"}))
+
+	spec, err = Parse(`{}`)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(spec.Prometheus.Exporter.Port).To(BeEquivalentTo(mona.PrometheusExporterPortNumber))
+	g.Expect(spec.Prometheus.ServiceMonitor.Interval).To(Equal(DefaultInterval))
+
+	_, err = Parse("fast")
+	g.Expect(err).To(MatchError(ContainSubstring("invalid agent spec")))
+	_, err = Parse(`{"agent":"prometheus.io/builtin"}`)
+	g.Expect(err).To(MatchError(ContainSubstring("not supported")))
+	_, err = Parse(`{"agent":`)
+	g.Expect(err).To(HaveOccurred())
+}
+
+func newMyPod() *corev1alpha1.MyPod {
+	return &corev1alpha1.MyPod{
+		TypeMeta: metav1.TypeMeta{APIVersion: "apps/v1", Kind: "Deployment"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "web",
+			Namespace: "demo",
+			UID:       "0b9e3c52-5f3a-4a1e-9c38-3f1c2b8a7d10",
+		},
+		Spec: corev1alpha1.MyPodSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+		},
+	}
+}
+
+func newPod(portName string, port int32) corev1.Pod {
+	return corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "web-1", Namespace: "demo", Labels: map[string]string{"app": "web", "pod-template-hash": "abc"}},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{
+			Name:  "web",
+			Ports: []corev1.ContainerPort{{Name: "http", ContainerPort: 8080}, {Name: portName, ContainerPort: port}},
+		}}},
+	}
+}
+
+func newService(name string, selector map[string]string, port corev1.ServicePort) corev1.Service {
+	return corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "demo", Labels: map[string]string{"app": "web", "svc": name}},
+		Spec: corev1.ServiceSpec{
+			Selector: selector,
+			Ports:    []corev1.ServicePort{{Name: "http", Port: 80, TargetPort: intstr.FromInt32(8080)}, port},
+		},
+	}
+}
+
+func TestDesiredServiceMonitor(t *testing.T) {
+	g := NewWithT(t)
+	mp := newMyPod()
+	spec, err := Parse(`{"prometheus":{"serviceMonitor":{"labels":{"release":"prometheus"},"interval":"15s"}}}`)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	pods := []corev1.Pod{newPod("metrics", mona.PrometheusExporterPortNumber)}
+	services := []corev1.Service{
+		newService("other", map[string]string{"app": "other"}, corev1.ServicePort{Name: "metrics", Port: 9000, TargetPort: intstr.FromString("metrics")}),
+		newService("web-stats", map[string]string{"app": "web"}, corev1.ServicePort{Name: "prom", Port: 9000, TargetPort: intstr.FromString("metrics")}),
+		newService("web", map[string]string{"app": "web"}, corev1.ServicePort{Name: "", Port: 9000, TargetPort: intstr.FromInt32(mona.PrometheusExporterPortNumber)}),
+	}
+
+	obj, err := Desired(mp, spec, pods, services)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(obj.GroupVersionKind()).To(Equal(ServiceMonitorGVK))
+	g.Expect(obj.GetNamespace()).To(Equal("demo"))
+	g.Expect(obj.GetName()).To(Equal("web-deployment"))
+	g.Expect(obj.GetLabels()).To(Equal(map[string]string{"release": "prometheus"}))
+	g.Expect(metav1.IsControlledBy(obj, mp)).To(BeTrue())
+	g.Expect(obj.GetOwnerReferences()[0].Kind).To(Equal("Deployment"))
+
+	// The first matching service by name is picked, its unnamed port by number.
+	sel, _, _ := unstructured.NestedStringMap(obj.Object, "spec", "selector", "matchLabels")
+	g.Expect(sel).To(Equal(map[string]string{"app": "web", "svc": "web"}))
+	endpoints, _, _ := unstructured.NestedSlice(obj.Object, "spec", "endpoints")
+	g.Expect(endpoints).To(Equal([]interface{}{map[string]interface{}{
+		"interval":   "15s",
+		"path":       DefaultPath,
+		"targetPort": int64(mona.PrometheusExporterPortNumber),
+	}}))
+}
+
+func TestDesiredPodMonitor(t *testing.T) {
+	g := NewWithT(t)
+	mp := newMyPod()
+	spec, err := Parse(`{"prometheus":{"exporter":{"port":9104}}}`)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	// The service does not forward to the exporter port.
+	services := []corev1.Service{newService("web", map[string]string{"app": "web"}, corev1.ServicePort{Name: "admin", Port: 9000})}
+
+	obj, err := Desired(mp, spec, []corev1.Pod{newPod("exporter", 9104)}, services)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(obj.GroupVersionKind()).To(Equal(PodMonitorGVK))
+	sel, _, _ := unstructured.NestedStringMap(obj.Object, "spec", "selector", "matchLabels")
+	g.Expect(sel).To(Equal(map[string]string{"app": "web"}))
+	endpoints, _, _ := unstructured.NestedSlice(obj.Object, "spec", "podMetricsEndpoints")
+	g.Expect(endpoints).To(Equal([]interface{}{map[string]interface{}{
+		"interval": DefaultInterval,
+		"path":     DefaultPath,
+		"port":     "exporter",
+	}}))
+
+	// Without pods, the port name is unknown.
+	obj, err = Desired(mp, spec, nil, nil)
+	g.Expect(err).NotTo(HaveOccurred())
+	endpoints, _, _ = unstructured.NestedSlice(obj.Object, "spec", "podMetricsEndpoints")
+	g.Expect(endpoints[0]).To(HaveKeyWithValue("targetPort", int64(9104)))
+}
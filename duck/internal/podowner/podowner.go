@@ -0,0 +1,175 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package podowner finds the workload controlling a pod and returns its MyPod
+// duck view, following controller ownerReferences through ReplicaSets, Jobs and
+// any other intermediate owner up to the top one.
+package podowner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"kmodules.xyz/client-go/client/duck"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	corev1alpha1 "github.com/ArnobKumarSaha/k8s/api/v1alpha1"
+)
+
+// maxDepth bounds the owner chain, in case of an ownerReference cycle.
+const maxDepth = 10
+
+// selectorKinds are the owners Duckify does not know whose .spec.selector is a
+// label selector, which is all their MyPod view needs.
+var selectorKinds = map[schema.GroupVersionKind]bool{
+	appsv1.SchemeGroupVersion.WithKind("ReplicaSet"):                true,
+	{Group: "apps.k8s.appscode.com", Version: "v1", Kind: "PetSet"}: true,
+}
+
+// Owner is one controller owner on the way from the pod to the top owner.
+type Owner struct {
+	GroupVersionKind schema.GroupVersionKind
+	Name             string
+	// Missing is set when the ownerReference points to an object that does not
+	// exist anymore. It is always the last owner of the chain.
+	Missing bool
+}
+
+func (o Owner) String() string {
+	return o.GroupVersionKind.Kind + "/" + o.Name
+}
+
+type Result struct {
+	// Owners are the controller owners of the pod, nearest first.
+	Owners []Owner
+	// MyPod is the duck view of the top existing owner. It is nil when the pod
+	// has no controller, or when its only controller is missing.
+	MyPod *corev1alpha1.MyPod
+}
+
+// Resolver resolves pods with c. Owners whose kind is not registered in the
+// scheme of c are read as unstructured objects.
+type Resolver struct {
+	c client.Client
+}
+
+func New(c client.Client) *Resolver {
+	return &Resolver{c: c}
+}
+
+// Resolve walks the controller ownerReferences of pod. The MyPod it returns
+// keeps the kind of the underlying workload, as Duckify sets it.
+func (r *Resolver) Resolve(ctx context.Context, pod *corev1.Pod) (*Result, error) {
+	var res Result
+	var top *Owner
+	cur := metav1.Object(pod)
+	for depth := 0; depth < maxDepth; depth++ {
+		ref := metav1.GetControllerOf(cur)
+		if ref == nil {
+			break
+		}
+		gv, err := schema.ParseGroupVersion(ref.APIVersion)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ownerReference of %s: %w", cur.GetName(), err)
+		}
+		owner := Owner{GroupVersionKind: gv.WithKind(ref.Kind), Name: ref.Name}
+
+		// Only the metadata is needed to keep walking, which works for any kind.
+		var meta metav1.PartialObjectMetadata
+		meta.SetGroupVersionKind(owner.GroupVersionKind)
+		err = r.c.Get(ctx, client.ObjectKey{Namespace: pod.Namespace, Name: ref.Name}, &meta)
+		if apierrors.IsNotFound(err) {
+			owner.Missing = true
+			res.Owners = append(res.Owners, owner)
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		if meta.UID != ref.UID {
+			// Same name, but the owner was deleted and created again.
+			owner.Missing = true
+			res.Owners = append(res.Owners, owner)
+			break
+		}
+		res.Owners = append(res.Owners, owner)
+		top = &res.Owners[len(res.Owners)-1]
+		cur = &meta
+	}
+	if top == nil {
+		return &res, nil
+	}
+
+	mp, err := r.duckify(ctx, pod.Namespace, *top, r.c.Scheme().Recognizes(top.GroupVersionKind))
+	var status apierrors.APIStatus
+	if err != nil && !errors.As(err, &status) && selectorKinds[top.GroupVersionKind] {
+		// Duckify only knows some of the workloads, like not ReplicaSets left
+		// without a Deployment or PetSets.
+		mp, err = r.fromSelector(ctx, pod.Namespace, *top)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to duckify %s: %w", top, err)
+	}
+	res.MyPod = mp
+	return &res, nil
+}
+
+// duckify reads owner through a duck client for its kind, typed or unstructured.
+func (r *Resolver) duckify(ctx context.Context, namespace string, owner Owner, typed bool) (*corev1alpha1.MyPod, error) {
+	var raw client.Object
+	if typed {
+		var mp corev1alpha1.MyPod
+		mp.SetGroupVersionKind(owner.GroupVersionKind)
+		raw = &mp
+	} else {
+		var u unstructured.Unstructured
+		u.SetGroupVersionKind(owner.GroupVersionKind)
+		raw = &u
+	}
+	dc, err := duck.NewClient().
+		ForDuckType(&corev1alpha1.MyPod{}).
+		WithUnderlyingType(raw).
+		Build(r.c)
+	if err != nil {
+		return nil, err
+	}
+	var mp corev1alpha1.MyPod
+	if err := dc.Get(ctx, client.ObjectKey{Namespace: namespace, Name: owner.Name}, &mp); err != nil {
+		return nil, err
+	}
+	return &mp, nil
+}
+
+// fromSelector reads owner as an unstructured object and keeps its .spec.selector.
+func (r *Resolver) fromSelector(ctx context.Context, namespace string, owner Owner) (*corev1alpha1.MyPod, error) {
+	var u unstructured.Unstructured
+	u.SetGroupVersionKind(owner.GroupVersionKind)
+	if err := r.c.Get(ctx, client.ObjectKey{Namespace: namespace, Name: owner.Name}, &u); err != nil {
+		return nil, err
+	}
+	var mp corev1alpha1.MyPod
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.UnstructuredContent(), &mp); err != nil {
+		return nil, err
+	}
+	return &mp, nil
+}
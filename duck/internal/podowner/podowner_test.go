@@ -0,0 +1,189 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package podowner
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	apps "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+
+	corev1alpha1 "github.com/ArnobKumarSaha/k8s/api/v1alpha1"
+)
+
+// objectClient serves Get from a fixed set of objects, in whatever form the
+// caller asks for: typed, unstructured or metadata only.
+type objectClient struct {
+	client.Client
+	scheme  *runtime.Scheme
+	objects map[schema.GroupVersionKind]map[client.ObjectKey]map[string]interface{}
+}
+
+func newObjectClient(t *testing.T, objs ...client.Object) *objectClient {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(corev1alpha1.AddToScheme(scheme))
+
+	c := &objectClient{scheme: scheme, objects: map[schema.GroupVersionKind]map[client.ObjectKey]map[string]interface{}{}}
+	for _, obj := range objs {
+		gvk, err := apiutil.GVKForObject(obj, scheme)
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.objects[gvk] == nil {
+			c.objects[gvk] = map[client.ObjectKey]map[string]interface{}{}
+		}
+		c.objects[gvk][client.ObjectKeyFromObject(obj)] = content
+	}
+	return c
+}
+
+func (c *objectClient) Scheme() *runtime.Scheme {
+	return c.scheme
+}
+
+func (c *objectClient) Get(_ context.Context, key client.ObjectKey, obj client.Object, _ ...client.GetOption) error {
+	gvk, err := apiutil.GVKForObject(obj, c.scheme)
+	if err != nil {
+		return err
+	}
+	content, ok := c.objects[gvk][key]
+	if !ok {
+		return apierrors.NewNotFound(schema.GroupResource{Group: gvk.Group, Resource: gvk.Kind}, key.Name)
+	}
+	if u, ok := obj.(*unstructured.Unstructured); ok {
+		u.SetUnstructuredContent(runtime.DeepCopyJSON(content))
+		return nil
+	}
+	return runtime.DefaultUnstructuredConverter.FromUnstructured(content, obj)
+}
+
+func controllerRef(apiVersion, kind, name string) []metav1.OwnerReference {
+	return []metav1.OwnerReference{{
+		APIVersion: apiVersion, Kind: kind, Name: name, UID: types.UID(name), Controller: ptr(true),
+	}}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+var selector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}
+
+func TestResolveDeployment(t *testing.T) {
+	g := NewWithT(t)
+
+	deploy := &apps.Deployment{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "apps/v1", Kind: "Deployment"},
+		ObjectMeta: metav1.ObjectMeta{Namespace: "demo", Name: "web", UID: "web"},
+		Spec:       apps.DeploymentSpec{Selector: selector},
+	}
+	rs := &apps.ReplicaSet{
+		TypeMeta: metav1.TypeMeta{APIVersion: "apps/v1", Kind: "ReplicaSet"},
+		ObjectMeta: metav1.ObjectMeta{Namespace: "demo", Name: "web-7d4b9c", UID: "web-7d4b9c",
+			OwnerReferences: controllerRef("apps/v1", "Deployment", "web")},
+		Spec: apps.ReplicaSetSpec{Selector: selector},
+	}
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "demo", Name: "web-7d4b9c-x2x9z",
+		OwnerReferences: controllerRef("apps/v1", "ReplicaSet", "web-7d4b9c")}}
+
+	res, err := New(newObjectClient(t, deploy, rs)).Resolve(context.Background(), pod)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(res.Owners).To(HaveLen(2))
+	g.Expect(res.Owners[1].String()).To(Equal("Deployment/web"))
+	g.Expect(res.MyPod).NotTo(BeNil())
+	g.Expect(res.MyPod.Kind).To(Equal("Deployment"))
+	g.Expect(res.MyPod.Name).To(Equal("web"))
+	g.Expect(res.MyPod.Spec.Selector).To(Equal(selector))
+
+	// Without the Deployment the ReplicaSet is the top owner, which the typed
+	// Duckify does not know.
+	res, err = New(newObjectClient(t, rs)).Resolve(context.Background(), pod)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(res.Owners).To(HaveLen(2))
+	g.Expect(res.Owners[1].Missing).To(BeTrue())
+	g.Expect(res.MyPod.Kind).To(Equal("ReplicaSet"))
+	g.Expect(res.MyPod.Spec.Selector).To(Equal(selector))
+}
+
+func TestResolveUnknownKind(t *testing.T) {
+	g := NewWithT(t)
+
+	petSet := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apps.k8s.appscode.com/v1",
+		"kind":       "PetSet",
+		"metadata":   map[string]interface{}{"namespace": "demo", "name": "db", "uid": "db"},
+		"spec": map[string]interface{}{
+			"replicas": int64(3),
+			"selector": map[string]interface{}{"matchLabels": map[string]interface{}{"app": "web"}},
+		},
+	}}
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "demo", Name: "db-0",
+		OwnerReferences: controllerRef("apps.k8s.appscode.com/v1", "PetSet", "db")}}
+
+	res, err := New(newObjectClient(t, petSet)).Resolve(context.Background(), pod)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(res.Owners).To(HaveLen(1))
+	g.Expect(res.MyPod.Kind).To(Equal("PetSet"))
+	g.Expect(res.MyPod.Spec.Selector).To(Equal(selector))
+}
+
+func TestResolveSelectorOfOtherKind(t *testing.T) {
+	g := NewWithT(t)
+
+	// A plain map selector, like the one of a Service, must not turn into a
+	// label selector that matches everything.
+	proxy := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "example.com/v1",
+		"kind":       "Proxy",
+		"metadata":   map[string]interface{}{"namespace": "demo", "name": "web", "uid": "web"},
+		"spec": map[string]interface{}{
+			"selector": map[string]interface{}{"app": "web"},
+		},
+	}}
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "demo", Name: "web-0",
+		OwnerReferences: controllerRef("example.com/v1", "Proxy", "web")}}
+
+	_, err := New(newObjectClient(t, proxy)).Resolve(context.Background(), pod)
+	g.Expect(err).To(MatchError(ContainSubstring("failed to duckify Proxy/web")))
+}
+
+func TestResolveWithoutController(t *testing.T) {
+	g := NewWithT(t)
+
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "demo", Name: "debug"}}
+	res, err := New(newObjectClient(t)).Resolve(context.Background(), pod)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(res.Owners).To(BeEmpty())
+	g.Expect(res.MyPod).To(BeNil())
+}
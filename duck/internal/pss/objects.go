@@ -0,0 +1,67 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pss
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// templatePaths lists where kinds keep the pod spec and the metadata that goes
+// with it, tried in order. Sidekicks embed the fields of a pod spec in their spec,
+// like pods, and KubeDB databases keep theirs in spec.podTemplate.
+var templatePaths = []struct {
+	meta, spec []string
+}{
+	{[]string{"spec", "template", "metadata"}, []string{"spec", "template", "spec"}},
+	{[]string{"spec", "jobTemplate", "spec", "template", "metadata"}, []string{"spec", "jobTemplate", "spec", "template", "spec"}},
+	{[]string{"spec", "podTemplate", "metadata"}, []string{"spec", "podTemplate", "spec"}},
+	{[]string{"metadata"}, []string{"spec"}},
+}
+
+// EvaluateObject checks the pod spec of a Pod, a Sidekick, a KubeDB database or
+// any workload with a pod template, like Deployments, StatefulSets and PetSets.
+// ok is false when obj has no pod spec.
+func EvaluateObject(level Level, obj *unstructured.Unstructured) (violations []Violation, ok bool, err error) {
+	for _, tp := range templatePaths {
+		specMap, found, err := unstructured.NestedMap(obj.Object, tp.spec...)
+		if err != nil || !found {
+			continue
+		}
+		if _, hasContainers := specMap["containers"]; !hasContainers {
+			continue
+		}
+
+		var spec corev1.PodSpec
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(specMap, &spec); err != nil {
+			return nil, true, fmt.Errorf("%s %s: invalid pod spec at %s: %w", obj.GetKind(), obj.GetName(), field.NewPath(tp.spec[0], tp.spec[1:]...), err)
+		}
+		var meta metav1.ObjectMeta
+		if metaMap, found, _ := unstructured.NestedMap(obj.Object, tp.meta...); found {
+			if err := runtime.DefaultUnstructuredConverter.FromUnstructured(metaMap, &meta); err != nil {
+				return nil, true, err
+			}
+		}
+		return Evaluate(level, &meta, field.NewPath(tp.meta[0], tp.meta[1:]...), &spec, field.NewPath(tp.spec[0], tp.spec[1:]...)), true, nil
+	}
+	return nil, false, nil
+}
@@ -0,0 +1,281 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package pss checks pod specs against the baseline and restricted Pod Security
+// Standards, https://kubernetes.io/docs/concepts/security/pod-security-standards/,
+// reporting the path of every violating field. Unlike the built in Pod Security
+// Admission it also checks the pod templates of workloads, Sidekicks and KubeDB
+// databases before any pod is created from them.
+package pss
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+type Level string
+
+const (
+	LevelBaseline   Level = "baseline"
+	LevelRestricted Level = "restricted"
+)
+
+func ParseLevel(s string) (Level, error) {
+	switch l := Level(strings.ToLower(s)); l {
+	case LevelBaseline, LevelRestricted:
+		return l, nil
+	}
+	return "", fmt.Errorf("unknown Pod Security Standard %q, expected baseline or restricted", s)
+}
+
+// Violation is one field breaking a check of the standard.
+type Violation struct {
+	Path string `json:"path"`
+	// Level is the lowest level forbidding the value.
+	Level  Level  `json:"level"`
+	Check  string `json:"check"`
+	Detail string `json:"detail"`
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("%s: %s (%s %s)", v.Path, v.Detail, v.Level, v.Check)
+}
+
+var (
+	baselineCapabilities = sets.New[corev1.Capability](
+		"AUDIT_WRITE", "CHOWN", "DAC_OVERRIDE", "FOWNER", "FSETID", "KILL", "MKNOD", "NET_BIND_SERVICE",
+		"SETFCAP", "SETGID", "SETPCAP", "SETUID", "SYS_CHROOT",
+	)
+	safeSysctls = sets.New(
+		"kernel.shm_rmid_forced", "net.ipv4.ip_local_port_range", "net.ipv4.ip_unprivileged_port_start",
+		"net.ipv4.tcp_syncookies", "net.ipv4.ping_group_range", "net.ipv4.ip_local_reserved_ports",
+		"net.ipv4.tcp_keepalive_time", "net.ipv4.tcp_fin_timeout", "net.ipv4.tcp_keepalive_intvl",
+		"net.ipv4.tcp_keepalive_probes",
+	)
+	seLinuxTypes = sets.New("", "container_t", "container_init_t", "container_kvm_t", "container_engine_t")
+)
+
+const appArmorAnnotationPrefix = "container.apparmor.security.beta.kubernetes.io/"
+
+// container is any of the containers, init containers and ephemeral containers,
+// which the standard treats the same.
+type container struct {
+	name string
+	path *field.Path
+	sc   *corev1.SecurityContext
+	port []corev1.ContainerPort
+}
+
+func containers(spec *corev1.PodSpec, path *field.Path) []container {
+	var out []container
+	for i, c := range spec.InitContainers {
+		out = append(out, container{c.Name, path.Child("initContainers").Index(i), c.SecurityContext, c.Ports})
+	}
+	for i, c := range spec.Containers {
+		out = append(out, container{c.Name, path.Child("containers").Index(i), c.SecurityContext, c.Ports})
+	}
+	for i, c := range spec.EphemeralContainers {
+		out = append(out, container{c.Name, path.Child("ephemeralContainers").Index(i), c.SecurityContext, c.Ports})
+	}
+	return out
+}
+
+// Evaluate checks a pod spec at path, with the metadata of its pod or template
+// at metaPath. level restricted includes the baseline checks.
+func Evaluate(level Level, meta *metav1.ObjectMeta, metaPath *field.Path, spec *corev1.PodSpec, path *field.Path) []Violation {
+	out := evaluate(level, meta, metaPath, spec, path)
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Path < out[j].Path })
+	return out
+}
+
+func evaluate(level Level, meta *metav1.ObjectMeta, metaPath *field.Path, spec *corev1.PodSpec, path *field.Path) []Violation {
+	var out []Violation
+	add := func(l Level, check string, p *field.Path, format string, args ...interface{}) {
+		out = append(out, Violation{Path: p.String(), Level: l, Check: check, Detail: fmt.Sprintf(format, args...)})
+	}
+	psc := spec.SecurityContext
+	if psc == nil {
+		psc = &corev1.PodSecurityContext{}
+	}
+	pscPath := path.Child("securityContext")
+	ctrs := containers(spec, path)
+
+	// Baseline.
+	if psc.WindowsOptions != nil && psc.WindowsOptions.HostProcess != nil && *psc.WindowsOptions.HostProcess {
+		add(LevelBaseline, "hostProcess", pscPath.Child("windowsOptions", "hostProcess"), "must not be true")
+	}
+	if spec.HostNetwork {
+		add(LevelBaseline, "hostNamespaces", path.Child("hostNetwork"), "must not be true")
+	}
+	if spec.HostPID {
+		add(LevelBaseline, "hostNamespaces", path.Child("hostPID"), "must not be true")
+	}
+	if spec.HostIPC {
+		add(LevelBaseline, "hostNamespaces", path.Child("hostIPC"), "must not be true")
+	}
+	for i, v := range spec.Volumes {
+		if v.HostPath != nil {
+			add(LevelBaseline, "hostPathVolumes", path.Child("volumes").Index(i).Child("hostPath"), "volume %s must not be a hostPath", v.Name)
+		}
+	}
+	for k, v := range meta.Annotations {
+		if strings.HasPrefix(k, appArmorAnnotationPrefix) && v != "runtime/default" && !strings.HasPrefix(v, "localhost/") {
+			add(LevelBaseline, "appArmorProfile", metaPath.Child("annotations").Key(k), "must be runtime/default or localhost/*, not %q", v)
+		}
+	}
+	checkAppArmor(psc.AppArmorProfile, pscPath, add)
+	checkSELinux(psc.SELinuxOptions, pscPath, add)
+	if psc.SeccompProfile != nil && psc.SeccompProfile.Type == corev1.SeccompProfileTypeUnconfined {
+		add(LevelBaseline, "seccompProfile", pscPath.Child("seccompProfile", "type"), "must not be Unconfined")
+	}
+	for i, s := range psc.Sysctls {
+		if !safeSysctls.Has(s.Name) {
+			add(LevelBaseline, "sysctls", pscPath.Child("sysctls").Index(i).Child("name"), "%s is not a safe sysctl", s.Name)
+		}
+	}
+	for _, c := range ctrs {
+		for i, p := range c.port {
+			if p.HostPort != 0 {
+				add(LevelBaseline, "hostPorts", c.path.Child("ports").Index(i).Child("hostPort"), "container %s must not use a host port", c.name)
+			}
+		}
+		sc, scPath := c.sc, c.path.Child("securityContext")
+		if sc == nil {
+			continue
+		}
+		if sc.WindowsOptions != nil && sc.WindowsOptions.HostProcess != nil && *sc.WindowsOptions.HostProcess {
+			add(LevelBaseline, "hostProcess", scPath.Child("windowsOptions", "hostProcess"), "must not be true")
+		}
+		if sc.Privileged != nil && *sc.Privileged {
+			add(LevelBaseline, "privileged", scPath.Child("privileged"), "container %s must not be privileged", c.name)
+		}
+		if sc.Capabilities != nil {
+			for i, capability := range sc.Capabilities.Add {
+				if !baselineCapabilities.Has(capability) {
+					add(LevelBaseline, "capabilities", scPath.Child("capabilities", "add").Index(i), "%s is not allowed", capability)
+				}
+			}
+		}
+		checkAppArmor(sc.AppArmorProfile, scPath, add)
+		checkSELinux(sc.SELinuxOptions, scPath, add)
+		if sc.ProcMount != nil && *sc.ProcMount != corev1.DefaultProcMount {
+			add(LevelBaseline, "procMount", scPath.Child("procMount"), "must be Default")
+		}
+		if sc.SeccompProfile != nil && sc.SeccompProfile.Type == corev1.SeccompProfileTypeUnconfined {
+			add(LevelBaseline, "seccompProfile", scPath.Child("seccompProfile", "type"), "must not be Unconfined")
+		}
+	}
+	if level == LevelBaseline {
+		return out
+	}
+
+	// Restricted.
+	for i, v := range spec.Volumes {
+		if v.HostPath == nil && !restrictedVolume(v.VolumeSource) {
+			add(LevelRestricted, "volumeTypes", path.Child("volumes").Index(i), "volume %s must be a configMap, csi, downwardAPI, emptyDir, ephemeral, persistentVolumeClaim, projected or secret", v.Name)
+		}
+	}
+	podNonRoot := psc.RunAsNonRoot != nil && *psc.RunAsNonRoot
+	if psc.RunAsNonRoot != nil && !*psc.RunAsNonRoot {
+		add(LevelRestricted, "runAsNonRoot", pscPath.Child("runAsNonRoot"), "must not be false")
+	}
+	if psc.RunAsUser != nil && *psc.RunAsUser == 0 {
+		add(LevelRestricted, "runAsUser", pscPath.Child("runAsUser"), "must not be 0")
+	}
+	podSeccomp := psc.SeccompProfile != nil && allowedSeccomp(psc.SeccompProfile.Type)
+	for _, c := range ctrs {
+		sc, scPath := c.sc, c.path.Child("securityContext")
+		if sc == nil {
+			sc = &corev1.SecurityContext{}
+		}
+		if sc.AllowPrivilegeEscalation == nil || *sc.AllowPrivilegeEscalation {
+			add(LevelRestricted, "allowPrivilegeEscalation", scPath.Child("allowPrivilegeEscalation"), "container %s must set it to false", c.name)
+		}
+		switch {
+		case sc.RunAsNonRoot != nil && !*sc.RunAsNonRoot:
+			add(LevelRestricted, "runAsNonRoot", scPath.Child("runAsNonRoot"), "container %s must not set it to false", c.name)
+		case sc.RunAsNonRoot == nil && !podNonRoot:
+			add(LevelRestricted, "runAsNonRoot", scPath.Child("runAsNonRoot"), "container %s must set it to true, or the pod must", c.name)
+		}
+		if sc.RunAsUser != nil && *sc.RunAsUser == 0 {
+			add(LevelRestricted, "runAsUser", scPath.Child("runAsUser"), "container %s must not run as user 0", c.name)
+		}
+		switch {
+		case sc.SeccompProfile != nil && !allowedSeccomp(sc.SeccompProfile.Type):
+			if sc.SeccompProfile.Type != corev1.SeccompProfileTypeUnconfined {
+				add(LevelRestricted, "seccompProfile", scPath.Child("seccompProfile", "type"), "must be RuntimeDefault or Localhost")
+			}
+		case sc.SeccompProfile == nil && !podSeccomp:
+			add(LevelRestricted, "seccompProfile", scPath.Child("seccompProfile", "type"), "container %s must set RuntimeDefault or Localhost, or the pod must", c.name)
+		}
+		if sc.Capabilities == nil || !hasCapability(sc.Capabilities.Drop, "ALL") {
+			add(LevelRestricted, "capabilities", scPath.Child("capabilities", "drop"), "container %s must drop ALL", c.name)
+		}
+		if sc.Capabilities != nil {
+			for i, capability := range sc.Capabilities.Add {
+				if capability != "NET_BIND_SERVICE" && baselineCapabilities.Has(capability) {
+					add(LevelRestricted, "capabilities", scPath.Child("capabilities", "add").Index(i), "only NET_BIND_SERVICE may be added, not %s", capability)
+				}
+			}
+		}
+	}
+	return out
+}
+
+func checkAppArmor(p *corev1.AppArmorProfile, path *field.Path, add func(Level, string, *field.Path, string, ...interface{})) {
+	if p != nil && p.Type != corev1.AppArmorProfileTypeRuntimeDefault && p.Type != corev1.AppArmorProfileTypeLocalhost {
+		add(LevelBaseline, "appArmorProfile", path.Child("appArmorProfile", "type"), "must be RuntimeDefault or Localhost, not %s", p.Type)
+	}
+}
+
+func checkSELinux(o *corev1.SELinuxOptions, path *field.Path, add func(Level, string, *field.Path, string, ...interface{})) {
+	if o == nil {
+		return
+	}
+	if !seLinuxTypes.Has(o.Type) {
+		add(LevelBaseline, "seLinuxOptions", path.Child("seLinuxOptions", "type"), "%s is not allowed", o.Type)
+	}
+	if o.User != "" {
+		add(LevelBaseline, "seLinuxOptions", path.Child("seLinuxOptions", "user"), "must not be set")
+	}
+	if o.Role != "" {
+		add(LevelBaseline, "seLinuxOptions", path.Child("seLinuxOptions", "role"), "must not be set")
+	}
+}
+
+func restrictedVolume(v corev1.VolumeSource) bool {
+	return v.ConfigMap != nil || v.CSI != nil || v.DownwardAPI != nil || v.EmptyDir != nil || v.Ephemeral != nil ||
+		v.PersistentVolumeClaim != nil || v.Projected != nil || v.Secret != nil
+}
+
+func allowedSeccomp(t corev1.SeccompProfileType) bool {
+	return t == corev1.SeccompProfileTypeRuntimeDefault || t == corev1.SeccompProfileTypeLocalhost
+}
+
+func hasCapability(caps []corev1.Capability, want corev1.Capability) bool {
+	for _, c := range caps {
+		if strings.EqualFold(string(c), string(want)) {
+			return true
+		}
+	}
+	return false
+}
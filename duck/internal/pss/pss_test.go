@@ -0,0 +1,200 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pss
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+	"sigs.k8s.io/yaml"
+)
+
+// sidekick is trimmed from the Sidekick of patch/sidekick.go, which sets up its
+// containers for the restricted standard by hand.
+const sidekick = `apiVersion: apps.k8s.appscode.com/v1alpha1
+kind: Sidekick
+metadata:
+  name: ace-db-sidekick
+  namespace: ace
+spec:
+  containers:
+  - name: wal-g
+    image: ghcr.io/kubedb/postgres-archiver:v0.9.0_15.5-alpine
+    securityContext:
+      allowPrivilegeEscalation: false
+      capabilities:
+        drop:
+        - ALL
+      runAsGroup: 70
+      runAsNonRoot: true
+      runAsUser: 70
+      seccompProfile:
+        type: RuntimeDefault
+    volumeMounts:
+    - mountPath: /var/pv
+      name: data
+  leader:
+    selectionPolicy: First
+  securityContext:
+    fsGroup: 70
+`
+
+const deployment = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: demo
+spec:
+  template:
+    metadata:
+      annotations:
+        container.apparmor.security.beta.kubernetes.io/nginx: unconfined
+    spec:
+      hostNetwork: true
+      containers:
+      - name: nginx
+        image: nginx
+        ports:
+        - containerPort: 80
+          hostPort: 80
+        securityContext:
+          privileged: true
+          capabilities:
+            add:
+            - NET_ADMIN
+            - CHOWN
+      volumes:
+      - name: logs
+        hostPath:
+          path: /var/log
+      - name: nfs
+        nfs:
+          server: nfs
+          path: /
+`
+
+func decode(t *testing.T, s string) *unstructured.Unstructured {
+	t.Helper()
+	obj := &unstructured.Unstructured{}
+	if err := yaml.Unmarshal([]byte(s), &obj.Object); err != nil {
+		t.Fatal(err)
+	}
+	return obj
+}
+
+func paths(violations []Violation, level Level) []string {
+	var out []string
+	for _, v := range violations {
+		if v.Level == level {
+			out = append(out, v.Path)
+		}
+	}
+	return out
+}
+
+func TestSidekickIsRestricted(t *testing.T) {
+	violations, ok, err := EvaluateObject(LevelRestricted, decode(t, sidekick))
+	if err != nil || !ok {
+		t.Fatalf("EvaluateObject() = %v, %v", ok, err)
+	}
+	if len(violations) != 0 {
+		t.Errorf("EvaluateObject() = %v, want none", violations)
+	}
+}
+
+func TestDeploymentViolations(t *testing.T) {
+	obj := decode(t, deployment)
+
+	baseline, ok, err := EvaluateObject(LevelBaseline, obj)
+	if err != nil || !ok {
+		t.Fatalf("EvaluateObject() = %v, %v", ok, err)
+	}
+	wantBaseline := []string{
+		"spec.template.metadata.annotations[container.apparmor.security.beta.kubernetes.io/nginx]",
+		"spec.template.spec.containers[0].ports[0].hostPort",
+		"spec.template.spec.containers[0].securityContext.capabilities.add[0]",
+		"spec.template.spec.containers[0].securityContext.privileged",
+		"spec.template.spec.hostNetwork",
+		"spec.template.spec.volumes[0].hostPath",
+	}
+	if got := paths(baseline, LevelBaseline); !reflect.DeepEqual(got, wantBaseline) {
+		t.Errorf("baseline violations = %v, want %v", got, wantBaseline)
+	}
+
+	restricted, _, err := EvaluateObject(LevelRestricted, obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := paths(restricted, LevelBaseline); !reflect.DeepEqual(got, wantBaseline) {
+		t.Errorf("restricted includes baseline violations %v, want %v", got, wantBaseline)
+	}
+	wantRestricted := []string{
+		"spec.template.spec.containers[0].securityContext.allowPrivilegeEscalation",
+		"spec.template.spec.containers[0].securityContext.capabilities.add[1]",
+		"spec.template.spec.containers[0].securityContext.capabilities.drop",
+		"spec.template.spec.containers[0].securityContext.runAsNonRoot",
+		"spec.template.spec.containers[0].securityContext.seccompProfile.type",
+		"spec.template.spec.volumes[1]",
+	}
+	if got := paths(restricted, LevelRestricted); !reflect.DeepEqual(got, wantRestricted) {
+		t.Errorf("restricted violations = %v, want %v", got, wantRestricted)
+	}
+}
+
+func TestEvaluateObjectWithoutPodSpec(t *testing.T) {
+	_, ok, err := EvaluateObject(LevelRestricted, decode(t, "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: a\ndata:\n  spec: x\n"))
+	if err != nil || ok {
+		t.Errorf("EvaluateObject() = %v, %v, want no pod spec", ok, err)
+	}
+}
+
+func TestValidator(t *testing.T) {
+	req := admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+		Namespace: "demo",
+		Object:    runtime.RawExtension{Raw: mustJSON(t, decode(t, deployment))},
+	}}
+
+	resp := (&Validator{Level: LevelBaseline}).Handle(context.Background(), req)
+	if !resp.Allowed || len(resp.Warnings) != 6 {
+		t.Errorf("warn: allowed = %v with %d warnings, want allowed with 6", resp.Allowed, len(resp.Warnings))
+	}
+
+	resp = (&Validator{Level: LevelBaseline, Enforce: true}).Handle(context.Background(), req)
+	if resp.Allowed || !strings.Contains(resp.Result.Message, "spec.template.spec.hostNetwork") {
+		t.Errorf("enforce: allowed = %v, message %q", resp.Allowed, resp.Result.Message)
+	}
+
+	req.Object.Raw = mustJSON(t, decode(t, sidekick))
+	if resp := (&Validator{Level: LevelRestricted, Enforce: true}).Handle(context.Background(), req); !resp.Allowed {
+		t.Errorf("enforce: Sidekick denied: %s", resp.Result.Message)
+	}
+}
+
+func mustJSON(t *testing.T, obj *unstructured.Unstructured) []byte {
+	t.Helper()
+	data, err := obj.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
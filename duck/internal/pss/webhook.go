@@ -0,0 +1,84 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pss
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+var log = logf.Log.WithName("pss")
+
+// WebhookPath is where the validating webhook is served.
+const WebhookPath = "/validate-pod-security"
+
+// +kubebuilder:webhook:path=/validate-pod-security,mutating=false,failurePolicy=ignore,sideEffects=None,groups="";apps;batch;apps.k8s.appscode.com,resources=pods;deployments;statefulsets;daemonsets;jobs;cronjobs;petsets;sidekicks,verbs=create;update,versions=v1;v1alpha1,name=vpodsecurity.duck.dev,admissionReviewVersions=v1
+
+// Validator is an admission.Handler checking the pod spec of the admitted object.
+// Violations are returned as warnings, or deny the request when Enforce is set.
+type Validator struct {
+	Level   Level
+	Enforce bool
+}
+
+var _ admission.Handler = &Validator{}
+
+// SetupWebhookWithManager registers v on the webhook server of mgr.
+func (v *Validator) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	if _, err := ParseLevel(string(v.Level)); err != nil {
+		return err
+	}
+	mgr.GetWebhookServer().Register(WebhookPath, &webhook.Admission{Handler: v})
+	return nil
+}
+
+func (v *Validator) Handle(_ context.Context, req admission.Request) admission.Response {
+	var obj unstructured.Unstructured
+	if err := obj.UnmarshalJSON(req.Object.Raw); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	violations, ok, err := EvaluateObject(v.Level, &obj)
+	if err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	if !ok || len(violations) == 0 {
+		return admission.Allowed("")
+	}
+
+	ref := fmt.Sprintf("%s %s/%s", obj.GetKind(), req.Namespace, obj.GetName())
+	if v.Enforce {
+		msgs := make([]string, 0, len(violations))
+		for _, viol := range violations {
+			msgs = append(msgs, viol.Path+": "+viol.Detail)
+		}
+		log.Info("denied", "object", ref, "level", v.Level, "violations", len(violations))
+		return admission.Denied(fmt.Sprintf("%s violates PodSecurity %q: %s", ref, v.Level, strings.Join(msgs, "; ")))
+	}
+	warnings := make([]string, 0, len(violations))
+	for _, viol := range violations {
+		warnings = append(warnings, fmt.Sprintf("would violate PodSecurity %q: %s", v.Level, viol))
+	}
+	return admission.Allowed("").WithWarnings(warnings...)
+}
@@ -0,0 +1,135 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package selector reasons about label selectors without looking at any objects.
+package selector
+
+import (
+	"strconv"
+
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// Overlaps reports whether some label set can be matched by both a and b.
+// A selector that matches nothing, like labels.Nothing(), overlaps with nothing.
+func Overlaps(a, b labels.Selector) bool {
+	ra, selectable := a.Requirements()
+	if !selectable {
+		return false
+	}
+	rb, selectable := b.Requirements()
+	if !selectable {
+		return false
+	}
+
+	byKey := map[string][]labels.Requirement{}
+	for _, r := range append(ra, rb...) {
+		byKey[r.Key()] = append(byKey[r.Key()], r)
+	}
+	for _, reqs := range byKey {
+		if !satisfiable(reqs) {
+			return false
+		}
+	}
+	return true
+}
+
+// satisfiable reports whether a single label key can be given a value, or be
+// left unset, such that all reqs on that key match.
+func satisfiable(reqs []labels.Requirement) bool {
+	mustExist, mustNotExist := false, false
+	var allowed sets.Set[string] // nil means any value
+	excluded := sets.New[string]()
+	var numeric []labels.Requirement
+
+	for _, r := range reqs {
+		switch r.Operator() {
+		case selection.Exists:
+			mustExist = true
+		case selection.DoesNotExist:
+			mustNotExist = true
+		case selection.Equals, selection.DoubleEquals, selection.In:
+			mustExist = true
+			values := sets.New(r.Values().UnsortedList()...)
+			if allowed == nil {
+				allowed = values
+			} else {
+				allowed = allowed.Intersection(values)
+			}
+		case selection.NotEquals, selection.NotIn:
+			excluded.Insert(r.Values().UnsortedList()...)
+		case selection.GreaterThan, selection.LessThan:
+			mustExist = true
+			numeric = append(numeric, r)
+		}
+	}
+
+	if mustNotExist {
+		// An unset key satisfies NotIn and NotEquals, but nothing that needs a value.
+		return !mustExist
+	}
+	if allowed == nil {
+		// Exists, NotIn and numeric bounds on their own can always be met by some
+		// value, unless the numeric bounds contradict each other.
+		return numericSatisfiable(numeric)
+	}
+	for v := range allowed {
+		if excluded.Has(v) {
+			continue
+		}
+		if matchesNumeric(numeric, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesNumeric(reqs []labels.Requirement, v string) bool {
+	for _, r := range reqs {
+		if !r.Matches(labels.Set{r.Key(): v}) {
+			return false
+		}
+	}
+	return true
+}
+
+func numericSatisfiable(reqs []labels.Requirement) bool {
+	if len(reqs) == 0 {
+		return true
+	}
+	// Gt and Lt are strict, so the largest lower bound must be at least two below
+	// the smallest upper bound.
+	var lower, upper *int64
+	for _, r := range reqs {
+		v, err := strconv.ParseInt(r.Values().UnsortedList()[0], 10, 64)
+		if err != nil {
+			continue
+		}
+		switch r.Operator() {
+		case selection.GreaterThan:
+			if lower == nil || v > *lower {
+				lower = &v
+			}
+		case selection.LessThan:
+			if upper == nil || v < *upper {
+				upper = &v
+			}
+		}
+	}
+	return lower == nil || upper == nil || *upper-*lower >= 2
+}
@@ -0,0 +1,64 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package selector
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+func TestOverlaps(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"app=web", "app=web", true},
+		{"app=web", "app=api", false},
+		{"app=web", "tier=frontend", true},
+		{"app=web,tier=frontend", "app=web,tier=backend", false},
+		{"app in (web,api)", "app in (api,db)", true},
+		{"app in (web,api)", "app notin (web,api)", false},
+		{"app=web", "!app", false},
+		{"!app", "app!=web", true},
+		{"app", "app!=web", true},
+		{"replicas>3", "replicas<5", true},
+		{"replicas>3", "replicas<4", false},
+		{"replicas>3,replicas in (2,3)", "app=web", false},
+		{"", "app=web", true},
+	}
+	for _, tt := range tests {
+		a, err := labels.Parse(tt.a)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := labels.Parse(tt.b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := Overlaps(a, b); got != tt.want {
+			t.Errorf("Overlaps(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+		if got := Overlaps(b, a); got != tt.want {
+			t.Errorf("Overlaps(%q, %q) = %v, want %v", tt.b, tt.a, got, tt.want)
+		}
+	}
+
+	if Overlaps(labels.Nothing(), labels.Everything()) {
+		t.Error("labels.Nothing() must not overlap with anything")
+	}
+}
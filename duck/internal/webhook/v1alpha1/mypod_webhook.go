@@ -0,0 +1,137 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package v1alpha1 serves the defaulting and validating webhooks of MyPod v1alpha1.
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	corev1alpha1 "github.com/ArnobKumarSaha/k8s/api/v1alpha1"
+	"github.com/ArnobKumarSaha/k8s/internal/selector"
+)
+
+// log is for logging in this package.
+var mypodlog = logf.Log.WithName("mypod-resource")
+
+// SetupMyPodWebhookWithManager registers the webhooks for MyPod in the manager.
+func SetupMyPodWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(&corev1alpha1.MyPod{}).
+		WithDefaulter(&corev1alpha1.MyPodCustomDefaulter{}).
+		WithValidator(&MyPodCustomValidator{Reader: mgr.GetClient()}).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/validate-core-duck-dev-v1alpha1-mypod,mutating=false,failurePolicy=fail,sideEffects=None,groups=core.duck.dev,resources=mypods,verbs=create;update,versions=v1alpha1,name=vmypod.kb.io,admissionReviewVersions=v1
+
+// MyPodCustomValidator rejects MyPods whose selector is missing, empty, invalid or
+// overlaps the selector of another MyPod in the same namespace.
+type MyPodCustomValidator struct {
+	Reader client.Reader
+}
+
+var _ webhook.CustomValidator = &MyPodCustomValidator{}
+
+// ValidateCreate implements webhook.CustomValidator so a webhook will be registered for the type
+func (v *MyPodCustomValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	mp, ok := obj.(*corev1alpha1.MyPod)
+	if !ok {
+		return nil, fmt.Errorf("expected a MyPod object but got %T", obj)
+	}
+	mypodlog.Info("validate create", "name", mp.Name)
+
+	return nil, v.validate(ctx, mp, true)
+}
+
+// ValidateUpdate implements webhook.CustomValidator so a webhook will be registered for the type
+func (v *MyPodCustomValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	old, ok := oldObj.(*corev1alpha1.MyPod)
+	if !ok {
+		return nil, fmt.Errorf("expected a MyPod object but got %T", oldObj)
+	}
+	mp, ok := newObj.(*corev1alpha1.MyPod)
+	if !ok {
+		return nil, fmt.Errorf("expected a MyPod object but got %T", newObj)
+	}
+	mypodlog.Info("validate update", "name", mp.Name)
+
+	// Only a changed selector can introduce a new overlap, so status and metadata
+	// updates of MyPods that predate this webhook are let through.
+	return nil, v.validate(ctx, mp, !apiequality.Semantic.DeepEqual(old.Spec.Selector, mp.Spec.Selector))
+}
+
+// ValidateDelete implements webhook.CustomValidator so a webhook will be registered for the type
+func (v *MyPodCustomValidator) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func (v *MyPodCustomValidator) validate(ctx context.Context, mp *corev1alpha1.MyPod, checkOverlap bool) error {
+	fldPath := field.NewPath("spec", "selector")
+
+	allErrs := corev1alpha1.ValidateSelector(mp, fldPath)
+	if len(allErrs) == 0 && checkOverlap {
+		errs, err := v.validateNoOverlap(ctx, mp, fldPath)
+		if err != nil {
+			return apierrors.NewInternalError(err)
+		}
+		allErrs = append(allErrs, errs...)
+	}
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(corev1alpha1.GroupVersion.WithKind("MyPod").GroupKind(), mp.Name, allErrs)
+}
+
+func (v *MyPodCustomValidator) validateNoOverlap(ctx context.Context, mp *corev1alpha1.MyPod, fldPath *field.Path) (field.ErrorList, error) {
+	sel, err := metav1.LabelSelectorAsSelector(mp.Spec.Selector)
+	if err != nil {
+		return field.ErrorList{field.Invalid(fldPath, mp.Spec.Selector, err.Error())}, nil
+	}
+
+	var list corev1alpha1.MyPodList
+	if err := v.Reader.List(ctx, &list, client.InNamespace(mp.Namespace)); err != nil {
+		return nil, err
+	}
+
+	var allErrs field.ErrorList
+	for _, other := range list.Items {
+		if other.Name == mp.Name {
+			continue
+		}
+		otherSel, err := metav1.LabelSelectorAsSelector(other.Spec.Selector)
+		if err != nil {
+			continue
+		}
+		if selector.Overlaps(sel, otherSel) {
+			allErrs = append(allErrs, field.Invalid(fldPath, metav1.FormatLabelSelector(mp.Spec.Selector),
+				fmt.Sprintf("overlaps with selector %q of MyPod %s", metav1.FormatLabelSelector(other.Spec.Selector), other.Name)))
+		}
+	}
+	return allErrs, nil
+}
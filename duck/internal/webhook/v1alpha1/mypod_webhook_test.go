@@ -0,0 +1,86 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	corev1alpha1 "github.com/ArnobKumarSaha/k8s/api/v1alpha1"
+)
+
+// stubReader lists the given MyPods regardless of the list options.
+type stubReader struct {
+	client.Reader
+	items []corev1alpha1.MyPod
+}
+
+func (r *stubReader) List(_ context.Context, list client.ObjectList, _ ...client.ListOption) error {
+	list.(*corev1alpha1.MyPodList).Items = r.items
+	return nil
+}
+
+func newMyPod(name string, sel *metav1.LabelSelector) *corev1alpha1.MyPod {
+	return &corev1alpha1.MyPod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec:       corev1alpha1.MyPodSpec{Selector: sel},
+	}
+}
+
+func TestValidateSelector(t *testing.T) {
+	g := NewWithT(t)
+	v := &MyPodCustomValidator{Reader: &stubReader{items: []corev1alpha1.MyPod{
+		*newMyPod("api", &metav1.LabelSelector{MatchLabels: map[string]string{"app": "api"}}),
+	}}}
+
+	_, err := v.ValidateCreate(context.TODO(), newMyPod("web", nil))
+	g.Expect(apierrors.IsInvalid(err)).To(BeTrue())
+
+	_, err = v.ValidateCreate(context.TODO(), newMyPod("web", &metav1.LabelSelector{}))
+	g.Expect(apierrors.IsInvalid(err)).To(BeTrue())
+
+	_, err = v.ValidateCreate(context.TODO(), newMyPod("web", &metav1.LabelSelector{
+		MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "app", Operator: metav1.LabelSelectorOpIn}},
+	}))
+	g.Expect(apierrors.IsInvalid(err)).To(BeTrue())
+
+	_, err = v.ValidateCreate(context.TODO(), newMyPod("web", &metav1.LabelSelector{
+		MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "app", Operator: metav1.LabelSelectorOpIn, Values: []string{"web", "api"}}},
+	}))
+	g.Expect(apierrors.IsInvalid(err)).To(BeTrue())
+	g.Expect(err.Error()).To(ContainSubstring("MyPod api"))
+
+	_, err = v.ValidateCreate(context.TODO(), newMyPod("web", &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}))
+	g.Expect(err).NotTo(HaveOccurred())
+}
+
+func TestValidateUpdateKeepsSelector(t *testing.T) {
+	g := NewWithT(t)
+	sel := &metav1.LabelSelector{MatchLabels: map[string]string{"app": "api"}}
+	v := &MyPodCustomValidator{Reader: &stubReader{items: []corev1alpha1.MyPod{*newMyPod("api", sel)}}}
+
+	old := newMyPod("api-copy", sel)
+	mp := old.DeepCopy()
+	mp.Labels = map[string]string{"owner": "me"}
+	_, err := v.ValidateUpdate(context.TODO(), old, mp)
+	g.Expect(err).NotTo(HaveOccurred())
+}
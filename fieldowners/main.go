@@ -0,0 +1,173 @@
+// fieldowners prints which field manager owns which field of an object, from its
+// metadata.managedFields. With --watch it follows the object and reports every
+// field that moves between managers, and marks fields that move back and forth,
+// which is what two controllers fighting over a field look like.
+//
+//	go run ./fieldowners -n demo sidekick ace-db-sidekick
+//	go run ./fieldowners -n demo --watch sidekicks.apps.k8s.appscode.com ace-db-sidekick
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/ArnobKumarSaha/k8s/kubeclient"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/klog/v2"
+	"os"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+var (
+	scm = runtime.NewScheme()
+)
+
+func init() {
+	utilruntime.Must(clientgoscheme.AddToScheme(scm))
+}
+
+func main() {
+	var namespace string
+	var watchObj, all bool
+	flag.StringVar(&namespace, "n", "default", "Namespace of the object. Ignored for cluster scoped kinds.")
+	flag.BoolVar(&watchObj, "watch", false, "Follow the object and report fields that change managers.")
+	flag.BoolVar(&all, "all", false, "Also list paths that only lead to owned fields, like .spec.")
+	var kopts kubeclient.Options
+	kopts.AddFlags(flag.CommandLine)
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "Usage: fieldowners [flags] <resource> <name>")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := run(kubeclient.New(kopts, scm), flag.Arg(0), flag.Arg(1), namespace, watchObj, !all); err != nil {
+		klog.Fatalln(err)
+	}
+}
+
+func run(factory *kubeclient.Factory, resource, name, namespace string, watchObj, leavesOnly bool) error {
+	cfg, err := factory.RESTConfig()
+	if err != nil {
+		return err
+	}
+	mapper, err := factory.RESTMapper()
+	if err != nil {
+		return err
+	}
+	kc, err := client.NewWithWatch(cfg, client.Options{Scheme: scm, Mapper: mapper})
+	if err != nil {
+		return err
+	}
+	gvk, err := kubeclient.KindFor(mapper, resource)
+	if err != nil {
+		return err
+	}
+	if namespaced, err := kc.IsObjectNamespaced(&unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": gvk.GroupVersion().String(), "kind": gvk.Kind,
+	}}); err != nil {
+		return err
+	} else if !namespaced {
+		namespace = ""
+	}
+
+	if !watchObj {
+		obj := &unstructured.Unstructured{}
+		obj.SetGroupVersionKind(gvk)
+		if err := kc.Get(context.TODO(), client.ObjectKey{Namespace: namespace, Name: name}, obj); err != nil {
+			return err
+		}
+		rows, err := decodeOwners(obj.GetManagedFields(), leavesOnly)
+		if err != nil {
+			return err
+		}
+		printOwners(rows)
+		return nil
+	}
+	return watchOwners(context.TODO(), kc, gvk, namespace, name, leavesOnly)
+}
+
+func printOwners(rows []fieldOwner) {
+	tw := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "FIELD\tMANAGER\tOPERATION\tSUBRESOURCE\tTIME")
+	for _, r := range rows {
+		t := ""
+		if r.Time != nil {
+			t = r.Time.UTC().Format(time.RFC3339)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", r.Path, r.Manager, r.Operation, r.Subresource, t)
+	}
+	tw.Flush()
+}
+
+// watchOwners prints the owners of the current version, then one line per field
+// whose owners change, until the watch ends.
+func watchOwners(ctx context.Context, kc client.WithWatch, gvk schema.GroupVersionKind, namespace, name string, leavesOnly bool) error {
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+	w, err := kc.Watch(ctx, list, client.InNamespace(namespace),
+		client.MatchingFieldsSelector{Selector: fields.OneTermEqualSelector("metadata.name", name)})
+	if err != nil {
+		return err
+	}
+	defer w.Stop()
+
+	tracker := newOwnershipTracker()
+	first := true
+	for ev := range w.ResultChan() {
+		switch ev.Type {
+		case watch.Added, watch.Modified:
+		case watch.Deleted:
+			fmt.Printf("%s/%s deleted\n", namespace, name)
+			return nil
+		case watch.Error:
+			return fmt.Errorf("watch failed: %v", ev.Object)
+		default:
+			continue
+		}
+		obj := ev.Object.(*unstructured.Unstructured)
+		rows, err := decodeOwners(obj.GetManagedFields(), leavesOnly)
+		if err != nil {
+			return err
+		}
+		changes := tracker.observe(ownersByPath(rows))
+		if first {
+			first = false
+			fmt.Printf("resourceVersion %s\n", obj.GetResourceVersion())
+			printOwners(rows)
+			fmt.Println()
+			continue
+		}
+		for _, c := range changes {
+			line := fmt.Sprintf("rv=%s  %s  %s -> %s", obj.GetResourceVersion(), c.Path, orNone(c.From), orNone(c.To))
+			if c.Alternating {
+				history := make([]string, len(c.History))
+				for i, o := range c.History {
+					history[i] = orNone(o)
+				}
+				line += "  ALTERNATING " + strings.Join(history, " -> ")
+			}
+			fmt.Println(line)
+		}
+	}
+	return nil
+}
+
+func orNone(owners string) string {
+	if owners == "" {
+		return "<none>"
+	}
+	return owners
+}
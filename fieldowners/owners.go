@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/structured-merge-diff/v4/fieldpath"
+	"sort"
+	"strings"
+)
+
+// fieldOwner is one manager owning a field path.
+type fieldOwner struct {
+	Path        string
+	Manager     string
+	Operation   metav1.ManagedFieldsOperationType
+	Subresource string
+	Time        *metav1.Time
+}
+
+// decodeOwners flattens managedFields into one row per manager and field path.
+// With leavesOnly, paths that only lead to owned fields, like .spec, are left out.
+func decodeOwners(entries []metav1.ManagedFieldsEntry, leavesOnly bool) ([]fieldOwner, error) {
+	var out []fieldOwner
+	for _, e := range entries {
+		if e.FieldsV1 == nil {
+			continue
+		}
+		set := &fieldpath.Set{}
+		if err := set.FromJSON(bytes.NewReader(e.FieldsV1.Raw)); err != nil {
+			return nil, fmt.Errorf("invalid managedFields of %s: %w", e.Manager, err)
+		}
+		if leavesOnly {
+			set = set.Leaves()
+		}
+		set.Iterate(func(p fieldpath.Path) {
+			out = append(out, fieldOwner{
+				Path:        p.String(),
+				Manager:     e.Manager,
+				Operation:   e.Operation,
+				Subresource: e.Subresource,
+				Time:        e.Time,
+			})
+		})
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Path != out[j].Path {
+			return out[i].Path < out[j].Path
+		}
+		return out[i].Manager < out[j].Manager
+	})
+	return out, nil
+}
+
+// ownersByPath joins the managers of every path, e.g. "helm,kubedb".
+func ownersByPath(rows []fieldOwner) map[string]string {
+	managers := map[string][]string{}
+	for _, r := range rows {
+		managers[r.Path] = append(managers[r.Path], r.Manager)
+	}
+	out := make(map[string]string, len(managers))
+	for p, m := range managers {
+		sort.Strings(m)
+		out[p] = strings.Join(m, ",")
+	}
+	return out
+}
+
+// ownershipTracker follows the owners of every path across versions of an
+// object. A path whose owners change back to owners it had before is fought over.
+type ownershipTracker struct {
+	history map[string][]string
+	seen    bool
+}
+
+type ownershipChange struct {
+	Path        string
+	From, To    string
+	Alternating bool
+	// History lists the owners of the path so far, oldest first.
+	History []string
+}
+
+func newOwnershipTracker() *ownershipTracker {
+	return &ownershipTracker{history: map[string][]string{}}
+}
+
+// observe records the owners of one version and returns the paths whose owners
+// changed since the previous version. The first version sets the baseline.
+func (t *ownershipTracker) observe(owners map[string]string) []ownershipChange {
+	first := !t.seen
+	t.seen = true
+	var changes []ownershipChange
+
+	paths := make([]string, 0, len(owners)+len(t.history))
+	for p := range owners {
+		paths = append(paths, p)
+	}
+	for p := range t.history {
+		if _, ok := owners[p]; !ok {
+			paths = append(paths, p)
+		}
+	}
+	sort.Strings(paths)
+
+	for _, p := range paths {
+		h := t.history[p]
+		cur := owners[p] // empty once nobody owns the path
+		prev := ""
+		if len(h) > 0 {
+			prev = h[len(h)-1]
+		}
+		if cur == prev && len(h) > 0 {
+			continue
+		}
+		if cur == "" && len(h) == 0 {
+			continue
+		}
+		// A path that nobody owned for a while is not fought over when it
+		// comes back to its last owner.
+		last := ""
+		for i := len(h) - 1; i >= 0 && last == ""; i-- {
+			last = h[i]
+		}
+		alternating := false
+		for _, o := range h {
+			if o == cur && cur != "" && cur != last {
+				alternating = true
+				break
+			}
+		}
+		t.history[p] = append(h, cur)
+		if !first {
+			changes = append(changes, ownershipChange{
+				Path:        p,
+				From:        prev,
+				To:          cur,
+				Alternating: alternating,
+				History:     append([]string(nil), t.history[p]...),
+			})
+		}
+	}
+	return changes
+}
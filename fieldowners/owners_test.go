@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDecodeOwners(t *testing.T) {
+	entry := func(manager, fields string) metav1.ManagedFieldsEntry {
+		e := metav1.ManagedFieldsEntry{Manager: manager, Operation: metav1.ManagedFieldsOperationApply}
+		if fields != "" {
+			e.FieldsV1 = &metav1.FieldsV1{Raw: []byte(fields)}
+		}
+		return e
+	}
+	cases := []struct {
+		name       string
+		entries    []metav1.ManagedFieldsEntry
+		leavesOnly bool
+		// want lists path=manager, sorted by path and manager.
+		want    []string
+		wantErr string
+	}{
+		{
+			name: "paths of every manager",
+			entries: []metav1.ManagedFieldsEntry{
+				entry("kubedb", `{"f:spec":{"f:replicas":{}}}`),
+				entry("helm", `{"f:metadata":{"f:labels":{".":{},"f:app":{}}},"f:spec":{"f:replicas":{}}}`),
+			},
+			want: []string{
+				".metadata.labels=helm",
+				".metadata.labels.app=helm",
+				".spec.replicas=helm",
+				".spec.replicas=kubedb",
+			},
+		},
+		{
+			name:       "leaves only",
+			entries:    []metav1.ManagedFieldsEntry{entry("helm", `{"f:metadata":{"f:labels":{".":{},"f:app":{}}}}`)},
+			leavesOnly: true,
+			want:       []string{".metadata.labels.app=helm"},
+		},
+		{
+			name:    "entries without fields are skipped",
+			entries: []metav1.ManagedFieldsEntry{entry("kubectl", ""), entry("kubedb", `{"f:spec":{"f:replicas":{}}}`)},
+			want:    []string{".spec.replicas=kubedb"},
+		},
+		{
+			name:    "invalid fields",
+			entries: []metav1.ManagedFieldsEntry{entry("kubedb", `{"f:spec":`)},
+			wantErr: "invalid managedFields of kubedb",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			rows, err := decodeOwners(c.entries, c.leavesOnly)
+			if c.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), c.wantErr) {
+					t.Fatalf("decodeOwners() error = %v, want %s", err, c.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, r := range rows {
+				got = append(got, r.Path+"="+r.Manager)
+			}
+			if !reflect.DeepEqual(got, c.want) {
+				t.Errorf("decodeOwners() = %q, want %q", got, c.want)
+			}
+		})
+	}
+}
+
+func TestOwnershipTracker(t *testing.T) {
+	cases := []struct {
+		name string
+		// versions are the owners of .spec.replicas in each version of the
+		// object, "" if nobody owns it.
+		versions []string
+		// want lists from>to and whether the change alternates, for each
+		// version after the first.
+		want []string
+	}{
+		{
+			name:     "taken back",
+			versions: []string{"helm", "kubedb", "helm"},
+			want:     []string{"helm>kubedb false", "kubedb>helm true"},
+		},
+		{
+			name:     "handed over",
+			versions: []string{"helm", "kubedb", "kubedb"},
+			want:     []string{"helm>kubedb false"},
+		},
+		{
+			name:     "unowned and back to its owner",
+			versions: []string{"helm", "", "helm"},
+			want:     []string{"helm> false", ">helm false"},
+		},
+		{
+			name:     "unowned and taken back",
+			versions: []string{"helm", "kubedb", "", "helm"},
+			want:     []string{"helm>kubedb false", "kubedb> false", ">helm true"},
+		},
+		{
+			name:     "owned after the first version",
+			versions: []string{"", "helm"},
+			want:     []string{">helm false"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			tracker := newOwnershipTracker()
+			var got []string
+			for i, owner := range c.versions {
+				owners := map[string]string{}
+				if owner != "" {
+					owners[".spec.replicas"] = owner
+				}
+				changes := tracker.observe(owners)
+				if i == 0 && len(changes) != 0 {
+					t.Errorf("first version has changes %+v, want none", changes)
+				}
+				for _, ch := range changes {
+					if ch.Path != ".spec.replicas" {
+						t.Errorf("change of %s, want .spec.replicas", ch.Path)
+					}
+					got = append(got, fmt.Sprintf("%s>%s %v", ch.From, ch.To, ch.Alternating))
+				}
+			}
+			if !reflect.DeepEqual(got, c.want) {
+				t.Errorf("changes = %q, want %q", got, c.want)
+			}
+		})
+	}
+}
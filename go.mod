@@ -3,6 +3,8 @@ module github.com/ArnobKumarSaha/k8s
 go 1.23.1
 
 require (
+	github.com/Masterminds/semver/v3 v3.3.0
+	github.com/go-sql-driver/mysql v1.8.1
 	go.bytebuilders.dev/catalog v0.0.8
 	gomodules.xyz/jsonpatch/v2 v2.4.0
 	k8s.io/api v0.30.2
@@ -11,16 +13,21 @@ require (
 	k8s.io/klog/v2 v2.130.1
 	k8s.io/utils v0.0.0-20240502163921-fe8a2dddb1d0
 	kmodules.xyz/client-go v0.30.22-0.20241009083138-319b68c14b29
+	kmodules.xyz/custom-resources v0.30.0
+	kmodules.xyz/objectstore-api v0.29.1
+	kmodules.xyz/offshoot-api v0.30.1
+	kmodules.xyz/resource-metadata v0.18.15
 	kubedb.dev/apimachinery v0.48.1-0.20241008042127-489a1e4bab29
 	kubeops.dev/petset v0.0.7
 	kubeops.dev/sidekick v0.0.8
+	kubestash.dev/apimachinery v0.13.0
 	sigs.k8s.io/controller-runtime v0.18.4
+	sigs.k8s.io/structured-merge-diff/v4 v4.4.1
 	sigs.k8s.io/yaml v1.4.0
 )
 
 require (
 	filippo.io/edwards25519 v1.1.0 // indirect
-	github.com/Masterminds/semver/v3 v3.3.0 // indirect
 	github.com/beorn7/perks v1.0.1 // indirect
 	github.com/blang/semver/v4 v4.0.0 // indirect
 	github.com/cert-manager/cert-manager v1.15.1 // indirect
@@ -37,7 +44,6 @@ require (
 	github.com/go-openapi/jsonpointer v0.21.0 // indirect
 	github.com/go-openapi/jsonreference v0.21.0 // indirect
 	github.com/go-openapi/swag v0.23.0 // indirect
-	github.com/go-sql-driver/mysql v1.8.1 // indirect
 	github.com/gogo/protobuf v1.3.2 // indirect
 	github.com/golang/groupcache v0.0.0-20210331224755-41bb18bfe9da // indirect
 	github.com/golang/protobuf v1.5.4 // indirect
@@ -89,17 +95,11 @@ require (
 	k8s.io/component-base v0.30.2 // indirect
 	k8s.io/kube-openapi v0.0.0-20240703190633-0aa61b46e8c2 // indirect
 	kmodules.xyz/apiversion v0.2.0 // indirect
-	kmodules.xyz/custom-resources v0.30.0 // indirect
 	kmodules.xyz/monitoring-agent-api v0.30.1 // indirect
-	kmodules.xyz/objectstore-api v0.29.1 // indirect
-	kmodules.xyz/offshoot-api v0.30.1 // indirect
 	kmodules.xyz/prober v0.29.0 // indirect
-	kmodules.xyz/resource-metadata v0.18.15 // indirect
-	kubestash.dev/apimachinery v0.13.0 // indirect
 	kubevault.dev/apimachinery v0.18.3 // indirect
 	sigs.k8s.io/gateway-api v1.1.0 // indirect
 	sigs.k8s.io/json v0.0.0-20221116044647-bc3834ca7abd // indirect
-	sigs.k8s.io/structured-merge-diff/v4 v4.4.1 // indirect
 )
 
 replace kmodules.xyz/client-go => ../../../kmodules.xyz/client-go
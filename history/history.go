@@ -0,0 +1,60 @@
+package main
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	kmapi "kmodules.xyz/client-go/api/v1"
+	"time"
+)
+
+// entry is one observed state of a condition.
+type entry struct {
+	kmapi.Condition `json:",inline"`
+	// RecordedAt is when the recorder saw the state, which can be later than
+	// LastTransitionTime if the recorder was not running at the time.
+	RecordedAt metav1.Time `json:"recordedAt"`
+}
+
+// history holds the latest condition transitions of one object, oldest first.
+type history struct {
+	APIGroup  string  `json:"apiGroup"`
+	Kind      string  `json:"kind"`
+	Namespace string  `json:"namespace"`
+	Name      string  `json:"name"`
+	Entries   []entry `json:"entries"`
+	// DeletedAt is when the recorder found the object gone. The history is
+	// deleted once it is older than the retention of the recorder.
+	DeletedAt *metav1.Time `json:"deletedAt,omitempty"`
+}
+
+func newHistory(gk schema.GroupKind, namespace, name string) *history {
+	return &history{APIGroup: gk.Group, Kind: gk.Kind, Namespace: namespace, Name: name}
+}
+
+// record appends every condition that differs from the last recorded state of
+// its type, and then drops the oldest entries beyond maxEntries. It reports whether
+// anything was appended.
+func (h *history) record(conds []kmapi.Condition, now time.Time, maxEntries int) bool {
+	last := map[kmapi.ConditionType]*kmapi.Condition{}
+	for i := range h.Entries {
+		last[h.Entries[i].Type] = &h.Entries[i].Condition
+	}
+
+	var changed bool
+	for _, c := range conds {
+		// ObservedGeneration moves with every spec change, so it alone is no transition.
+		if prev, ok := last[c.Type]; ok &&
+			prev.Status == c.Status &&
+			prev.Reason == c.Reason &&
+			prev.Message == c.Message &&
+			prev.LastTransitionTime.Equal(&c.LastTransitionTime) {
+			continue
+		}
+		h.Entries = append(h.Entries, entry{Condition: c, RecordedAt: metav1.NewTime(now)})
+		changed = true
+	}
+	if n := len(h.Entries); maxEntries > 0 && n > maxEntries {
+		h.Entries = append([]entry(nil), h.Entries[n-maxEntries:]...)
+	}
+	return changed
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	kmapi "kmodules.xyz/client-go/api/v1"
+	dbapi "kubedb.dev/apimachinery/apis/kubedb/v1"
+	"reflect"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"testing"
+	"time"
+)
+
+var t0 = time.Date(2024, 10, 1, 12, 0, 0, 0, time.UTC)
+
+func condition(typ kmapi.ConditionType, status metav1.ConditionStatus, reason string, at time.Time) kmapi.Condition {
+	return kmapi.Condition{Type: typ, Status: status, Reason: reason, LastTransitionTime: metav1.NewTime(at)}
+}
+
+func TestRecord(t *testing.T) {
+	ready := condition("Ready", metav1.ConditionFalse, "Provisioning", t0)
+	cases := []struct {
+		name       string
+		recorded   []kmapi.Condition
+		conds      []kmapi.Condition
+		maxEntries int
+		// want lists type=status/reason of the entries after the record.
+		want        []string
+		wantChanged bool
+	}{
+		{
+			name:        "first state",
+			conds:       []kmapi.Condition{ready},
+			want:        []string{"Ready=False/Provisioning"},
+			wantChanged: true,
+		},
+		{
+			name:     "unchanged",
+			recorded: []kmapi.Condition{ready},
+			conds:    []kmapi.Condition{ready},
+			want:     []string{"Ready=False/Provisioning"},
+		},
+		{
+			name:     "only the observed generation moved",
+			recorded: []kmapi.Condition{ready},
+			conds: func() []kmapi.Condition {
+				c := ready
+				c.ObservedGeneration = 2
+				return []kmapi.Condition{c}
+			}(),
+			want: []string{"Ready=False/Provisioning"},
+		},
+		{
+			name:        "transition",
+			recorded:    []kmapi.Condition{ready},
+			conds:       []kmapi.Condition{condition("Ready", metav1.ConditionTrue, "Ready", t0.Add(time.Minute))},
+			want:        []string{"Ready=False/Provisioning", "Ready=True/Ready"},
+			wantChanged: true,
+		},
+		{
+			name:     "same state again after a transition",
+			recorded: []kmapi.Condition{ready, condition("Ready", metav1.ConditionTrue, "Ready", t0.Add(time.Minute))},
+			conds: []kmapi.Condition{
+				condition("Ready", metav1.ConditionTrue, "Ready", t0.Add(time.Minute)),
+				condition("Provisioned", metav1.ConditionTrue, "", t0.Add(time.Minute)),
+			},
+			want:        []string{"Ready=False/Provisioning", "Ready=True/Ready", "Provisioned=True/"},
+			wantChanged: true,
+		},
+		{
+			name:        "oldest entries are dropped",
+			recorded:    []kmapi.Condition{ready, condition("Ready", metav1.ConditionTrue, "Ready", t0.Add(time.Minute))},
+			conds:       []kmapi.Condition{condition("Ready", metav1.ConditionFalse, "Halted", t0.Add(2*time.Minute))},
+			maxEntries:  2,
+			want:        []string{"Ready=True/Ready", "Ready=False/Halted"},
+			wantChanged: true,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			h := newHistory(dbapi.SchemeGroupVersion.WithKind("Postgres").GroupKind(), "demo", "pg")
+			for _, r := range c.recorded {
+				h.record([]kmapi.Condition{r}, t0, 0)
+			}
+			changed := h.record(c.conds, t0.Add(time.Hour), c.maxEntries)
+			var got []string
+			for _, e := range h.Entries {
+				got = append(got, string(e.Type)+"="+string(e.Status)+"/"+e.Reason)
+			}
+			if !reflect.DeepEqual(got, c.want) || changed != c.wantChanged {
+				t.Errorf("record() = %v with entries %q, want %v with %q", changed, got, c.wantChanged, c.want)
+			}
+		})
+	}
+}
+
+func TestRecorderDeleted(t *testing.T) {
+	gvk := dbapi.SchemeGroupVersion.WithKind("Postgres")
+	key := types.NamespacedName{Namespace: "demo", Name: "pg"}
+	req := ctrl.Request{NamespacedName: key}
+	store := &fileStore{dir: t.TempDir()}
+	ctx := context.TODO()
+
+	history := func() *history {
+		t.Helper()
+		histories, err := store.List(ctx, "demo")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(histories) == 0 {
+			return nil
+		}
+		return histories[0]
+	}
+
+	pg := &unstructured.Unstructured{}
+	pg.SetGroupVersionKind(gvk)
+	pg.SetNamespace(key.Namespace)
+	pg.SetName(key.Name)
+	_ = unstructured.SetNestedSlice(pg.Object, []interface{}{map[string]interface{}{
+		"type":               "Ready",
+		"status":             "True",
+		"lastTransitionTime": t0.Format(time.RFC3339),
+	}}, "status", "conditions")
+	r := &recorder{
+		Client:    fake.NewClientBuilder().WithScheme(scm).WithObjects(pg).Build(),
+		gvk:       gvk,
+		store:     store,
+		retention: time.Hour,
+	}
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatal(err)
+	}
+	if h := history(); h == nil || len(h.Entries) != 1 || h.DeletedAt != nil {
+		t.Fatalf("history = %+v, want one entry", h)
+	}
+
+	if err := r.Delete(ctx, pg); err != nil {
+		t.Fatal(err)
+	}
+	res, err := r.expire(ctx, req, t0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h := history(); h == nil || h.DeletedAt == nil || !h.DeletedAt.Time.Equal(t0) {
+		t.Fatalf("history = %+v, want it marked deleted at %s", h, t0)
+	}
+	if res.RequeueAfter != time.Hour {
+		t.Errorf("requeue after %s, want the retention of 1h", res.RequeueAfter)
+	}
+
+	// A later event before the retention passed keeps the first deletion time.
+	if res, err = r.expire(ctx, req, t0.Add(40*time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if res.RequeueAfter != 20*time.Minute || history() == nil {
+		t.Errorf("requeue after %s, want the 20m left of the retention", res.RequeueAfter)
+	}
+
+	if _, err = r.expire(ctx, req, t0.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if h := history(); h != nil {
+		t.Errorf("history = %+v, want it deleted after the retention", h)
+	}
+
+	// There is no history left to mark.
+	if res, err = r.expire(ctx, req, t0.Add(2*time.Hour)); err != nil || res.RequeueAfter != 0 || history() != nil {
+		t.Errorf("expire() = %+v, %v and history %+v, want nothing to do", res, err, history())
+	}
+}
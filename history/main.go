@@ -0,0 +1,157 @@
+// history keeps the transitions of the status conditions of KubeDB databases and
+// catalog bindings. conditions.SetCondition replaces a condition in place, so an
+// object only ever shows its latest state; the recorder keeps the states before.
+//
+//	go run ./history --context=kind-kind record [--store=configmap|file] [--dir=...] [--max-entries=100] [--retention=168h]
+//	go run ./history timeline -n demo [mongodbbinding [mg-binding]]
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/ArnobKumarSaha/k8s/kubeclient"
+	bapi "go.bytebuilders.dev/catalog/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/klog/v2"
+	dbapi "kubedb.dev/apimachinery/apis/kubedb/v1"
+	kubedbscheme "kubedb.dev/apimachinery/client/clientset/versioned/scheme"
+	"os"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+	"time"
+)
+
+var (
+	scm = runtime.NewScheme()
+)
+
+func init() {
+	utilruntime.Must(clientgoscheme.AddToScheme(scm))
+	utilruntime.Must(kubedbscheme.AddToScheme(scm))
+	utilruntime.Must(bapi.AddToScheme(scm))
+}
+
+type storeOptions struct {
+	kind string
+	dir  string
+}
+
+func (o *storeOptions) addFlags(fs *flag.FlagSet) {
+	fs.StringVar(&o.kind, "store", "configmap", "Where histories are kept: configmap, a ConfigMap named "+configMapName+" per namespace, or file.")
+	fs.StringVar(&o.dir, "dir", ".condition-history", "Directory of the file store.")
+}
+
+func (o *storeOptions) newStore(reader client.Reader, writer client.Client) (historyStore, error) {
+	switch o.kind {
+	case "configmap":
+		return &configMapStore{reader: reader, writer: writer}, nil
+	case "file":
+		return &fileStore{dir: o.dir}, nil
+	}
+	return nil, fmt.Errorf("unknown store %q, expected configmap or file", o.kind)
+}
+
+var kopts kubeclient.Options
+
+func main() {
+	kopts.AddFlags(flag.CommandLine)
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "Usage: history [flags] record|timeline [args]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var err error
+	switch flag.Arg(0) {
+	case "record":
+		err = runRecord(flag.Args()[1:])
+	case "timeline":
+		err = runTimeline(flag.Args()[1:])
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		klog.Fatalln(err)
+	}
+}
+
+func runRecord(args []string) error {
+	fs := flag.NewFlagSet("record", flag.ExitOnError)
+	var so storeOptions
+	so.addFlags(fs)
+	namespace := fs.String("n", "", "Only record objects of this namespace.")
+	maxEntries := fs.Int("max-entries", 100, "Number of entries kept per object, oldest are dropped first.")
+	retention := fs.Duration("retention", 7*24*time.Hour, "How long the history of a deleted object is kept. 0 deletes it with the object.")
+	_ = fs.Parse(args)
+
+	ctrl.SetLogger(klog.NewKlogr())
+	opts := ctrl.Options{
+		Scheme:  scm,
+		Metrics: metricsserver.Options{BindAddress: "0"},
+	}
+	if *namespace != "" {
+		opts.Cache.DefaultNamespaces = map[string]cache.Config{*namespace: {}}
+	}
+	cfg, err := kubeclient.New(kopts, scm).RESTConfig()
+	if err != nil {
+		return err
+	}
+	mgr, err := ctrl.NewManager(cfg, opts)
+	if err != nil {
+		return err
+	}
+	// The ConfigMaps are read directly, so the manager does not cache every
+	// ConfigMap of the cluster.
+	store, err := so.newStore(mgr.GetAPIReader(), mgr.GetClient())
+	if err != nil {
+		return err
+	}
+	if err := setupRecorders(mgr, store, *maxEntries, *retention, dbapi.SchemeGroupVersion, bapi.GroupVersion); err != nil {
+		return err
+	}
+	return mgr.Start(ctrl.SetupSignalHandler())
+}
+
+func runTimeline(args []string) error {
+	fs := flag.NewFlagSet("timeline", flag.ExitOnError)
+	var so storeOptions
+	so.addFlags(fs)
+	namespace := fs.String("n", "default", "Namespace of the objects.")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: history timeline [flags] [kind [name]]")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+	if fs.NArg() > 2 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	var kc client.Client
+	if so.kind == "configmap" {
+		var err error
+		if kc, err = kubeclient.New(kopts, scm).Client(); err != nil {
+			return err
+		}
+	}
+	store, err := so.newStore(kc, kc)
+	if err != nil {
+		return err
+	}
+	histories, err := store.List(context.TODO(), *namespace)
+	if err != nil {
+		return err
+	}
+	printTimeline(os.Stdout, histories, fs.Arg(0), fs.Arg(1))
+	return nil
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/klog/v2"
+	kmapi "kmodules.xyz/client-go/api/v1"
+	"reflect"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sort"
+	"strings"
+	"time"
+)
+
+// recorder appends the condition transitions of one kind to the history store.
+type recorder struct {
+	client.Client
+	gvk        schema.GroupVersionKind
+	store      historyStore
+	maxEntries int
+	// retention is how long the history of a deleted object is kept.
+	retention time.Duration
+}
+
+func (r *recorder) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(r.gvk)
+	if err := r.Get(ctx, req.NamespacedName, obj); err != nil {
+		if apierrors.IsNotFound(err) {
+			return r.expire(ctx, req, time.Now())
+		}
+		return ctrl.Result{}, err
+	}
+	conds, err := readConditions(obj)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if len(conds) == 0 {
+		return ctrl.Result{}, nil
+	}
+	err = r.store.Update(ctx, r.gvk.GroupKind(), req.Namespace, req.Name, func(h *history) bool {
+		// The object was deleted and created again under the same name.
+		recreated := h.DeletedAt != nil
+		h.DeletedAt = nil
+		return h.record(conds, time.Now(), r.maxEntries) || recreated
+	})
+	return ctrl.Result{}, err
+}
+
+// expire marks the history of a deleted object, and deletes it once it is older
+// than the retention. Objects deleted while the recorder was not running are
+// not seen, their histories stay until removed by hand.
+func (r *recorder) expire(ctx context.Context, req ctrl.Request, now time.Time) (ctrl.Result, error) {
+	gk := r.gvk.GroupKind()
+	var deletedAt *metav1.Time
+	err := r.store.Update(ctx, gk, req.Namespace, req.Name, func(h *history) bool {
+		if len(h.Entries) == 0 || h.DeletedAt != nil {
+			deletedAt = h.DeletedAt
+			return false
+		}
+		h.DeletedAt = &metav1.Time{Time: now}
+		deletedAt = h.DeletedAt
+		return true
+	})
+	if err != nil || deletedAt == nil {
+		return ctrl.Result{}, err
+	}
+	if left := deletedAt.Add(r.retention).Sub(now); left > 0 {
+		return ctrl.Result{RequeueAfter: left}, nil
+	}
+	return ctrl.Result{}, r.store.Delete(ctx, gk, req.Namespace, req.Name)
+}
+
+func readConditions(obj *unstructured.Unstructured) ([]kmapi.Condition, error) {
+	raw, ok, err := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	if !ok || err != nil {
+		return nil, err
+	}
+	conds := make([]kmapi.Condition, 0, len(raw))
+	for _, item := range raw {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("invalid condition %v", item)
+		}
+		var c kmapi.Condition
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(m, &c); err != nil {
+			return nil, err
+		}
+		conds = append(conds, c)
+	}
+	return conds, nil
+}
+
+var conditionsType = reflect.TypeOf(kmapi.Conditions{})
+
+// hasConditions reports whether t, a type registered in the scheme, has a
+// status.conditions field of kmapi conditions.
+func hasConditions(t reflect.Type) bool {
+	status, ok := t.FieldByName("Status")
+	if !ok || status.Type.Kind() != reflect.Struct {
+		return false
+	}
+	conds, ok := status.Type.FieldByName("Conditions")
+	return ok && conds.Type.ConvertibleTo(conditionsType)
+}
+
+// recordedKinds returns the namespaced kinds of the given group versions that
+// have kmapi conditions and are served by the cluster.
+func recordedKinds(mapper meta.RESTMapper, gvs ...schema.GroupVersion) []schema.GroupVersionKind {
+	var out []schema.GroupVersionKind
+	for _, gv := range gvs {
+		for kind, t := range scm.KnownTypes(gv) {
+			if strings.HasSuffix(kind, "List") || !hasConditions(t) {
+				continue
+			}
+			gvk := gv.WithKind(kind)
+			m, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+			if err != nil {
+				klog.V(2).Infof("skipping %s: %v", gvk, err)
+				continue
+			}
+			if m.Scope.Name() != meta.RESTScopeNameNamespace {
+				continue
+			}
+			out = append(out, gvk)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].String() < out[j].String() })
+	return out
+}
+
+func setupRecorders(mgr ctrl.Manager, store historyStore, maxEntries int, retention time.Duration, gvs ...schema.GroupVersion) error {
+	kinds := recordedKinds(mgr.GetRESTMapper(), gvs...)
+	if len(kinds) == 0 {
+		return fmt.Errorf("none of %v is served by the cluster", gvs)
+	}
+	for _, gvk := range kinds {
+		obj := &unstructured.Unstructured{}
+		obj.SetGroupVersionKind(gvk)
+		err := ctrl.NewControllerManagedBy(mgr).
+			Named(strings.ToLower(gvk.Kind) + "." + gvk.Group).
+			For(obj).
+			Complete(&recorder{Client: mgr.GetClient(), gvk: gvk, store: store, maxEntries: maxEntries, retention: retention})
+		if err != nil {
+			return err
+		}
+		klog.Infof("recording conditions of %s", gvk)
+	}
+	return nil
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	core "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"os"
+	"path/filepath"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"strings"
+)
+
+// historyStore keeps one history per object.
+type historyStore interface {
+	// Update loads the history of an object, or a new empty one, passes it to fn
+	// and saves it if fn returns true.
+	Update(ctx context.Context, gk schema.GroupKind, namespace, name string, fn func(h *history) bool) error
+	// Delete removes the history of an object, if there is one.
+	Delete(ctx context.Context, gk schema.GroupKind, namespace, name string) error
+	// List returns the histories of all objects in namespace.
+	List(ctx context.Context, namespace string) ([]*history, error)
+}
+
+// configMapName is the ConfigMap holding the histories of a namespace.
+const configMapName = "condition-history"
+
+// configMapStore keeps the histories of a namespace in a ConfigMap of that
+// namespace, one JSON document per object. ConfigMaps are limited to 1MiB, so
+// keep max-entries low when many objects share a namespace.
+type configMapStore struct {
+	reader client.Reader
+	writer client.Client
+}
+
+func historyKey(gk schema.GroupKind, name string) string {
+	return strings.ToLower(gk.Kind) + "." + gk.Group + "." + name
+}
+
+func (s *configMapStore) Update(ctx context.Context, gk schema.GroupKind, namespace, name string, fn func(h *history) bool) error {
+	key := historyKey(gk, name)
+	// Recorders of different kinds share the ConfigMap of a namespace, so an
+	// update can conflict. Retry with the latest copy a few times.
+	var err error
+	for i := 0; i < 5; i++ {
+		if err = s.update(ctx, gk, namespace, name, key, fn); !apierrors.IsConflict(err) && !apierrors.IsAlreadyExists(err) {
+			return err
+		}
+	}
+	return err
+}
+
+func (s *configMapStore) update(ctx context.Context, gk schema.GroupKind, namespace, name, key string, fn func(h *history) bool) error {
+	var cm core.ConfigMap
+	err := s.reader.Get(ctx, client.ObjectKey{Namespace: namespace, Name: configMapName}, &cm)
+	create := apierrors.IsNotFound(err)
+	if err != nil && !create {
+		return err
+	}
+
+	h := newHistory(gk, namespace, name)
+	if data, ok := cm.Data[key]; ok {
+		if err := json.Unmarshal([]byte(data), h); err != nil {
+			return fmt.Errorf("invalid history %s in ConfigMap %s/%s: %w", key, namespace, configMapName, err)
+		}
+	}
+	if !fn(h) {
+		return nil
+	}
+	data, err := json.Marshal(h)
+	if err != nil {
+		return err
+	}
+	if cm.Data == nil {
+		cm.Data = map[string]string{}
+	}
+	cm.Data[key] = string(data)
+
+	if create {
+		cm.ObjectMeta = metav1.ObjectMeta{Name: configMapName, Namespace: namespace}
+		return s.writer.Create(ctx, &cm)
+	}
+	return s.writer.Update(ctx, &cm)
+}
+
+func (s *configMapStore) Delete(ctx context.Context, gk schema.GroupKind, namespace, name string) error {
+	key := historyKey(gk, name)
+	var err error
+	for i := 0; i < 5; i++ {
+		var cm core.ConfigMap
+		if err = s.reader.Get(ctx, client.ObjectKey{Namespace: namespace, Name: configMapName}, &cm); err != nil {
+			return client.IgnoreNotFound(err)
+		}
+		if _, ok := cm.Data[key]; !ok {
+			return nil
+		}
+		delete(cm.Data, key)
+		if err = s.writer.Update(ctx, &cm); !apierrors.IsConflict(err) {
+			return err
+		}
+	}
+	return err
+}
+
+func (s *configMapStore) List(ctx context.Context, namespace string) ([]*history, error) {
+	var cm core.ConfigMap
+	if err := s.reader.Get(ctx, client.ObjectKey{Namespace: namespace, Name: configMapName}, &cm); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	out := make([]*history, 0, len(cm.Data))
+	for key, data := range cm.Data {
+		var h history
+		if err := json.Unmarshal([]byte(data), &h); err != nil {
+			return nil, fmt.Errorf("invalid history %s in ConfigMap %s/%s: %w", key, namespace, configMapName, err)
+		}
+		out = append(out, &h)
+	}
+	return out, nil
+}
+
+// fileStore keeps every history in its own file, at
+// <dir>/<namespace>/<kind>.<group>.<name>.json.
+type fileStore struct {
+	dir string
+}
+
+func (s *fileStore) path(gk schema.GroupKind, namespace, name string) string {
+	return filepath.Join(s.dir, namespace, historyKey(gk, name)+".json")
+}
+
+func (s *fileStore) Update(_ context.Context, gk schema.GroupKind, namespace, name string, fn func(h *history) bool) error {
+	path := s.path(gk, namespace, name)
+	h := newHistory(gk, namespace, name)
+	data, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(data, h); err != nil {
+			return fmt.Errorf("invalid history %s: %w", path, err)
+		}
+	case !os.IsNotExist(err):
+		return err
+	}
+	if !fn(h) {
+		return nil
+	}
+	if data, err = json.MarshalIndent(h, "", "  "); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	// Write and rename, so a reader never sees a half written file.
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func (s *fileStore) Delete(_ context.Context, gk schema.GroupKind, namespace, name string) error {
+	if err := os.Remove(s.path(gk, namespace, name)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (s *fileStore) List(_ context.Context, namespace string) ([]*history, error) {
+	paths, err := filepath.Glob(filepath.Join(s.dir, namespace, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*history, 0, len(paths))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var h history
+		if err := json.Unmarshal(data, &h); err != nil {
+			return nil, fmt.Errorf("invalid history %s: %w", path, err)
+		}
+		out = append(out, &h)
+	}
+	return out, nil
+}
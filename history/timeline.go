@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+type timelineRow struct {
+	object string
+	entry
+}
+
+// printTimeline prints the entries of all histories matching kind and name,
+// ordered by transition time. Empty kind or name match everything.
+func printTimeline(w io.Writer, histories []*history, kind, name string) {
+	var rows []timelineRow
+	for _, h := range histories {
+		if kind != "" && !strings.EqualFold(h.Kind, kind) {
+			continue
+		}
+		if name != "" && h.Name != name {
+			continue
+		}
+		for _, e := range h.Entries {
+			rows = append(rows, timelineRow{object: h.Kind + "/" + h.Name, entry: e})
+		}
+	}
+	// Entries of one object are in recorded order already, which breaks ties
+	// between conditions that changed within the same second.
+	sort.SliceStable(rows, func(i, j int) bool {
+		return rows[i].LastTransitionTime.Before(&rows[j].LastTransitionTime)
+	})
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "TIME\tOBJECT\tTYPE\tSTATUS\tREASON\tMESSAGE")
+	for _, r := range rows {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			r.LastTransitionTime.UTC().Format(time.RFC3339), r.object, r.Type, r.Status, r.Reason, r.Message)
+	}
+	tw.Flush()
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kmapi "kmodules.xyz/client-go/api/v1"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPrintTimeline(t *testing.T) {
+	newEntries := func(conds ...kmapi.Condition) []entry {
+		var out []entry
+		for _, c := range conds {
+			out = append(out, entry{Condition: c, RecordedAt: metav1.NewTime(t0.Add(time.Hour))})
+		}
+		return out
+	}
+	histories := []*history{
+		{Kind: "Postgres", Namespace: "demo", Name: "pg", Entries: newEntries(
+			condition("Ready", metav1.ConditionFalse, "Provisioning", t0),
+			condition("Ready", metav1.ConditionTrue, "Ready", t0.Add(2*time.Minute)),
+		)},
+		{Kind: "PostgresBinding", Namespace: "demo", Name: "pg", Entries: newEntries(
+			// Both changed within the same second, recorded in this order.
+			condition("SecretReady", metav1.ConditionTrue, "", t0.Add(time.Minute)),
+			condition("GatewayReady", metav1.ConditionTrue, "", t0.Add(time.Minute)),
+		)},
+		{Kind: "Postgres", Namespace: "demo", Name: "other", Entries: newEntries(
+			condition("Ready", metav1.ConditionTrue, "Ready", t0.Add(3*time.Minute)),
+		)},
+	}
+
+	cases := []struct {
+		name string
+		// kind and object filter the timeline.
+		kind, object string
+		want         []string
+	}{
+		{
+			name: "all objects by transition time",
+			want: []string{
+				"2024-10-01T12:00:00Z  Postgres/pg         Ready         False   Provisioning",
+				"2024-10-01T12:01:00Z  PostgresBinding/pg  SecretReady   True",
+				"2024-10-01T12:01:00Z  PostgresBinding/pg  GatewayReady  True",
+				"2024-10-01T12:02:00Z  Postgres/pg         Ready         True    Ready",
+				"2024-10-01T12:03:00Z  Postgres/other      Ready         True    Ready",
+			},
+		},
+		{
+			name: "kind in any case",
+			kind: "postgresbinding",
+			want: []string{
+				"2024-10-01T12:01:00Z  PostgresBinding/pg  SecretReady   True",
+				"2024-10-01T12:01:00Z  PostgresBinding/pg  GatewayReady  True",
+			},
+		},
+		{
+			name:   "kind and name",
+			kind:   "Postgres",
+			object: "pg",
+			want: []string{
+				"2024-10-01T12:00:00Z  Postgres/pg  Ready  False   Provisioning",
+				"2024-10-01T12:02:00Z  Postgres/pg  Ready  True    Ready",
+			},
+		},
+		{
+			name: "no match",
+			kind: "MySQL",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var out bytes.Buffer
+			printTimeline(&out, histories, c.kind, c.object)
+			lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+			if !strings.HasPrefix(lines[0], "TIME") {
+				t.Fatalf("timeline has no header:\n%s", out.String())
+			}
+			var got []string
+			for _, l := range lines[1:] {
+				got = append(got, strings.TrimRight(l, " "))
+			}
+			if strings.Join(got, "\n") != strings.Join(c.want, "\n") {
+				t.Errorf("timeline =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(c.want, "\n"))
+			}
+		})
+	}
+}
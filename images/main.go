@@ -0,0 +1,117 @@
+// images lists every container image referenced by KubeDB catalog versions,
+// databases, PetSets and Sidekicks, flags the ones referenced by tag only, and
+// flags running pods whose image digest differs from the digest pinned by their
+// pod spec or by the catalog version with the same image tag.
+//
+//	go run ./images --context=kind-kind
+//	go run ./images --from-dir ./images/testdata --unpinned
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/ArnobKumarSaha/k8s/kubeclient"
+	"github.com/ArnobKumarSaha/k8s/objsource"
+	"io"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/klog/v2"
+	kubedbscheme "kubedb.dev/apimachinery/client/clientset/versioned/scheme"
+	psapi "kubeops.dev/petset/apis/apps/v1"
+	skapi "kubeops.dev/sidekick/apis/apps/v1alpha1"
+	"os"
+	"text/tabwriter"
+)
+
+var (
+	scm = runtime.NewScheme()
+)
+
+func init() {
+	utilruntime.Must(clientgoscheme.AddToScheme(scm))
+	utilruntime.Must(kubedbscheme.AddToScheme(scm))
+	utilruntime.Must(psapi.AddToScheme(scm))
+	utilruntime.Must(skapi.AddToScheme(scm))
+}
+
+func main() {
+	var fromDir, namespace, output string
+	var unpinned bool
+	flag.StringVar(&fromDir, "from-dir", "", "Read objects from the YAML and JSON files below this directory instead of the cluster.")
+	flag.StringVar(&namespace, "n", "", "Only look at objects and pods of this namespace. Catalog versions are always included.")
+	flag.StringVar(&output, "o", "text", "Output format: text or json.")
+	flag.BoolVar(&unpinned, "unpinned", false, "Only list images referenced by tag, without a digest.")
+	var kopts kubeclient.Options
+	kopts.AddFlags(flag.CommandLine)
+	flag.Parse()
+
+	var src objsource.Source
+	if fromDir != "" {
+		ds, err := objsource.LoadDir(fromDir, scm)
+		if err != nil {
+			klog.Fatalln(err)
+		}
+		src = ds
+	} else {
+		kc, err := kubeclient.New(kopts, scm).Client()
+		if err != nil {
+			klog.Fatalln(err)
+		}
+		src = objsource.Live(kc)
+	}
+
+	r, err := collect(context.TODO(), src, namespace)
+	if err != nil {
+		klog.Fatalln(err)
+	}
+	if unpinned {
+		imgs := r.Images[:0]
+		for _, img := range r.Images {
+			if !img.Pinned {
+				imgs = append(imgs, img)
+			}
+		}
+		r.Images = imgs
+	}
+
+	switch output {
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(r)
+	case "text":
+		printReport(os.Stdout, r)
+	default:
+		err = fmt.Errorf("unknown output format %q, expected text or json", output)
+	}
+	if err != nil {
+		klog.Fatalln(err)
+	}
+}
+
+func printReport(w io.Writer, r *report) {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "OBJECT\tFIELD\tIMAGE\tPINNED")
+	for _, img := range r.Images {
+		pinned := "yes"
+		if !img.Pinned {
+			pinned = "NO, tag only"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", img.object(), img.Field, img.Image, pinned)
+	}
+	tw.Flush()
+
+	fmt.Fprintln(w)
+	if len(r.Drift) == 0 {
+		fmt.Fprintln(w, "No running pod differs from its pinned digest.")
+		return
+	}
+	fmt.Fprintln(tw, "POD\tCONTAINER\tIMAGE\tEXPECTED\tFROM\tRUNNING")
+	for _, d := range r.Drift {
+		fmt.Fprintf(tw, "%s/%s\t%s\t%s\t%s\t%s\t%s\n", d.Namespace, d.Pod, d.Container, d.Image, d.Expected, d.ExpectedFrom, d.Running)
+	}
+	tw.Flush()
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"strings"
+)
+
+// imageRef is a container image reference split into its parts. Repository is
+// normalized like the container runtimes do, so nginx and
+// docker.io/library/nginx compare equal.
+type imageRef struct {
+	Repository string
+	Tag        string
+	Digest     string
+}
+
+func parseImage(s string) imageRef {
+	var ref imageRef
+	if i := strings.Index(s, "@"); i >= 0 {
+		s, ref.Digest = s[:i], s[i+1:]
+	}
+	// A colon after the last slash starts the tag, one before it is a registry port.
+	if i := strings.LastIndex(s, ":"); i > strings.LastIndex(s, "/") {
+		s, ref.Tag = s[:i], s[i+1:]
+	}
+	ref.Repository = normalizeRepository(s)
+	return ref
+}
+
+func normalizeRepository(s string) string {
+	parts := strings.SplitN(s, "/", 2)
+	if len(parts) == 1 {
+		return "docker.io/library/" + s
+	}
+	if !strings.ContainsAny(parts[0], ".:") && parts[0] != "localhost" {
+		return "docker.io/" + s
+	}
+	return s
+}
+
+func (r imageRef) Pinned() bool {
+	return strings.HasPrefix(r.Digest, "sha256:")
+}
+
+// runningDigest returns the digest of a containerStatus imageID, which depending
+// on the runtime looks like docker-pullable://nginx@sha256:..., like
+// docker.io/library/nginx@sha256:..., or is only the local image ID.
+func runningDigest(imageID string) (imageRef, bool) {
+	if i := strings.Index(imageID, "://"); i >= 0 {
+		imageID = imageID[i+3:]
+	}
+	if !strings.Contains(imageID, "@") {
+		return imageRef{}, false
+	}
+	ref := parseImage(imageID)
+	return ref, ref.Pinned()
+}
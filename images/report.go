@@ -0,0 +1,255 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/ArnobKumarSaha/k8s/objsource"
+	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	catalogapi "kubedb.dev/apimachinery/apis/catalog/v1alpha1"
+	dbapi "kubedb.dev/apimachinery/apis/kubedb/v1"
+	dbv1alpha2 "kubedb.dev/apimachinery/apis/kubedb/v1alpha2"
+	psapi "kubeops.dev/petset/apis/apps/v1"
+	skapi "kubeops.dev/sidekick/apis/apps/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sort"
+	"strings"
+)
+
+// image is one image reference found in the spec of an object.
+type image struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+	Field     string `json:"field"`
+	Image     string `json:"image"`
+	Pinned    bool   `json:"pinned"`
+}
+
+func (i image) object() string {
+	if i.Namespace == "" {
+		return i.Kind + " " + i.Name
+	}
+	return i.Kind + " " + i.Namespace + "/" + i.Name
+}
+
+// drift is a running container whose image digest is not the one it should run.
+type drift struct {
+	Namespace string `json:"namespace"`
+	Pod       string `json:"pod"`
+	Container string `json:"container"`
+	Image     string `json:"image"`
+	// Expected is the digest pinned by the pod spec, or else by the catalog
+	// version named in ExpectedFrom.
+	Expected     string `json:"expected"`
+	ExpectedFrom string `json:"expectedFrom"`
+	Running      string `json:"running"`
+}
+
+type report struct {
+	Images []image `json:"images"`
+	Drift  []drift `json:"drift"`
+}
+
+// catalogKinds are the KubeDB catalog versions, like PostgresVersion.
+func catalogKinds() []schema.GroupVersionKind {
+	var out []schema.GroupVersionKind
+	for gvk := range scm.AllKnownTypes() {
+		if gvk.GroupVersion() == catalogapi.SchemeGroupVersion && strings.HasSuffix(gvk.Kind, "Version") {
+			out = append(out, gvk)
+		}
+	}
+	sortKinds(out)
+	return out
+}
+
+// databaseKinds are the KubeDB database kinds, from kubedb.com/v1 and, for the
+// kinds not graduated yet, from v1alpha2.
+func databaseKinds() []schema.GroupVersionKind {
+	v1 := map[string]bool{}
+	var out []schema.GroupVersionKind
+	for gvk := range scm.AllKnownTypes() {
+		if gvk.GroupVersion() == dbapi.SchemeGroupVersion && !strings.HasSuffix(gvk.Kind, "List") {
+			v1[gvk.Kind] = true
+			out = append(out, gvk)
+		}
+	}
+	for gvk := range scm.AllKnownTypes() {
+		if gvk.GroupVersion() == dbv1alpha2.SchemeGroupVersion && !strings.HasSuffix(gvk.Kind, "List") && !v1[gvk.Kind] {
+			out = append(out, gvk)
+		}
+	}
+	sortKinds(out)
+	return out
+}
+
+func sortKinds(gvks []schema.GroupVersionKind) {
+	sort.Slice(gvks, func(i, j int) bool { return gvks[i].String() < gvks[j].String() })
+}
+
+func collect(ctx context.Context, src objsource.Source, namespace string) (*report, error) {
+	r := &report{Images: []image{}}
+
+	// Catalog versions are cluster scoped.
+	var catalog []image
+	for _, gvk := range catalogKinds() {
+		objs, err := src.List(ctx, gvk, "")
+		if err != nil {
+			return nil, err
+		}
+		for _, obj := range objs {
+			imgs, err := specImages(gvk.Kind, obj)
+			if err != nil {
+				return nil, err
+			}
+			catalog = append(catalog, imgs...)
+		}
+	}
+	r.Images = append(r.Images, catalog...)
+
+	gvks := append(databaseKinds(),
+		psapi.SchemeGroupVersion.WithKind("PetSet"),
+		skapi.SchemeGroupVersion.WithKind("Sidekick"),
+	)
+	for _, gvk := range gvks {
+		objs, err := src.List(ctx, gvk, namespace)
+		if err != nil {
+			return nil, err
+		}
+		for _, obj := range objs {
+			imgs, err := specImages(gvk.Kind, obj)
+			if err != nil {
+				return nil, err
+			}
+			r.Images = append(r.Images, imgs...)
+		}
+	}
+
+	pods, err := src.List(ctx, core.SchemeGroupVersion.WithKind("Pod"), namespace)
+	if err != nil {
+		return nil, err
+	}
+	r.Drift = podDrift(pods, catalog)
+
+	sort.SliceStable(r.Images, func(i, j int) bool {
+		if a, b := r.Images[i].object(), r.Images[j].object(); a != b {
+			return a < b
+		}
+		return r.Images[i].Field < r.Images[j].Field
+	})
+	return r, nil
+}
+
+// specImages returns every string field named image below the spec of obj.
+func specImages(kind string, obj client.Object) ([]image, error) {
+	u, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil, err
+	}
+	var out []image
+	walkImages(".spec", u["spec"], func(field, value string) {
+		out = append(out, image{
+			Kind:      kind,
+			Namespace: obj.GetNamespace(),
+			Name:      obj.GetName(),
+			Field:     field,
+			Image:     value,
+			Pinned:    parseImage(value).Pinned(),
+		})
+	})
+	return out, nil
+}
+
+func walkImages(path string, v interface{}, fn func(field, value string)) {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(t))
+		for k := range t {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if s, ok := t[k].(string); ok && k == "image" && s != "" {
+				fn(path+"."+k, s)
+				continue
+			}
+			walkImages(path+"."+k, t[k], fn)
+		}
+	case []interface{}:
+		for i, item := range t {
+			// Name list items, like containers, by name rather than position.
+			idx := fmt.Sprint(i)
+			if m, ok := item.(map[string]interface{}); ok {
+				if name, ok := m["name"].(string); ok && name != "" {
+					idx = name
+				}
+			}
+			walkImages(path+"["+idx+"]", item, fn)
+		}
+	}
+}
+
+// podDrift compares the digest every container runs with the digest pinned by
+// its pod spec, and with the digest pinned by a catalog version with the same
+// repository and tag. A container that runs the digest its pod spec pins still
+// drifts from a catalog version pinning another one. Containers whose expected
+// digest is unknown are skipped.
+func podDrift(pods []client.Object, catalog []image) []drift {
+	pinned := map[string]image{}
+	for _, img := range catalog {
+		ref := parseImage(img.Image)
+		if ref.Pinned() && ref.Tag != "" {
+			pinned[ref.Repository+":"+ref.Tag] = img
+		}
+	}
+
+	out := []drift{}
+	for _, obj := range pods {
+		pod := obj.(*core.Pod)
+		specImage := map[string]string{}
+		for _, c := range pod.Spec.InitContainers {
+			specImage[c.Name] = c.Image
+		}
+		for _, c := range pod.Spec.Containers {
+			specImage[c.Name] = c.Image
+		}
+		statuses := make([]core.ContainerStatus, 0, len(pod.Status.InitContainerStatuses)+len(pod.Status.ContainerStatuses))
+		statuses = append(statuses, pod.Status.InitContainerStatuses...)
+		statuses = append(statuses, pod.Status.ContainerStatuses...)
+		for _, st := range statuses {
+			running, ok := runningDigest(st.ImageID)
+			if !ok {
+				continue
+			}
+			img := specImage[st.Name]
+			ref := parseImage(img)
+			d := drift{Namespace: pod.Namespace, Pod: pod.Name, Container: st.Name, Image: img, Running: running.Digest}
+			if ref.Pinned() && ref.Digest != running.Digest {
+				d.Expected, d.ExpectedFrom = ref.Digest, "pod spec"
+				out = append(out, d)
+			}
+			if c, ok := pinned[ref.Repository+":"+ref.Tag]; ok {
+				// A pod spec pinning the digest of the catalog is reported once above.
+				digest := parseImage(c.Image).Digest
+				if digest != running.Digest && (!ref.Pinned() || digest != ref.Digest) {
+					d.Expected, d.ExpectedFrom = digest, c.object()+" "+c.Field
+					out = append(out, d)
+				}
+			}
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Namespace != out[j].Namespace {
+			return out[i].Namespace < out[j].Namespace
+		}
+		if out[i].Pod != out[j].Pod {
+			return out[i].Pod < out[j].Pod
+		}
+		if out[i].Container != out[j].Container {
+			return out[i].Container < out[j].Container
+		}
+		return out[i].ExpectedFrom < out[j].ExpectedFrom
+	})
+	return out
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"github.com/ArnobKumarSaha/k8s/objsource"
+	"reflect"
+	"testing"
+)
+
+func TestCollect(t *testing.T) {
+	src, err := objsource.LoadDir("testdata", scm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := collect(context.TODO(), src, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tagOnly []string
+	for _, img := range r.Images {
+		if !img.Pinned {
+			tagOnly = append(tagOnly, img.object()+" "+img.Field)
+		}
+	}
+	wantTagOnly := []string{
+		"PetSet ace/ace-db .spec.template.spec.containers[exporter].image",
+		"PetSet ace/ace-db .spec.template.spec.containers[pg-coordinator].image",
+		"PetSet ace/ace-db .spec.template.spec.containers[postgres].image",
+		"Postgres ace/ace-db .spec.podTemplate.spec.initContainers[fix-perms].image",
+		"PostgresVersion 15.5 .spec.coordinator.image",
+		"PostgresVersion 15.5 .spec.db.image",
+	}
+	if !reflect.DeepEqual(tagOnly, wantTagOnly) {
+		t.Errorf("tag only images = %v, want %v", tagOnly, wantTagOnly)
+	}
+	if len(r.Images) != 11 {
+		t.Errorf("collect() found %d images, want 11", len(r.Images))
+	}
+
+	wantDrift := []drift{
+		{
+			Namespace:    "ace",
+			Pod:          "ace-db-0",
+			Container:    "exporter",
+			Image:        "prometheuscommunity/postgres-exporter:v0.15.0",
+			Expected:     "sha256:2cbd1a1b3f0a7b2a1e8c2b4a4f1e0d7c9b6a5f4e3d2c1b0a9f8e7d6c5b4a3f2e",
+			ExpectedFrom: "PostgresVersion 15.5 .spec.exporter.image",
+			Running:      "sha256:9e8d7c6b5a4f3e2d1c0b9a8f7e6d5c4b3a2f1e0d9c8b7a6f5e4d3c2b1a0f9e8d",
+		},
+		{
+			Namespace:    "ace",
+			Pod:          "ace-db-1",
+			Container:    "postgres-init-container",
+			Image:        "ghcr.io/kubedb/postgres-init:0.15.0@sha256:8c7b6a5f4e3d2c1b0a9f8e7d6c5b4a3f2e1d0c9b8a7f6e5d4c3b2a1f0e9d8c7b",
+			Expected:     "sha256:33a36e2d34f06771160693e88aa5893c358aad3bddbdd0e4df2f746c3d7ae625",
+			ExpectedFrom: "PostgresVersion 15.5 .spec.initContainer.image",
+			Running:      "sha256:8c7b6a5f4e3d2c1b0a9f8e7d6c5b4a3f2e1d0c9b8a7f6e5d4c3b2a1f0e9d8c7b",
+		},
+		{
+			Namespace:    "ace",
+			Pod:          "ace-db-sidekick",
+			Container:    "postgres-init-container",
+			Image:        "ghcr.io/kubedb/postgres-init:0.15.0@sha256:33a36e2d34f06771160693e88aa5893c358aad3bddbdd0e4df2f746c3d7ae625",
+			Expected:     "sha256:33a36e2d34f06771160693e88aa5893c358aad3bddbdd0e4df2f746c3d7ae625",
+			ExpectedFrom: "pod spec",
+			Running:      "sha256:5d1e0b8a7c6f5e4d3c2b1a0f9e8d7c6b5a4f3e2d1c0b9a8f7e6d5c4b3a2f1e0d",
+		},
+	}
+	if !reflect.DeepEqual(r.Drift, wantDrift) {
+		t.Errorf("drift = %+v, want %+v", r.Drift, wantDrift)
+	}
+
+	r, err = collect(context.TODO(), src, "other")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Images) != 4 || len(r.Drift) != 0 {
+		t.Errorf("collect() in namespace other = %d images and %v, want the 4 catalog images and no drift", len(r.Images), r.Drift)
+	}
+}
+
+func TestParseImage(t *testing.T) {
+	cases := []struct {
+		in   string
+		want imageRef
+	}{
+		{"nginx", imageRef{Repository: "docker.io/library/nginx"}},
+		{"nginx:1.27", imageRef{Repository: "docker.io/library/nginx", Tag: "1.27"}},
+		{"bitnami/redis:7.2", imageRef{Repository: "docker.io/bitnami/redis", Tag: "7.2"}},
+		{"ghcr.io/kubedb/postgres-init:0.15.0@sha256:33a3", imageRef{Repository: "ghcr.io/kubedb/postgres-init", Tag: "0.15.0", Digest: "sha256:33a3"}},
+		{"registry.local:5000/team/app", imageRef{Repository: "registry.local:5000/team/app"}},
+		{"registry.local:5000/team/app:v2", imageRef{Repository: "registry.local:5000/team/app", Tag: "v2"}},
+		{"localhost:5000/app@sha256:ab12", imageRef{Repository: "localhost:5000/app", Digest: "sha256:ab12"}},
+		{"localhost/app:dev", imageRef{Repository: "localhost/app", Tag: "dev"}},
+	}
+	for _, c := range cases {
+		if got := parseImage(c.in); got != c.want {
+			t.Errorf("parseImage(%q) = %+v, want %+v", c.in, got, c.want)
+		}
+	}
+}
+
+func TestRunningDigest(t *testing.T) {
+	cases := []struct {
+		imageID string
+		want    imageRef
+		ok      bool
+	}{
+		{
+			imageID: "docker-pullable://nginx@sha256:ab12",
+			want:    imageRef{Repository: "docker.io/library/nginx", Digest: "sha256:ab12"},
+			ok:      true,
+		},
+		{
+			imageID: "docker.io/library/nginx@sha256:ab12",
+			want:    imageRef{Repository: "docker.io/library/nginx", Digest: "sha256:ab12"},
+			ok:      true,
+		},
+		{
+			imageID: "registry.local:5000/team/app@sha256:cd34",
+			want:    imageRef{Repository: "registry.local:5000/team/app", Digest: "sha256:cd34"},
+			ok:      true,
+		},
+		// Only the local image ID, as docker reports images built on the node.
+		{imageID: "sha256:ef56"},
+		{imageID: "docker://sha256:ef56"},
+		{imageID: ""},
+	}
+	for _, c := range cases {
+		got, ok := runningDigest(c.imageID)
+		if ok != c.ok || (ok && got != c.want) {
+			t.Errorf("runningDigest(%q) = %+v, %v, want %+v, %v", c.imageID, got, ok, c.want, c.ok)
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"context"
+	"github.com/ArnobKumarSaha/k8s/objsource"
+	bapi "go.bytebuilders.dev/catalog/api/v1alpha1"
+	apps "k8s.io/api/apps/v1"
+	policy "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dbapi "kubedb.dev/apimachinery/apis/kubedb/v1"
+	dbv1alpha2 "kubedb.dev/apimachinery/apis/kubedb/v1alpha2"
+	psapi "kubeops.dev/petset/apis/apps/v1"
+	skapi "kubeops.dev/sidekick/apis/apps/v1alpha1"
+	"reflect"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sort"
+	"strings"
+)
+
+// database is one row of the inventory.
+type database struct {
+	Kind                 string   `json:"kind"`
+	Namespace            string   `json:"namespace"`
+	Name                 string   `json:"name"`
+	Version              string   `json:"version"`
+	Replicas             *int64   `json:"replicas,omitempty"`
+	Storage              string   `json:"storage,omitempty"`
+	TLS                  bool     `json:"tls"`
+	Phase                string   `json:"phase,omitempty"`
+	PetSets              []string `json:"petSets,omitempty"`
+	StatefulSets         []string `json:"statefulSets,omitempty"`
+	Sidekicks            []string `json:"sidekicks,omitempty"`
+	PodDisruptionBudgets []string `json:"podDisruptionBudgets,omitempty"`
+	Bindings             []string `json:"bindings,omitempty"`
+}
+
+// databaseKinds returns the database kinds of the scheme. Kinds served as
+// kubedb.com/v1 are taken from there, the rest from v1alpha2.
+func databaseKinds() []schema.GroupVersionKind {
+	var out []schema.GroupVersionKind
+	seen := map[string]bool{}
+	for _, gv := range []schema.GroupVersion{dbapi.SchemeGroupVersion, dbv1alpha2.SchemeGroupVersion} {
+		for kind, t := range scm.KnownTypes(gv) {
+			if seen[kind] || strings.HasSuffix(kind, "List") || !hasVersion(t) {
+				continue
+			}
+			seen[kind] = true
+			out = append(out, gv.WithKind(kind))
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Kind < out[j].Kind })
+	return out
+}
+
+// hasVersion tells databases, which all have spec.version, from the other kinds
+// of the kubedb.com group.
+func hasVersion(t reflect.Type) bool {
+	spec, ok := t.FieldByName("Spec")
+	if !ok || spec.Type.Kind() != reflect.Struct {
+		return false
+	}
+	v, ok := spec.Type.FieldByName("Version")
+	return ok && v.Type.Kind() == reflect.String
+}
+
+func bindingKinds() []schema.GroupVersionKind {
+	var out []schema.GroupVersionKind
+	for kind := range scm.KnownTypes(bapi.GroupVersion) {
+		if strings.HasSuffix(kind, "Binding") && kind != "GenericBinding" {
+			out = append(out, bapi.GroupVersion.WithKind(kind))
+		}
+	}
+	return out
+}
+
+// related are the objects a database may own, listed once for all databases.
+type related struct {
+	petSets, statefulSets, sidekicks, pdbs, bindings []client.Object
+}
+
+func listAll(ctx context.Context, src objsource.Source, namespace string, gvks ...schema.GroupVersionKind) ([]client.Object, error) {
+	var out []client.Object
+	for _, gvk := range gvks {
+		objs, err := src.List(ctx, gvk, namespace)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, objs...)
+	}
+	return out, nil
+}
+
+func collect(ctx context.Context, src objsource.Source, namespace string) ([]database, error) {
+	var rel related
+	var err error
+	if rel.petSets, err = listAll(ctx, src, namespace, psapi.SchemeGroupVersion.WithKind("PetSet")); err != nil {
+		return nil, err
+	}
+	if rel.statefulSets, err = listAll(ctx, src, namespace, apps.SchemeGroupVersion.WithKind("StatefulSet")); err != nil {
+		return nil, err
+	}
+	if rel.sidekicks, err = listAll(ctx, src, namespace, skapi.SchemeGroupVersion.WithKind("Sidekick")); err != nil {
+		return nil, err
+	}
+	if rel.pdbs, err = listAll(ctx, src, namespace, policy.SchemeGroupVersion.WithKind("PodDisruptionBudget")); err != nil {
+		return nil, err
+	}
+	// A binding may live in the namespace of the app that uses the database.
+	if rel.bindings, err = listAll(ctx, src, "", bindingKinds()...); err != nil {
+		return nil, err
+	}
+
+	var out []database
+	for _, gvk := range databaseKinds() {
+		dbs, err := src.List(ctx, gvk, namespace)
+		if err != nil {
+			return nil, err
+		}
+		for _, db := range dbs {
+			row, err := newDatabase(gvk, db, &rel)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, *row)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Kind != out[j].Kind {
+			return out[i].Kind < out[j].Kind
+		}
+		if out[i].Namespace != out[j].Namespace {
+			return out[i].Namespace < out[j].Namespace
+		}
+		return out[i].Name < out[j].Name
+	})
+	return out, nil
+}
+
+func newDatabase(gvk schema.GroupVersionKind, db client.Object, rel *related) (*database, error) {
+	kind := gvk.Kind
+	u, err := runtime.DefaultUnstructuredConverter.ToUnstructured(db)
+	if err != nil {
+		return nil, err
+	}
+	row := &database{Kind: kind, Namespace: db.GetNamespace(), Name: db.GetName()}
+	row.Version, _, _ = unstructured.NestedString(u, "spec", "version")
+	if n, ok, _ := unstructured.NestedInt64(u, "spec", "replicas"); ok {
+		row.Replicas = &n
+	}
+	row.Storage, _, _ = unstructured.NestedString(u, "spec", "storage", "resources", "requests", "storage")
+	tls, _, _ := unstructured.NestedFieldNoCopy(u, "spec", "tls")
+	row.TLS = tls != nil
+	row.Phase, _, _ = unstructured.NestedString(u, "status", "phase")
+
+	row.PetSets = ownedBy(rel.petSets, gvk, db)
+	row.StatefulSets = ownedBy(rel.statefulSets, gvk, db)
+	row.Sidekicks = ownedBy(rel.sidekicks, gvk, db)
+	row.PodDisruptionBudgets = ownedBy(rel.pdbs, gvk, db)
+	for _, b := range rel.bindings {
+		bu, err := runtime.DefaultUnstructuredConverter.ToUnstructured(b)
+		if err != nil {
+			return nil, err
+		}
+		name, _, _ := unstructured.NestedString(bu, "spec", "sourceRef", "name")
+		ns, _, _ := unstructured.NestedString(bu, "spec", "sourceRef", "namespace")
+		if ns == "" {
+			ns = b.GetNamespace()
+		}
+		if name == db.GetName() && ns == db.GetNamespace() &&
+			b.GetObjectKind().GroupVersionKind().Kind == kind+"Binding" {
+			row.Bindings = append(row.Bindings, b.GetNamespace()+"/"+b.GetName())
+		}
+	}
+	return row, nil
+}
+
+// ownedBy returns the names of the objects of db. KubeDB sets db as owner of the
+// objects it creates, and also labels them, which is what remains when a dump
+// was taken without owner references.
+func ownedBy(objs []client.Object, gvk schema.GroupVersionKind, db client.Object) []string {
+	fqn := resourceFQN(gvk, db)
+	var out []string
+	for _, obj := range objs {
+		if obj.GetNamespace() != db.GetNamespace() {
+			continue
+		}
+		if isOwnedBy(obj, gvk, fqn, db.GetName()) {
+			out = append(out, obj.GetName())
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// resourceFQN returns the resource of db with its group, e.g.
+// redises.kubedb.com, which KubeDB sets as app.kubernetes.io/name of the objects
+// of db.
+func resourceFQN(gvk schema.GroupVersionKind, db client.Object) string {
+	if r, ok := db.(interface{ ResourceFQN() string }); ok {
+		return r.ResourceFQN()
+	}
+	plural, _ := meta.UnsafeGuessKindToResource(gvk)
+	return plural.GroupResource().String()
+}
+
+// isOwnedBy tells if obj belongs to the database of gvk named name. Kinds may
+// share a prefix, as Redis and RedisSentinel do, so the label must match the
+// resource of the database exactly.
+func isOwnedBy(obj client.Object, gvk schema.GroupVersionKind, fqn, name string) bool {
+	for _, ref := range obj.GetOwnerReferences() {
+		gv, err := schema.ParseGroupVersion(ref.APIVersion)
+		if err != nil {
+			continue
+		}
+		if gv.Group == gvk.Group && ref.Kind == gvk.Kind && ref.Name == name {
+			return true
+		}
+	}
+	l := obj.GetLabels()
+	return l["app.kubernetes.io/managed-by"] == "kubedb.com" &&
+		l["app.kubernetes.io/instance"] == name &&
+		l["app.kubernetes.io/name"] == fqn
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"github.com/ArnobKumarSaha/k8s/objsource"
+	"reflect"
+	"testing"
+)
+
+func TestCollect(t *testing.T) {
+	src, err := objsource.LoadDir("testdata", scm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := collect(context.TODO(), src, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	three := int64(3)
+	want := []database{
+		{
+			Kind:                 "Redis",
+			Namespace:            "demo",
+			Name:                 "foo",
+			Version:              "7.2.3",
+			Replicas:             &three,
+			Storage:              "1Gi",
+			Phase:                "Ready",
+			PetSets:              []string{"foo"},
+			PodDisruptionBudgets: []string{"foo"},
+		},
+		{
+			Kind:      "RedisSentinel",
+			Namespace: "demo",
+			Name:      "foo",
+			Version:   "7.2.3",
+			Replicas:  &three,
+			Phase:     "Ready",
+			PetSets:   []string{"foo-sentinel"},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("collect() = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,83 @@
+// inventory lists every KubeDB database with its version, size, phase and the
+// objects that belong to it, from a live cluster or from a directory of YAML
+// dumps.
+//
+//	go run ./inventory --context=kind-kind -o markdown
+//	go run ./inventory --from-dir ./dump -n demo -o csv > inventory.csv
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/ArnobKumarSaha/k8s/kubeclient"
+	"github.com/ArnobKumarSaha/k8s/objsource"
+	bapi "go.bytebuilders.dev/catalog/api/v1alpha1"
+	"io"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/klog/v2"
+	kubedbscheme "kubedb.dev/apimachinery/client/clientset/versioned/scheme"
+	psapi "kubeops.dev/petset/apis/apps/v1"
+	skapi "kubeops.dev/sidekick/apis/apps/v1alpha1"
+	"os"
+)
+
+var (
+	scm = runtime.NewScheme()
+)
+
+func init() {
+	utilruntime.Must(clientgoscheme.AddToScheme(scm))
+	utilruntime.Must(kubedbscheme.AddToScheme(scm))
+	utilruntime.Must(psapi.AddToScheme(scm))
+	utilruntime.Must(skapi.AddToScheme(scm))
+	utilruntime.Must(bapi.AddToScheme(scm))
+}
+
+func main() {
+	var fromDir, namespace, output string
+	flag.StringVar(&fromDir, "from-dir", "", "Read objects from the YAML and JSON files below this directory instead of the cluster.")
+	flag.StringVar(&namespace, "n", "", "Only list databases of this namespace. Defaults to all namespaces.")
+	flag.StringVar(&output, "o", "markdown", "Output format: json, csv or markdown.")
+	var kopts kubeclient.Options
+	kopts.AddFlags(flag.CommandLine)
+	flag.Parse()
+
+	if err := run(kubeclient.New(kopts, scm), fromDir, namespace, output); err != nil {
+		klog.Fatalln(err)
+	}
+}
+
+func run(factory *kubeclient.Factory, fromDir, namespace, output string) error {
+	write, ok := map[string]func(io.Writer, []database) error{
+		"json":     writeJSON,
+		"csv":      writeCSV,
+		"markdown": writeMarkdown,
+	}[output]
+	if !ok {
+		return fmt.Errorf("unknown output format %q, expected json, csv or markdown", output)
+	}
+
+	var src objsource.Source
+	if fromDir != "" {
+		ds, err := objsource.LoadDir(fromDir, scm)
+		if err != nil {
+			return err
+		}
+		src = ds
+	} else {
+		kc, err := factory.Client()
+		if err != nil {
+			return err
+		}
+		src = objsource.Live(kc)
+	}
+
+	dbs, err := collect(context.TODO(), src, namespace)
+	if err != nil {
+		return err
+	}
+	return write(os.Stdout, dbs)
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+var columns = []string{"KIND", "NAMESPACE", "NAME", "VERSION", "REPLICAS", "STORAGE", "TLS", "PHASE",
+	"PETSETS", "STATEFULSETS", "SIDEKICKS", "PDBS", "BINDINGS"}
+
+func (d *database) cells(sep string) []string {
+	replicas := ""
+	if d.Replicas != nil {
+		replicas = strconv.FormatInt(*d.Replicas, 10)
+	}
+	return []string{d.Kind, d.Namespace, d.Name, d.Version, replicas, d.Storage, strconv.FormatBool(d.TLS), d.Phase,
+		strings.Join(d.PetSets, sep), strings.Join(d.StatefulSets, sep), strings.Join(d.Sidekicks, sep),
+		strings.Join(d.PodDisruptionBudgets, sep), strings.Join(d.Bindings, sep)}
+}
+
+func writeJSON(w io.Writer, dbs []database) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if dbs == nil {
+		dbs = []database{}
+	}
+	return enc.Encode(dbs)
+}
+
+func writeCSV(w io.Writer, dbs []database) error {
+	cw := csv.NewWriter(w)
+	header := make([]string, len(columns))
+	for i, c := range columns {
+		header[i] = strings.ToLower(c)
+	}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for i := range dbs {
+		if err := cw.Write(dbs[i].cells(";")); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+var markdownEscaper = strings.NewReplacer("|", `\|`, "\n", " ")
+
+func writeMarkdown(w io.Writer, dbs []database) error {
+	row := func(cells []string) {
+		for i := range cells {
+			cells[i] = markdownEscaper.Replace(cells[i])
+		}
+		fmt.Fprintf(w, "| %s |\n", strings.Join(cells, " | "))
+	}
+	row(append([]string(nil), columns...))
+	sep := make([]string, len(columns))
+	for i := range sep {
+		sep[i] = "---"
+	}
+	row(sep)
+	for i := range dbs {
+		row(dbs[i].cells(", "))
+	}
+	return nil
+}
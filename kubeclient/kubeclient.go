@@ -0,0 +1,202 @@
+// Package kubeclient builds the clients of the demos and tools in this repo from
+// one set of flags: --kubeconfig, --context and --client-settings.
+//
+// The tools of the duck module, kubectl-duck and selector-overlaps, are built in
+// a module of their own that can not import this package. They take --kubeconfig
+// and --context, but not --client-settings.
+package kubeclient
+
+import (
+	"flag"
+	"fmt"
+	"github.com/ArnobKumarSaha/k8s/cassette"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"os"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sync"
+)
+
+// InClusterContext names the in-cluster config in a SettingsFile.
+const InClusterContext = "in-cluster"
+
+// ReplayContext is the context name while replaying a cassette.
+const ReplayContext = "replay"
+
+type Options struct {
+	// Kubeconfig is the path of the kubeconfig file. If empty, $KUBECONFIG and
+	// then ~/.kube/config are used, and the in-cluster config if neither exists.
+	Kubeconfig string
+	// Context is the kubeconfig context to use instead of the current one.
+	Context string
+	// SettingsFile is the path of a SettingsFile.
+	SettingsFile string
+	// Record appends every request to the cassette at this path.
+	Record string
+	// Replay answers requests from the cassette at this path instead of a cluster.
+	Replay string
+
+	// kubeconfigFlag is set when the flag set already had a kubeconfig flag, as
+	// every program importing controller-runtime has.
+	kubeconfigFlag *flag.Flag
+}
+
+// AddFlags registers the flags of o to fs.
+func (o *Options) AddFlags(fs *flag.FlagSet) {
+	if f := fs.Lookup("kubeconfig"); f != nil {
+		o.kubeconfigFlag = f
+	} else {
+		fs.StringVar(&o.Kubeconfig, "kubeconfig", "", "Path to the kubeconfig file. Defaults to $KUBECONFIG or ~/.kube/config.")
+	}
+	fs.StringVar(&o.Context, "context", "", "The name of the kubeconfig context to use.")
+	fs.StringVar(&o.SettingsFile, "client-settings", os.Getenv("CLIENT_SETTINGS"), "Path to a YAML file of per context qps, burst, timeout and impersonation. Defaults to $CLIENT_SETTINGS.")
+	fs.StringVar(&o.Record, "record", "", "Append every API request and response, with Secrets redacted, to this cassette.")
+	fs.StringVar(&o.Replay, "replay", "", "Answer API requests from this cassette instead of a cluster.")
+}
+
+func (o *Options) kubeconfig() string {
+	if o.Kubeconfig == "" && o.kubeconfigFlag != nil {
+		return o.kubeconfigFlag.Value.String()
+	}
+	return o.Kubeconfig
+}
+
+// Factory builds clients that share one rest config.
+type Factory struct {
+	opts   Options
+	scheme *runtime.Scheme
+
+	once    sync.Once
+	cfg     *rest.Config
+	context string
+	err     error
+
+	mapperOnce sync.Once
+	mapper     meta.RESTMapper
+	mapperErr  error
+}
+
+// New returns a factory whose controller-runtime clients use scheme.
+func New(opts Options, scheme *runtime.Scheme) *Factory {
+	return &Factory{opts: opts, scheme: scheme}
+}
+
+func (f *Factory) load() {
+	f.loadConfig()
+	if f.err != nil {
+		return
+	}
+	if f.opts.Record != "" {
+		rec, err := cassette.NewRecorder(f.opts.Record)
+		if err != nil {
+			f.err = err
+			return
+		}
+		rec.Configure(f.cfg)
+	}
+}
+
+func (f *Factory) loadConfig() {
+	if f.opts.Replay != "" {
+		interactions, err := cassette.Load(f.opts.Replay)
+		if err != nil {
+			f.err = err
+			return
+		}
+		srv := cassette.NewServer(interactions)
+		if _, f.err = srv.Start("127.0.0.1:0"); f.err != nil {
+			return
+		}
+		f.cfg, f.err = srv.RESTConfig()
+		f.context = ReplayContext
+		return
+	}
+
+	loader := clientcmd.NewDefaultClientConfigLoadingRules()
+	loader.ExplicitPath = f.opts.kubeconfig()
+	cc := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loader, &clientcmd.ConfigOverrides{
+		CurrentContext: f.opts.Context,
+	})
+
+	f.cfg, f.err = cc.ClientConfig()
+	switch {
+	case f.err == nil:
+		f.context = f.opts.Context
+		if f.context == "" {
+			raw, err := cc.RawConfig()
+			if err != nil {
+				f.err = err
+				return
+			}
+			f.context = raw.CurrentContext
+		}
+	case clientcmd.IsEmptyConfig(f.err) && f.opts.Context == "" && loader.ExplicitPath == "":
+		if f.cfg, f.err = rest.InClusterConfig(); f.err != nil {
+			f.err = fmt.Errorf("no kubeconfig found and not running in a cluster: %w", f.err)
+			return
+		}
+		f.context = InClusterContext
+	default:
+		return
+	}
+
+	if f.opts.SettingsFile != "" {
+		sf, err := LoadSettingsFile(f.opts.SettingsFile)
+		if err != nil {
+			f.err = err
+			return
+		}
+		sf.For(f.context).apply(f.cfg)
+	}
+}
+
+// RESTConfig returns a copy of the rest config, so callers may change it.
+func (f *Factory) RESTConfig() (*rest.Config, error) {
+	f.once.Do(f.load)
+	if f.err != nil {
+		return nil, f.err
+	}
+	return rest.CopyConfig(f.cfg), nil
+}
+
+// ContextName returns the kubeconfig context in use, or InClusterContext.
+func (f *Factory) ContextName() (string, error) {
+	f.once.Do(f.load)
+	return f.context, f.err
+}
+
+func (f *Factory) Kubernetes() (kubernetes.Interface, error) {
+	cfg, err := f.RESTConfig()
+	if err != nil {
+		return nil, err
+	}
+	return kubernetes.NewForConfig(cfg)
+}
+
+func (f *Factory) Client() (client.Client, error) {
+	cfg, err := f.RESTConfig()
+	if err != nil {
+		return nil, err
+	}
+	mapper, err := f.RESTMapper()
+	if err != nil {
+		return nil, err
+	}
+	return client.New(cfg, client.Options{
+		Scheme: f.scheme,
+		Mapper: mapper,
+	})
+}
+
+// RESTConfigOrDie is like RESTConfig, but exits when the config can not be built.
+func (f *Factory) RESTConfigOrDie() *rest.Config {
+	cfg, err := f.RESTConfig()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+	return cfg
+}
@@ -0,0 +1,55 @@
+package kubeclient
+
+import (
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
+)
+
+// RESTMapper returns a mapper backed by the discovery of all API groups, which
+// knows the short names of the resources, like kubectl does.
+func (f *Factory) RESTMapper() (meta.RESTMapper, error) {
+	f.mapperOnce.Do(func() {
+		cfg, err := f.RESTConfig()
+		if err != nil {
+			f.mapperErr = err
+			return
+		}
+		f.mapper, f.mapperErr = newRESTMapper(cfg)
+	})
+	return f.mapper, f.mapperErr
+}
+
+func newRESTMapper(cfg *rest.Config) (meta.RESTMapper, error) {
+	dc, err := discovery.NewDiscoveryClientForConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	cached := memory.NewMemCacheClient(dc)
+	return restmapper.NewShortcutExpander(restmapper.NewDeferredDiscoveryRESTMapper(cached), cached, nil), nil
+}
+
+// KindFor resolves a resource as kubectl takes it: a plural, singular or short
+// name, optionally qualified by group or by version and group, like sts,
+// sidekick, petsets.apps.k8s.appscode.com or deployments.v1.apps.
+func (f *Factory) KindFor(resource string) (schema.GroupVersionKind, error) {
+	mapper, err := f.RESTMapper()
+	if err != nil {
+		return schema.GroupVersionKind{}, err
+	}
+	return KindFor(mapper, resource)
+}
+
+// KindFor resolves resource with mapper, see Factory.KindFor.
+func KindFor(mapper meta.RESTMapper, resource string) (schema.GroupVersionKind, error) {
+	fqr, gr := schema.ParseResourceArg(resource)
+	if fqr != nil {
+		if gvk, err := mapper.KindFor(*fqr); err == nil {
+			return gvk, nil
+		}
+	}
+	return mapper.KindFor(gr.WithVersion(""))
+}
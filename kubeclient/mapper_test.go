@@ -0,0 +1,88 @@
+package kubeclient
+
+import (
+	"encoding/json"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/rest"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// discoveryServer serves the discovery of the core group with pods, of apps with
+// statefulsets and deployments, and of the KubeOps group with sidekicks.
+func discoveryServer() *httptest.Server {
+	docs := map[string]interface{}{
+		"/api": &metav1.APIVersions{Versions: []string{"v1"}},
+		"/apis": &metav1.APIGroupList{Groups: []metav1.APIGroup{
+			{
+				Name:             "apps",
+				Versions:         []metav1.GroupVersionForDiscovery{{GroupVersion: "apps/v1", Version: "v1"}},
+				PreferredVersion: metav1.GroupVersionForDiscovery{GroupVersion: "apps/v1", Version: "v1"},
+			},
+			{
+				Name:             "apps.k8s.appscode.com",
+				Versions:         []metav1.GroupVersionForDiscovery{{GroupVersion: "apps.k8s.appscode.com/v1alpha1", Version: "v1alpha1"}},
+				PreferredVersion: metav1.GroupVersionForDiscovery{GroupVersion: "apps.k8s.appscode.com/v1alpha1", Version: "v1alpha1"},
+			},
+		}},
+		"/api/v1": &metav1.APIResourceList{GroupVersion: "v1", APIResources: []metav1.APIResource{
+			{Name: "pods", SingularName: "pod", Namespaced: true, Kind: "Pod", ShortNames: []string{"po"}, Verbs: []string{"get", "list"}},
+		}},
+		"/apis/apps/v1": &metav1.APIResourceList{GroupVersion: "apps/v1", APIResources: []metav1.APIResource{
+			{Name: "statefulsets", SingularName: "statefulset", Namespaced: true, Kind: "StatefulSet", ShortNames: []string{"sts"}, Verbs: []string{"get", "list"}},
+			{Name: "deployments", SingularName: "deployment", Namespaced: true, Kind: "Deployment", ShortNames: []string{"deploy"}, Verbs: []string{"get", "list"}},
+		}},
+		"/apis/apps.k8s.appscode.com/v1alpha1": &metav1.APIResourceList{GroupVersion: "apps.k8s.appscode.com/v1alpha1", APIResources: []metav1.APIResource{
+			{Name: "sidekicks", SingularName: "sidekick", Namespaced: true, Kind: "Sidekick", Verbs: []string{"get", "list"}},
+		}},
+	}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		doc, ok := docs[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(doc)
+	}))
+}
+
+func TestKindFor(t *testing.T) {
+	srv := discoveryServer()
+	defer srv.Close()
+	mapper, err := newRESTMapper(&rest.Config{Host: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sidekick := schema.GroupVersionKind{Group: "apps.k8s.appscode.com", Version: "v1alpha1", Kind: "Sidekick"}
+	sts := schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "StatefulSet"}
+	cases := []struct {
+		resource string
+		want     schema.GroupVersionKind
+		wantErr  bool
+	}{
+		{resource: "sidekick", want: sidekick},
+		{resource: "sidekicks", want: sidekick},
+		{resource: "sidekicks.apps.k8s.appscode.com", want: sidekick},
+		{resource: "sidekicks.v1alpha1.apps.k8s.appscode.com", want: sidekick},
+		{resource: "sts", want: sts},
+		{resource: "statefulsets.apps", want: sts},
+		{resource: "deployments.v1.apps", want: schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}},
+		{resource: "po", want: schema.GroupVersionKind{Version: "v1", Kind: "Pod"}},
+		{resource: "petsets", wantErr: true},
+	}
+	for _, c := range cases {
+		got, err := KindFor(mapper, c.resource)
+		switch {
+		case c.wantErr && err == nil:
+			t.Errorf("KindFor(%s) = %v, want an error", c.resource, got)
+		case !c.wantErr && err != nil:
+			t.Errorf("KindFor(%s): %v", c.resource, err)
+		case !c.wantErr && got != c.want:
+			t.Errorf("KindFor(%s) = %v, want %v", c.resource, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package kubeclient
+
+import (
+	"fmt"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+	"os"
+	"sigs.k8s.io/yaml"
+)
+
+// Settings tune the clients of one kubeconfig context. Zero values leave the
+// client-go defaults in place.
+//
+//	default:
+//	  qps: 50
+//	  burst: 100
+//	contexts:
+//	  kind-kind:
+//	    qps: 100
+//	    timeout: 30s
+//	    impersonate:
+//	      user: system:serviceaccount:demo:reader
+type Settings struct {
+	QPS         float32         `json:"qps,omitempty"`
+	Burst       int             `json:"burst,omitempty"`
+	Timeout     metav1.Duration `json:"timeout,omitempty"`
+	Impersonate *Impersonation  `json:"impersonate,omitempty"`
+}
+
+type Impersonation struct {
+	User   string              `json:"user,omitempty"`
+	UID    string              `json:"uid,omitempty"`
+	Groups []string            `json:"groups,omitempty"`
+	Extra  map[string][]string `json:"extra,omitempty"`
+}
+
+// SettingsFile is the content of the --client-settings file. The settings of a
+// context are applied on top of the default ones. The in-cluster config is
+// looked up as context InClusterContext.
+type SettingsFile struct {
+	Default  Settings            `json:"default,omitempty"`
+	Contexts map[string]Settings `json:"contexts,omitempty"`
+}
+
+func LoadSettingsFile(path string) (*SettingsFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var sf SettingsFile
+	if err := yaml.UnmarshalStrict(data, &sf); err != nil {
+		return nil, fmt.Errorf("invalid client settings %s: %w", path, err)
+	}
+	return &sf, nil
+}
+
+// For returns the settings of context.
+func (sf *SettingsFile) For(context string) Settings {
+	s := sf.Default
+	o, ok := sf.Contexts[context]
+	if !ok {
+		return s
+	}
+	if o.QPS != 0 {
+		s.QPS = o.QPS
+	}
+	if o.Burst != 0 {
+		s.Burst = o.Burst
+	}
+	if o.Timeout.Duration != 0 {
+		s.Timeout = o.Timeout
+	}
+	if o.Impersonate != nil {
+		s.Impersonate = o.Impersonate
+	}
+	return s
+}
+
+func (s Settings) apply(cfg *rest.Config) {
+	if s.QPS != 0 {
+		cfg.QPS = s.QPS
+	}
+	if s.Burst != 0 {
+		cfg.Burst = s.Burst
+	}
+	if s.Timeout.Duration != 0 {
+		cfg.Timeout = s.Timeout.Duration
+	}
+	if i := s.Impersonate; i != nil {
+		cfg.Impersonate = rest.ImpersonationConfig{
+			UserName: i.User,
+			UID:      i.UID,
+			Groups:   i.Groups,
+			Extra:    i.Extra,
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package kubeclient
+
+import (
+	"k8s.io/client-go/rest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const kubeconfig = `apiVersion: v1
+kind: Config
+current-context: kind-kind
+clusters:
+- name: kind
+  cluster:
+    server: https://127.0.0.1:6443
+users:
+- name: admin
+  user:
+    token: secret
+contexts:
+- name: kind-kind
+  context:
+    cluster: kind
+    user: admin
+- name: staging
+  context:
+    cluster: kind
+    user: admin
+- name: prod
+  context:
+    cluster: kind
+    user: admin
+`
+
+const settings = `default:
+  qps: 50
+  burst: 100
+contexts:
+  kind-kind:
+    qps: 100
+    timeout: 30s
+  staging:
+    burst: 20
+    impersonate:
+      user: system:serviceaccount:demo:reader
+      groups: [system:authenticated]
+`
+
+func TestSettings(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{"kubeconfig": kubeconfig, "settings.yaml": settings} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	type want struct {
+		qps         float32
+		burst       int
+		timeout     time.Duration
+		impersonate rest.ImpersonationConfig
+	}
+	cases := []struct {
+		name string
+		// context is the --context flag.
+		context string
+		want    want
+	}{
+		{
+			name: "current context",
+			want: want{qps: 100, burst: 100, timeout: 30 * time.Second},
+		},
+		{
+			name:    "context flag overrides the current context",
+			context: "staging",
+			want: want{qps: 50, burst: 20, impersonate: rest.ImpersonationConfig{
+				UserName: "system:serviceaccount:demo:reader",
+				Groups:   []string{"system:authenticated"},
+			}},
+		},
+		{
+			name:    "context without settings falls back to the default",
+			context: "prod",
+			want:    want{qps: 50, burst: 100},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			f := New(Options{
+				Kubeconfig:   filepath.Join(dir, "kubeconfig"),
+				Context:      c.context,
+				SettingsFile: filepath.Join(dir, "settings.yaml"),
+			}, nil)
+			cfg, err := f.RESTConfig()
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := want{qps: cfg.QPS, burst: cfg.Burst, timeout: cfg.Timeout, impersonate: cfg.Impersonate}
+			if !reflect.DeepEqual(got, c.want) {
+				t.Errorf("settings = %+v, want %+v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestLoadSettingsFileIsStrict(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "settings.yaml")
+	if err := os.WriteFile(path, []byte("default:\n  qsp: 50\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadSettingsFile(path); err == nil {
+		t.Error("LoadSettingsFile() accepted the unknown field qsp")
+	}
+}
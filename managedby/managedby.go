@@ -0,0 +1,258 @@
+// Package managedby explains which actor effectively manages a Kubernetes object,
+// that is who will revert a manual edit of it. It weighs the signals objects
+// carry about that:
+//
+//   - the controller ownerReferences chain, e.g. Sidekick -> Postgres
+//   - the app.kubernetes.io/managed-by label, e.g. kubedb.com or Helm
+//   - the meta.helm.sh release annotations
+//   - the helm.toolkit.fluxcd.io and kustomize.toolkit.fluxcd.io labels of Flux
+//   - the field managers in metadata.managedFields
+//
+// Signals naming the same actor add up, and actors are ranked by their score.
+package managedby
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/structured-merge-diff/v4/fieldpath"
+	"sort"
+	"strings"
+)
+
+// Scores of the signals. A controller owner reverts any edit, a GitOps tool
+// reverts on its next sync, Helm only on the next upgrade, and a field manager
+// only shows who wrote last.
+const (
+	scoreControllerOwner = 100
+	scoreManagedByLabel  = 50
+	scoreFlux            = 70
+	scoreHelm            = 40
+	scoreLastWriter      = 30
+	scoreWriter          = 10
+)
+
+type ObjectRef struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Namespace  string `json:"namespace,omitempty"`
+	Name       string `json:"name"`
+}
+
+func (r ObjectRef) String() string {
+	if r.Namespace == "" {
+		return r.Kind + " " + r.Name
+	}
+	return r.Kind + " " + r.Namespace + "/" + r.Name
+}
+
+func refOf(obj client.Object) ObjectRef {
+	gvk := obj.GetObjectKind().GroupVersionKind()
+	return ObjectRef{APIVersion: gvk.GroupVersion().String(), Kind: gvk.Kind, Namespace: obj.GetNamespace(), Name: obj.GetName()}
+}
+
+// Evidence is one signal pointing at an actor.
+type Evidence struct {
+	Source string `json:"source"`
+	Detail string `json:"detail"`
+	Score  int    `json:"score"`
+}
+
+// Candidate is an actor that may manage the object.
+type Candidate struct {
+	Actor    string     `json:"actor"`
+	Score    int        `json:"score"`
+	Evidence []Evidence `json:"evidence"`
+}
+
+type Result struct {
+	Object ObjectRef `json:"object"`
+	// Owners is the chain of controller owners, nearest first.
+	Owners []ObjectRef `json:"owners,omitempty"`
+	// Candidates are ranked, the effective manager first.
+	Candidates []Candidate `json:"candidates"`
+}
+
+// Resolver follows ownerReferences with Reader. Without a Reader only the
+// object itself is looked at.
+type Resolver struct {
+	Reader client.Reader
+	// MaxDepth bounds the owner chain. Zero means 10.
+	MaxDepth int
+}
+
+func (r *Resolver) Resolve(ctx context.Context, obj *unstructured.Unstructured) (*Result, error) {
+	res := &Result{Object: refOf(obj)}
+	scores := map[string]*Candidate{}
+	add := func(actor, source, detail string, score int) {
+		c, ok := scores[actor]
+		if !ok {
+			c = &Candidate{Actor: actor}
+			scores[actor] = c
+		}
+		c.Score += score
+		c.Evidence = append(c.Evidence, Evidence{Source: source, Detail: detail, Score: score})
+	}
+
+	if err := r.resolveOwners(ctx, obj, res, add); err != nil {
+		return nil, err
+	}
+	labelSignals(obj, "", add)
+	if err := fieldManagerSignals(obj, add); err != nil {
+		return nil, err
+	}
+
+	for _, c := range scores {
+		res.Candidates = append(res.Candidates, *c)
+	}
+	sort.Slice(res.Candidates, func(i, j int) bool {
+		if res.Candidates[i].Score != res.Candidates[j].Score {
+			return res.Candidates[i].Score > res.Candidates[j].Score
+		}
+		return res.Candidates[i].Actor < res.Candidates[j].Actor
+	})
+	return res, nil
+}
+
+func (r *Resolver) resolveOwners(ctx context.Context, obj *unstructured.Unstructured, res *Result, add func(actor, source, detail string, score int)) error {
+	maxDepth := r.MaxDepth
+	if maxDepth == 0 {
+		maxDepth = 10
+	}
+	cur := obj
+	for depth := 0; depth < maxDepth; depth++ {
+		ref := metav1.GetControllerOf(cur)
+		if ref == nil {
+			return nil
+		}
+		owner := ObjectRef{APIVersion: ref.APIVersion, Kind: ref.Kind, Namespace: cur.GetNamespace(), Name: ref.Name}
+		res.Owners = append(res.Owners, owner)
+		gv, _ := schema.ParseGroupVersion(ref.APIVersion)
+		// Only the nearest owner counts fully, it is the one whose controller
+		// writes this object. Owners further up explain where it comes from.
+		score := scoreControllerOwner
+		if depth > 0 {
+			score = scoreWriter
+		}
+		add(controllerOf(gv.Group), "ownerReferences", fmt.Sprintf("%s is controlled by %s", refOf(cur), owner), score)
+
+		if r.Reader == nil {
+			return nil
+		}
+		next := &unstructured.Unstructured{}
+		next.SetAPIVersion(ref.APIVersion)
+		next.SetKind(ref.Kind)
+		if err := r.Reader.Get(ctx, client.ObjectKey{Namespace: owner.Namespace, Name: owner.Name}, next); err != nil {
+			if client.IgnoreNotFound(err) == nil {
+				add(controllerOf(gv.Group), "ownerReferences", owner.String()+" is gone, the object will be garbage collected", 0)
+				return nil
+			}
+			return err
+		}
+		// Whoever manages the root of the chain manages the object through it.
+		labelSignals(next, " on "+owner.String(), add)
+		cur = next
+	}
+	return nil
+}
+
+// controllerOf names the actor reconciling objects of an API group.
+func controllerOf(group string) string {
+	switch {
+	case group == "kubedb.com" || strings.HasSuffix(group, ".kubedb.com"):
+		return "KubeDB operator"
+	case group == "apps.k8s.appscode.com":
+		return "KubeOps operator"
+	case group == "catalog.appscode.com":
+		return "catalog manager"
+	case group == "" || group == "apps" || group == "batch":
+		return "kube-controller-manager"
+	case strings.HasSuffix(group, ".toolkit.fluxcd.io"):
+		return "Flux"
+	}
+	return "controller of " + group
+}
+
+// actorOfFieldManager maps field manager names to the actors of the other signals.
+func actorOfFieldManager(manager string) string {
+	m := strings.ToLower(manager)
+	switch {
+	case strings.Contains(m, "helm-controller"), strings.Contains(m, "kustomize-controller"):
+		return "Flux"
+	case m == "helm" || strings.HasPrefix(m, "helm-"):
+		return "Helm"
+	case strings.Contains(m, "kubedb"):
+		return "KubeDB operator"
+	case strings.HasPrefix(m, "kubectl"):
+		return "kubectl (manual edits)"
+	case m == "kube-controller-manager":
+		return "kube-controller-manager"
+	}
+	return manager
+}
+
+func labelSignals(obj client.Object, on string, add func(actor, source, detail string, score int)) {
+	labels, annotations := obj.GetLabels(), obj.GetAnnotations()
+
+	switch v := labels["app.kubernetes.io/managed-by"]; {
+	case v == "":
+	case v == "kubedb.com":
+		add("KubeDB operator", "label", "app.kubernetes.io/managed-by=kubedb.com"+on, scoreManagedByLabel)
+	case strings.EqualFold(v, "Helm"):
+		add("Helm", "label", "app.kubernetes.io/managed-by="+v+on, scoreManagedByLabel)
+	default:
+		add(v, "label", "app.kubernetes.io/managed-by="+v+on, scoreManagedByLabel)
+	}
+
+	if name := annotations["meta.helm.sh/release-name"]; name != "" {
+		add("Helm", "helm", fmt.Sprintf("part of Helm release %s/%s%s", annotations["meta.helm.sh/release-namespace"], name, on), scoreHelm)
+	}
+	if name := labels["helm.toolkit.fluxcd.io/name"]; name != "" {
+		add("Flux", "flux", fmt.Sprintf("installed by HelmRelease %s/%s%s, Flux reverts drift on every sync",
+			labels["helm.toolkit.fluxcd.io/namespace"], name, on), scoreFlux)
+	}
+	if name := labels["kustomize.toolkit.fluxcd.io/name"]; name != "" {
+		add("Flux", "flux", fmt.Sprintf("applied by Kustomization %s/%s%s, Flux reverts drift on every sync",
+			labels["kustomize.toolkit.fluxcd.io/namespace"], name, on), scoreFlux)
+	}
+}
+
+// fieldManagerSignals credits the manager that wrote the object last, and every
+// other manager a little. Status writes do not count, they do not fight over spec.
+func fieldManagerSignals(obj client.Object, add func(actor, source, detail string, score int)) error {
+	var latest *metav1.ManagedFieldsEntry
+	entries := obj.GetManagedFields()
+	for i := range entries {
+		e := &entries[i]
+		if e.Subresource != "" || e.FieldsV1 == nil {
+			continue
+		}
+		if latest == nil || (e.Time != nil && latest.Time != nil && latest.Time.Before(e.Time)) {
+			latest = e
+		}
+	}
+	for i := range entries {
+		e := &entries[i]
+		if e.Subresource != "" || e.FieldsV1 == nil {
+			continue
+		}
+		set := &fieldpath.Set{}
+		if err := set.FromJSON(bytes.NewReader(e.FieldsV1.Raw)); err != nil {
+			return fmt.Errorf("invalid managedFields of %s: %w", e.Manager, err)
+		}
+		detail := fmt.Sprintf("field manager %s owns %d fields by %s", e.Manager, set.Leaves().Size(), e.Operation)
+		score := scoreWriter
+		if e == latest {
+			score = scoreLastWriter
+			if e.Time != nil {
+				detail += ", last write " + e.Time.UTC().Format("2006-01-02T15:04:05Z")
+			}
+		}
+		add(actorOfFieldManager(e.Manager), "managedFields", detail, score)
+	}
+	return nil
+}
@@ -0,0 +1,199 @@
+package managedby
+
+import (
+	"context"
+	"fmt"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+	"strings"
+	"testing"
+	"time"
+)
+
+// objects is a client.Reader over a fixed set of objects, keyed by kind,
+// namespace and name.
+type objects map[string]*unstructured.Unstructured
+
+func (o objects) Get(_ context.Context, key client.ObjectKey, obj client.Object, _ ...client.GetOption) error {
+	u := obj.(*unstructured.Unstructured)
+	found, ok := o[u.GetKind()+"/"+key.Namespace+"/"+key.Name]
+	if !ok {
+		gvk := u.GroupVersionKind()
+		return apierrors.NewNotFound(schema.GroupResource{Group: gvk.Group, Resource: strings.ToLower(gvk.Kind) + "s"}, key.Name)
+	}
+	found.DeepCopyInto(u)
+	return nil
+}
+
+func (o objects) List(context.Context, client.ObjectList, ...client.ListOption) error {
+	return fmt.Errorf("not supported")
+}
+
+func parse(t *testing.T, s string) *unstructured.Unstructured {
+	t.Helper()
+	obj := &unstructured.Unstructured{}
+	if err := yaml.Unmarshal([]byte(s), &obj.Object); err != nil {
+		t.Fatal(err)
+	}
+	return obj
+}
+
+// entry is a managedFields entry owning the given fields of spec.
+func entry(manager string, op metav1.ManagedFieldsOperationType, subresource string, at int, fields ...string) metav1.ManagedFieldsEntry {
+	var parts []string
+	for _, f := range fields {
+		parts = append(parts, fmt.Sprintf(`"f:%s":{}`, f))
+	}
+	t := metav1.NewTime(time.Date(2024, 1, 1, at, 0, 0, 0, time.UTC))
+	return metav1.ManagedFieldsEntry{
+		Manager:     manager,
+		Operation:   op,
+		APIVersion:  "apps/v1",
+		Time:        &t,
+		FieldsType:  "FieldsV1",
+		FieldsV1:    &metav1.FieldsV1{Raw: []byte(`{"f:spec":{` + strings.Join(parts, ",") + `}}`)},
+		Subresource: subresource,
+	}
+}
+
+const deployment = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: demo
+`
+
+func TestResolve(t *testing.T) {
+	cases := []struct {
+		name    string
+		labels  map[string]string
+		annots  map[string]string
+		fields  []metav1.ManagedFieldsEntry
+		reader  objects
+		owner   bool
+		want    []string
+		wantErr string
+	}{
+		{
+			name: "last writer wins over other writers",
+			fields: []metav1.ManagedFieldsEntry{
+				entry("kubectl-client-side-apply", metav1.ManagedFieldsOperationUpdate, "", 1, "replicas", "template"),
+				entry("argocd-controller", metav1.ManagedFieldsOperationApply, "", 3, "replicas"),
+				entry("kube-controller-manager", metav1.ManagedFieldsOperationUpdate, "", 2, "paused"),
+			},
+			want: []string{"argocd-controller=30", "kube-controller-manager=10", "kubectl (manual edits)=10"},
+		},
+		{
+			name: "status writes do not count",
+			fields: []metav1.ManagedFieldsEntry{
+				entry("kubectl-edit", metav1.ManagedFieldsOperationUpdate, "", 1, "replicas"),
+				entry("kube-controller-manager", metav1.ManagedFieldsOperationUpdate, "status", 5, "replicas"),
+			},
+			want: []string{"kubectl (manual edits)=30"},
+		},
+		{
+			name: "ties go by actor name",
+			fields: []metav1.ManagedFieldsEntry{
+				entry("zeta", metav1.ManagedFieldsOperationUpdate, "", 1, "replicas"),
+				entry("kubectl-edit", metav1.ManagedFieldsOperationUpdate, "", 2, "template"),
+				entry("alpha", metav1.ManagedFieldsOperationUpdate, "", 1, "paused"),
+			},
+			want: []string{"kubectl (manual edits)=30", "alpha=10", "zeta=10"},
+		},
+		{
+			name: "same time keeps the first entry as last writer",
+			fields: []metav1.ManagedFieldsEntry{
+				entry("zeta", metav1.ManagedFieldsOperationUpdate, "", 1, "replicas"),
+				entry("alpha", metav1.ManagedFieldsOperationUpdate, "", 1, "paused"),
+			},
+			want: []string{"zeta=30", "alpha=10"},
+		},
+		{
+			name:   "signals of the same actor add up",
+			labels: map[string]string{"app.kubernetes.io/managed-by": "kubedb.com"},
+			fields: []metav1.ManagedFieldsEntry{
+				entry("kubedb-provisioner", metav1.ManagedFieldsOperationUpdate, "", 1, "replicas"),
+				entry("kubectl-patch", metav1.ManagedFieldsOperationUpdate, "", 2, "paused"),
+			},
+			want: []string{"KubeDB operator=60", "kubectl (manual edits)=30"},
+		},
+		{
+			name:   "Helm and Flux",
+			labels: map[string]string{"app.kubernetes.io/managed-by": "Helm", "helm.toolkit.fluxcd.io/name": "web", "helm.toolkit.fluxcd.io/namespace": "flux-system"},
+			annots: map[string]string{"meta.helm.sh/release-name": "web", "meta.helm.sh/release-namespace": "demo"},
+			fields: []metav1.ManagedFieldsEntry{
+				entry("helm-controller", metav1.ManagedFieldsOperationUpdate, "", 1, "replicas"),
+			},
+			want: []string{"Flux=100", "Helm=90"},
+		},
+		{
+			name:  "controller owner beats the last writer",
+			owner: true,
+			reader: objects{"Sidekick/demo/web": parse(t, `apiVersion: apps.k8s.appscode.com/v1alpha1
+kind: Sidekick
+metadata:
+  name: web
+  namespace: demo
+  labels:
+    app.kubernetes.io/managed-by: kubedb.com
+`)},
+			fields: []metav1.ManagedFieldsEntry{
+				entry("kubectl-edit", metav1.ManagedFieldsOperationUpdate, "", 1, "replicas"),
+			},
+			want: []string{"KubeOps operator=100", "KubeDB operator=50", "kubectl (manual edits)=30"},
+		},
+		{
+			name:   "gone owner",
+			owner:  true,
+			reader: objects{},
+			want:   []string{"KubeOps operator=100"},
+		},
+		{
+			name: "invalid managedFields",
+			fields: []metav1.ManagedFieldsEntry{{
+				Manager:    "broken",
+				Operation:  metav1.ManagedFieldsOperationUpdate,
+				FieldsType: "FieldsV1",
+				FieldsV1:   &metav1.FieldsV1{Raw: []byte(`{"f:spec":[]}`)},
+			}},
+			wantErr: "invalid managedFields of broken",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			obj := parse(t, deployment)
+			obj.SetLabels(c.labels)
+			obj.SetAnnotations(c.annots)
+			obj.SetManagedFields(c.fields)
+			if c.owner {
+				yes := true
+				obj.SetOwnerReferences([]metav1.OwnerReference{{APIVersion: "apps.k8s.appscode.com/v1alpha1", Kind: "Sidekick", Name: "web", Controller: &yes}})
+			}
+			r := &Resolver{}
+			if c.reader != nil {
+				r.Reader = c.reader
+			}
+			res, err := r.Resolve(context.TODO(), obj)
+			if c.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), c.wantErr) {
+					t.Fatalf("Resolve() error = %v, want %q", err, c.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, cand := range res.Candidates {
+				got = append(got, fmt.Sprintf("%s=%d", cand.Actor, cand.Score))
+			}
+			if strings.Join(got, ",") != strings.Join(c.want, ",") {
+				t.Errorf("Resolve() candidates = %v, want %v", got, c.want)
+			}
+		})
+	}
+}
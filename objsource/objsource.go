@@ -0,0 +1,154 @@
+// Package objsource lists typed objects either from a cluster or from a directory
+// of YAML and JSON dumps, so that reports can be run and checked against fixture
+// objects without a cluster.
+package objsource
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/klog/v2"
+	"os"
+	"path/filepath"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"strings"
+)
+
+// Source lists typed objects, from a cluster or from a dump of one.
+type Source interface {
+	// List returns the objects of gvk in namespace, or in all namespaces if
+	// namespace is empty. Kinds the source does not know yield no objects.
+	List(ctx context.Context, gvk schema.GroupVersionKind, namespace string) ([]client.Object, error)
+}
+
+type liveSource struct {
+	kc client.Client
+}
+
+// Live lists through kc, with the typed lists of its scheme. Kinds whose CRD is
+// not installed yield no objects.
+func Live(kc client.Client) Source {
+	return &liveSource{kc: kc}
+}
+
+func (s *liveSource) List(ctx context.Context, gvk schema.GroupVersionKind, namespace string) ([]client.Object, error) {
+	listGVK := gvk
+	listGVK.Kind += "List"
+	obj, err := s.kc.Scheme().New(listGVK)
+	if err != nil {
+		return nil, err
+	}
+	list := obj.(client.ObjectList)
+	if err := s.kc.List(ctx, list, client.InNamespace(namespace)); err != nil {
+		if meta.IsNoMatchError(err) {
+			return nil, nil // CRD not installed
+		}
+		return nil, err
+	}
+	items, err := meta.ExtractList(list)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]client.Object, 0, len(items))
+	for _, item := range items {
+		out = append(out, item.(client.Object))
+	}
+	return out, nil
+}
+
+// dirSource holds the objects of the YAML and JSON files below a directory, like
+// the output of `kubectl get -A -o yaml <kinds> > dump.yaml`. Files may hold
+// several documents and List objects.
+type dirSource struct {
+	scheme  *runtime.Scheme
+	objects map[schema.GroupVersionKind][]client.Object
+}
+
+// LoadDir reads the objects below dir, converted to the types of scheme. Objects
+// of kinds scheme does not know are skipped.
+func LoadDir(dir string, scheme *runtime.Scheme) (Source, error) {
+	s := &dirSource{scheme: scheme, objects: map[schema.GroupVersionKind][]client.Object{}}
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".yaml", ".yml", ".json":
+		default:
+			return nil
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		if err := s.load(f); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		return nil
+	})
+	return s, err
+}
+
+func (s *dirSource) load(r io.Reader) error {
+	dec := utilyaml.NewYAMLOrJSONDecoder(r, 4096)
+	for {
+		var u unstructured.Unstructured
+		if err := dec.Decode(&u.Object); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		if len(u.Object) == 0 {
+			continue // empty document
+		}
+		if u.IsList() {
+			list, err := u.ToList()
+			if err != nil {
+				return err
+			}
+			for i := range list.Items {
+				if err := s.add(&list.Items[i]); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if err := s.add(&u); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *dirSource) add(u *unstructured.Unstructured) error {
+	gvk := u.GroupVersionKind()
+	obj, err := s.scheme.New(gvk)
+	if runtime.IsNotRegisteredError(err) {
+		klog.V(2).Infof("skipping %s %s/%s: not a known kind", gvk, u.GetNamespace(), u.GetName())
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, obj); err != nil {
+		return fmt.Errorf("%s %s/%s: %w", gvk.Kind, u.GetNamespace(), u.GetName(), err)
+	}
+	s.objects[gvk] = append(s.objects[gvk], obj.(client.Object))
+	return nil
+}
+
+func (s *dirSource) List(_ context.Context, gvk schema.GroupVersionKind, namespace string) ([]client.Object, error) {
+	var out []client.Object
+	for _, obj := range s.objects[gvk] {
+		if namespace == "" || obj.GetNamespace() == namespace {
+			out = append(out, obj)
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,359 @@
+package objstore
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	kmapi "kmodules.xyz/client-go/api/v1"
+	"kmodules.xyz/client-go/conditions"
+	"net"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	ConditionBucketValid        kmapi.ConditionType = "BucketValid"
+	ConditionEndpointValid      kmapi.ConditionType = "EndpointValid"
+	ConditionTLSConfigured      kmapi.ConditionType = "TLSConfigured"
+	ConditionPathStyleSupported kmapi.ConditionType = "PathStyleSupported"
+	ConditionCredentialsFound   kmapi.ConditionType = "CredentialsFound"
+	ConditionRoundTripSucceeded kmapi.ConditionType = "RoundTripSucceeded"
+)
+
+type Options struct {
+	// RoundTrip lists the prefix, and writes and deletes an object below it.
+	// Only S3 compatible stores are supported.
+	RoundTrip bool
+	// Timeout of the round trip, 30s by default.
+	Timeout time.Duration
+}
+
+// Check validates t and returns one condition per aspect checked, led by a
+// Ready condition that is False if any of them failed with an Error severity.
+// Warnings leave Ready True.
+func Check(ctx context.Context, t Target, opts Options) kmapi.Conditions {
+	var conds []*kmapi.Condition
+	conds = append(conds, checkBucket(t))
+	if t.Provider == ProviderS3 || t.Provider == ProviderRest {
+		conds = append(conds, checkEndpoint(t), checkTLS(t))
+	}
+	if t.Provider == ProviderS3 {
+		conds = append(conds, checkPathStyle(t), checkCredentials(t))
+	}
+	if opts.RoundTrip {
+		conds = append(conds, roundTrip(ctx, t, opts))
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	out := kmapi.Conditions{*summary(conds)}
+	for _, c := range conds {
+		out = append(out, *c)
+	}
+	for i := range out {
+		out[i].LastTransitionTime.Time = now
+	}
+	return out
+}
+
+func summary(conds []*kmapi.Condition) *kmapi.Condition {
+	var failed, warned []string
+	for _, c := range conds {
+		if c.Status != metav1.ConditionFalse {
+			continue
+		}
+		msg := fmt.Sprintf("%s: %s", c.Type, c.Message)
+		switch c.Severity {
+		case kmapi.ConditionSeverityError:
+			failed = append(failed, msg)
+		case kmapi.ConditionSeverityWarning:
+			warned = append(warned, msg)
+		}
+	}
+	if len(failed) > 0 {
+		return conditions.FalseCondition(kmapi.ReadyCondition, "CheckFailed", kmapi.ConditionSeverityError, "%s", strings.Join(failed, "; "))
+	}
+	c := conditions.TrueCondition(kmapi.ReadyCondition)
+	if len(warned) > 0 {
+		c.Reason = "CheckedWithWarnings"
+		c.Message = strings.Join(warned, "; ")
+	}
+	return c
+}
+
+func failed(t kmapi.ConditionType, reason string, format string, args ...interface{}) *kmapi.Condition {
+	return conditions.FalseCondition(t, reason, kmapi.ConditionSeverityError, format, args...)
+}
+
+func warning(t kmapi.ConditionType, reason string, format string, args ...interface{}) *kmapi.Condition {
+	return conditions.FalseCondition(t, reason, kmapi.ConditionSeverityWarning, format, args...)
+}
+
+func passed(t kmapi.ConditionType, format string, args ...interface{}) *kmapi.Condition {
+	c := conditions.TrueCondition(t)
+	c.Message = fmt.Sprintf(format, args...)
+	return c
+}
+
+var (
+	s3BucketRe    = regexp.MustCompile(`^[a-z0-9][a-z0-9.-]{1,61}[a-z0-9]$`)
+	gcsBucketRe   = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{1,220}[a-z0-9]$`)
+	azureBucketRe = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+	b2BucketRe    = regexp.MustCompile(`^[a-zA-Z0-9-]{6,50}$`)
+)
+
+func checkBucket(t Target) *kmapi.Condition {
+	const ct = ConditionBucketValid
+	if t.PrefixURL != "" && t.Provider != ProviderLocal {
+		u, err := url.Parse(t.PrefixURL)
+		if err != nil {
+			return failed(ct, "InvalidPrefixURL", "%s %q is not a URL: %v", t.PrefixEnv, t.PrefixURL, err)
+		}
+		if want := prefixSchemes[t.PrefixEnv]; u.Scheme != want {
+			return failed(ct, "InvalidPrefixURL", "%s %q must start with %s://", t.PrefixEnv, t.PrefixURL, want)
+		}
+		if u.RawQuery != "" || u.Fragment != "" || u.User != nil {
+			return failed(ct, "InvalidPrefixURL", "%s %q must be <scheme>://<bucket>/<prefix>", t.PrefixEnv, t.PrefixURL)
+		}
+	}
+
+	switch t.Provider {
+	case ProviderRest:
+		return passed(ct, "rest servers have no bucket")
+	case ProviderLocal:
+		if !strings.HasPrefix(t.Path, "/") {
+			return failed(ct, "InvalidPath", "local path %q must be absolute", t.Path)
+		}
+		return checkPrefix(t)
+	case "":
+		return failed(ct, "NoBackend", "no object store is configured")
+	}
+
+	if t.Bucket == "" {
+		return failed(ct, "BucketMissing", "no bucket is set")
+	}
+	if msg := invalidBucket(t.Provider, t.Bucket); msg != "" {
+		return failed(ct, "InvalidBucketName", "bucket %q %s", t.Bucket, msg)
+	}
+	return checkPrefix(t)
+}
+
+// invalidBucket tells why name is not a valid bucket name of provider.
+func invalidBucket(provider, name string) string {
+	switch provider {
+	case ProviderS3:
+		switch {
+		case !s3BucketRe.MatchString(name):
+			return "must be 3 to 63 lowercase letters, digits, dots and hyphens, starting and ending with a letter or digit"
+		case strings.Contains(name, "..") || strings.Contains(name, ".-") || strings.Contains(name, "-."):
+			return "must not have adjacent dots or a dot next to a hyphen"
+		case net.ParseIP(name) != nil:
+			return "must not look like an IP address"
+		case strings.HasPrefix(name, "xn--") || strings.HasSuffix(name, "-s3alias"):
+			return "uses a prefix or suffix reserved by S3"
+		}
+	case ProviderGCS:
+		switch {
+		case !gcsBucketRe.MatchString(name):
+			return "must be 3 to 222 lowercase letters, digits, dots, hyphens and underscores, starting and ending with a letter or digit"
+		case strings.HasPrefix(name, "goog"):
+			return "must not start with goog"
+		}
+		for _, part := range strings.Split(name, ".") {
+			if len(part) > 63 {
+				return "must not have dot separated parts longer than 63 characters"
+			}
+		}
+	case ProviderAzure:
+		if len(name) < 3 || len(name) > 63 || !azureBucketRe.MatchString(name) {
+			return "must be 3 to 63 lowercase letters, digits and single hyphens, starting and ending with a letter or digit"
+		}
+	case ProviderB2:
+		if !b2BucketRe.MatchString(name) {
+			return "must be 6 to 50 letters, digits and hyphens"
+		}
+	}
+	return ""
+}
+
+func checkPrefix(t Target) *kmapi.Condition {
+	const ct = ConditionBucketValid
+	p := t.Prefix
+	switch {
+	case strings.Contains(p, "://"):
+		return failed(ct, "InvalidPrefix", "prefix %q is a URL, it must be a path inside the bucket", p)
+	case strings.TrimSpace(p) != p || strings.ContainsAny(p, "\t\n\r"):
+		return failed(ct, "InvalidPrefix", "prefix %q has leading, trailing or control whitespace", p)
+	}
+	for _, seg := range strings.Split(strings.Trim(p, "/"), "/") {
+		if seg == "." || seg == ".." {
+			return failed(ct, "InvalidPrefix", "prefix %q has a %q segment", p, seg)
+		}
+	}
+	if strings.HasPrefix(p, "/") || strings.Contains(p, "//") {
+		return warning(ct, "EmptyPathSegment", "prefix %q has an empty path segment, so keys get a double slash some tools do not list", p)
+	}
+	if t.Provider == ProviderLocal {
+		return passed(ct, "%s", strings.TrimSuffix(t.Path+"/"+p, "/"))
+	}
+	return passed(ct, "bucket %q, prefix %q", t.Bucket, p)
+}
+
+func checkEndpoint(t Target) *kmapi.Condition {
+	const ct = ConditionEndpointValid
+	if t.Endpoint == "" {
+		if t.Provider == ProviderRest {
+			return failed(ct, "EndpointMissing", "no rest server URL is set")
+		}
+		if t.Region == "" {
+			return warning(ct, "RegionMissing", "no endpoint is set, so AWS S3 is used, but no region is set")
+		}
+		return passed(ct, "AWS S3 in %s", t.Region)
+	}
+
+	u, err := url.Parse(t.Endpoint)
+	switch {
+	case err != nil:
+		return failed(ct, "InvalidEndpoint", "endpoint %q is not a URL: %v", t.Endpoint, err)
+	case u.Host == "" || (u.Scheme != "http" && u.Scheme != "https"):
+		return failed(ct, "InvalidEndpoint", "endpoint %q must start with http:// or https://", t.Endpoint)
+	case u.User != nil:
+		return failed(ct, "InvalidEndpoint", "endpoint %q has user info, credentials belong in the Secret", t.Endpoint)
+	case u.RawQuery != "" || u.Fragment != "":
+		return failed(ct, "InvalidEndpoint", "endpoint %q must not have a query or fragment", t.Endpoint)
+	}
+	if t.Provider == ProviderRest {
+		return passed(ct, "%s", t.Endpoint)
+	}
+	if u.Path != "" && u.Path != "/" {
+		return failed(ct, "InvalidEndpoint", "endpoint %q has a path, the bucket and prefix must be set on their own", t.Endpoint)
+	}
+	host := u.Hostname()
+	if net.ParseIP(host) == nil && len(validation.IsDNS1123Subdomain(strings.ToLower(host))) > 0 {
+		return failed(ct, "InvalidEndpoint", "endpoint host %q is not a DNS name or IP address", host)
+	}
+	if t.Bucket != "" && strings.HasPrefix(host, t.Bucket+".") {
+		return warning(ct, "BucketInEndpoint", "endpoint %q starts with the bucket, the endpoint must name the service only", t.Endpoint)
+	}
+	return passed(ct, "%s", t.Endpoint)
+}
+
+func checkTLS(t Target) *kmapi.Condition {
+	const ct = ConditionTLSConfigured
+	var caData []byte
+	if t.Credentials != nil {
+		caData = t.Credentials.CACertData
+	}
+	if len(caData) > 0 && !x509.NewCertPool().AppendCertsFromPEM(caData) {
+		return failed(ct, "InvalidCACert", "CA_CERT_DATA of Secret %s holds no PEM certificate", t.SecretName)
+	}
+
+	if t.Endpoint == "" {
+		return passed(ct, "https with the system roots")
+	}
+	u := endpointURL(t)
+	if u == nil {
+		return conditions.UnknownCondition(ct, "InvalidEndpoint", "endpoint %q is invalid", t.Endpoint)
+	}
+	if u.Scheme == "http" {
+		return warning(ct, "PlainHTTP", "endpoint %q is not TLS, credentials and WAL are sent unencrypted", t.Endpoint)
+	}
+	switch {
+	case t.InsecureTLS:
+		return warning(ct, "InsecureTLS", "the certificate of %s is not verified", u.Host)
+	case len(caData) > 0:
+		return passed(ct, "https with the CA_CERT_DATA of Secret %s", t.SecretName)
+	case t.CACertFile != "":
+		return passed(ct, "https with the CA bundle %s of the archiver, which is not checked", t.CACertFile)
+	}
+	if net.ParseIP(u.Hostname()) != nil {
+		return warning(ct, "IPEndpoint", "%s is an IP address, so its certificate must be signed by a public root for an IP SAN or a CA must be given", u.Host)
+	}
+	return passed(ct, "https with the system roots")
+}
+
+// endpointURL parses the endpoint of t, and returns nil if it is not set or not
+// an http or https URL.
+func endpointURL(t Target) *url.URL {
+	u, err := url.Parse(t.Endpoint)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil
+	}
+	return u
+}
+
+// needsPathStyle tells why virtual host style requests, which address the
+// bucket as a subdomain of the endpoint, cannot reach bucket.
+func needsPathStyle(t Target) string {
+	u := endpointURL(t)
+	if u == nil {
+		return ""
+	}
+	host := u.Hostname()
+	switch {
+	case net.ParseIP(host) != nil:
+		return fmt.Sprintf("endpoint host %s is an IP address", host)
+	case !strings.Contains(host, "."), host == "localhost",
+		strings.HasSuffix(host, ".svc"), strings.Contains(host, ".svc."), strings.HasSuffix(host, ".local"):
+		return fmt.Sprintf("%s.%s does not resolve", t.Bucket, host)
+	case u.Scheme == "https" && strings.Contains(t.Bucket, "."):
+		return fmt.Sprintf("bucket %s has dots, which a wildcard certificate of %s does not cover", t.Bucket, host)
+	}
+	return ""
+}
+
+func isAWS(t Target) bool {
+	if t.Endpoint == "" {
+		return true
+	}
+	u := endpointURL(t)
+	return u != nil && strings.HasSuffix(u.Hostname(), ".amazonaws.com")
+}
+
+func checkPathStyle(t Target) *kmapi.Condition {
+	const ct = ConditionPathStyleSupported
+	if t.Endpoint != "" && endpointURL(t) == nil {
+		return conditions.UnknownCondition(ct, "InvalidEndpoint", "endpoint %q is invalid", t.Endpoint)
+	}
+	why := needsPathStyle(t)
+	switch {
+	case t.PathStyle == nil && why != "":
+		return conditions.UnknownCondition(ct, "NotSet", "path style requests are needed as %s, which the archiver must set", why)
+	case t.PathStyle == nil:
+		return passed(ct, "the addressing style is left to the archiver")
+	case *t.PathStyle:
+		return passed(ct, "path style requests")
+	case why != "":
+		return failed(ct, "PathStyleRequired", "path style requests are needed as %s, set %s=true", why, EnvForcePathStyle)
+	case !isAWS(t):
+		return warning(ct, "VirtualHostStyle", "most S3 compatible stores, like MinIO and Ceph, need path style requests, set %s=true unless %s serves virtual hosts", EnvForcePathStyle, t.Endpoint)
+	}
+	return passed(ct, "virtual host style requests")
+}
+
+func checkCredentials(t Target) *kmapi.Condition {
+	const ct = ConditionCredentialsFound
+	if t.Credentials == nil {
+		if t.SecretName == "" {
+			return conditions.UnknownCondition(ct, "NotLoaded", "the credentials were not loaded")
+		}
+		return conditions.UnknownCondition(ct, "NotLoaded", "Secret %s was not loaded", t.SecretName)
+	}
+	var missing []string
+	if t.Credentials.AccessKeyID == "" {
+		missing = append(missing, "AWS_ACCESS_KEY_ID")
+	}
+	if t.Credentials.SecretAccessKey == "" {
+		missing = append(missing, "AWS_SECRET_ACCESS_KEY")
+	}
+	if len(missing) == 0 {
+		return passed(ct, "access key %s", t.Credentials.AccessKeyID)
+	}
+	if isAWS(t) {
+		return warning(ct, "MissingCredentials", "%s not set, so the archiver needs an IAM role", strings.Join(missing, " and "))
+	}
+	return failed(ct, "MissingCredentials", "%s not set", strings.Join(missing, " and "))
+}
@@ -0,0 +1,225 @@
+// Package objstore checks the object store settings given to WAL-G archivers
+// before a backup depends on them. Settings are read from an objectstore-api
+// Backend, a KubeStash BackupStorage or the WALG_* and AWS_* environment of an
+// archiver, like the one the KubeDB operator assembles for a Sidekick. Check
+// reports the result as kmapi conditions, and can confirm the credentials and
+// the path style with a list, put and delete round trip against the store.
+package objstore
+
+import (
+	storeapi "kmodules.xyz/objectstore-api/api/v1"
+	storageapi "kubestash.dev/apimachinery/apis/storage/v1alpha1"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	ProviderS3    = "s3"
+	ProviderGCS   = "gcs"
+	ProviderAzure = "azure"
+	ProviderSwift = "swift"
+	ProviderB2    = "b2"
+	ProviderLocal = "local"
+	ProviderRest  = "rest"
+)
+
+// WAL-G and AWS SDK environment variables of archivers.
+const (
+	EnvS3Prefix       = "WALG_S3_PREFIX"
+	EnvGSPrefix       = "WALG_GS_PREFIX"
+	EnvAzurePrefix    = "WALG_AZ_PREFIX"
+	EnvSwiftPrefix    = "WALG_SWIFT_PREFIX"
+	EnvFilePrefix     = "WALG_FILE_PREFIX"
+	EnvS3CACertFile   = "WALG_S3_CA_CERT_FILE"
+	EnvEndpoint       = "AWS_ENDPOINT"
+	EnvRegion         = "AWS_REGION"
+	EnvForcePathStyle = "AWS_S3_FORCE_PATH_STYLE"
+)
+
+// prefixSchemes are the URL schemes of the WALG_*_PREFIX variables.
+var prefixSchemes = map[string]string{
+	EnvS3Prefix:    "s3",
+	EnvGSPrefix:    "gs",
+	EnvAzurePrefix: "azure",
+	EnvSwiftPrefix: "swift",
+}
+
+var prefixProviders = map[string]string{
+	EnvS3Prefix:    ProviderS3,
+	EnvGSPrefix:    ProviderGCS,
+	EnvAzurePrefix: ProviderAzure,
+	EnvSwiftPrefix: ProviderSwift,
+	EnvFilePrefix:  ProviderLocal,
+}
+
+// Target is an object store location with the settings to reach it.
+type Target struct {
+	Provider string `json:"provider"`
+	// Endpoint of an S3 compatible store, empty for AWS.
+	Endpoint string `json:"endpoint,omitempty"`
+	// Bucket, or container for Azure and Swift.
+	Bucket string `json:"bucket,omitempty"`
+	Prefix string `json:"prefix,omitempty"`
+	Region string `json:"region,omitempty"`
+	// Path is the directory of a local backend.
+	Path        string `json:"path,omitempty"`
+	InsecureTLS bool   `json:"insecureTLS,omitempty"`
+	// PathStyle is nil when the source leaves the addressing style to the
+	// archiver, like objectstore-api Backends do.
+	PathStyle *bool `json:"pathStyle,omitempty"`
+
+	// PrefixEnv and PrefixURL are the WALG_*_PREFIX variable and its value, when
+	// the target was read from an environment.
+	PrefixEnv string `json:"prefixEnv,omitempty"`
+	PrefixURL string `json:"prefixURL,omitempty"`
+	// CACertFile is the WALG_S3_CA_CERT_FILE path inside the archiver container.
+	CACertFile string `json:"caCertFile,omitempty"`
+
+	// SecretName holds the credentials, if the source names one.
+	SecretName string `json:"secretName,omitempty"`
+	// Credentials is nil if they were not loaded.
+	Credentials *Credentials `json:"-"`
+}
+
+type Credentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	CACertData      []byte
+}
+
+// String returns the target as the URL WAL-G would be given.
+func (t Target) String() string {
+	if t.PrefixURL != "" {
+		return t.PrefixURL
+	}
+	switch t.Provider {
+	case ProviderLocal:
+		return t.Path
+	case ProviderRest:
+		return t.Endpoint
+	}
+	scheme := t.Provider
+	if t.Provider == ProviderGCS {
+		scheme = "gs"
+	}
+	return scheme + "://" + strings.TrimSuffix(t.Bucket+"/"+strings.TrimPrefix(t.Prefix, "/"), "/")
+}
+
+// FromBackend reads an objectstore-api Backend. Its credentials live in
+// b.StorageSecretName and are not loaded.
+func FromBackend(b storeapi.Backend) Target {
+	t := Target{SecretName: b.StorageSecretName}
+	switch {
+	case b.S3 != nil:
+		t.Provider = ProviderS3
+		t.Endpoint = b.S3.Endpoint
+		t.Bucket = b.S3.Bucket
+		t.Prefix = b.S3.Prefix
+		t.Region = b.S3.Region
+		t.InsecureTLS = b.S3.InsecureTLS
+	case b.GCS != nil:
+		t.Provider = ProviderGCS
+		t.Bucket = b.GCS.Bucket
+		t.Prefix = b.GCS.Prefix
+	case b.Azure != nil:
+		t.Provider = ProviderAzure
+		t.Bucket = b.Azure.Container
+		t.Prefix = b.Azure.Prefix
+	case b.Swift != nil:
+		t.Provider = ProviderSwift
+		t.Bucket = b.Swift.Container
+		t.Prefix = b.Swift.Prefix
+	case b.B2 != nil:
+		t.Provider = ProviderB2
+		t.Bucket = b.B2.Bucket
+		t.Prefix = b.B2.Prefix
+	case b.Rest != nil:
+		t.Provider = ProviderRest
+		t.Endpoint = b.Rest.URL
+	case b.Local != nil:
+		t.Provider = ProviderLocal
+		t.Path = b.Local.MountPath
+		t.Prefix = b.Local.SubPath
+	}
+	return t
+}
+
+// FromBackupStorage reads the backend of a KubeStash BackupStorage, which KubeDB
+// archivers store their WAL in.
+func FromBackupStorage(bs *storageapi.BackupStorage) Target {
+	b := bs.Spec.Storage
+	t := Target{Provider: string(b.Provider)}
+	switch {
+	case b.S3 != nil:
+		t.Provider = ProviderS3
+		t.Endpoint = b.S3.Endpoint
+		t.Bucket = b.S3.Bucket
+		t.Prefix = b.S3.Prefix
+		t.Region = b.S3.Region
+		t.InsecureTLS = b.S3.InsecureTLS
+		t.SecretName = b.S3.SecretName
+	case b.GCS != nil:
+		t.Provider = ProviderGCS
+		t.Bucket = b.GCS.Bucket
+		t.Prefix = b.GCS.Prefix
+		t.SecretName = b.GCS.SecretName
+	case b.Azure != nil:
+		t.Provider = ProviderAzure
+		t.Bucket = b.Azure.Container
+		t.Prefix = b.Azure.Prefix
+		t.SecretName = b.Azure.SecretName
+	case b.Local != nil:
+		t.Provider = ProviderLocal
+		t.Path = b.Local.MountPath
+		t.Prefix = b.Local.SubPath
+	}
+	return t
+}
+
+// FromEnv reads the environment of a WAL-G archiver. ok is false if env has no
+// WALG_*_PREFIX variable. The credentials are taken from env as well.
+func FromEnv(env map[string]string) (t Target, ok bool) {
+	for _, name := range []string{EnvS3Prefix, EnvGSPrefix, EnvAzurePrefix, EnvSwiftPrefix, EnvFilePrefix} {
+		if v, found := env[name]; found {
+			t.Provider = prefixProviders[name]
+			t.PrefixEnv = name
+			t.PrefixURL = v
+			ok = true
+			break
+		}
+	}
+	if !ok {
+		return t, false
+	}
+
+	if t.Provider == ProviderLocal {
+		t.Path = t.PrefixURL
+	} else if u, err := url.Parse(t.PrefixURL); err == nil {
+		// Malformed prefixes are reported by Check.
+		t.Bucket = u.Host
+		t.Prefix = strings.TrimPrefix(u.Path, "/")
+	}
+	if t.Provider == ProviderS3 {
+		t.Endpoint = env[EnvEndpoint]
+		t.Region = env[EnvRegion]
+		t.CACertFile = env[EnvS3CACertFile]
+		pathStyle, _ := strconv.ParseBool(env[EnvForcePathStyle])
+		t.PathStyle = &pathStyle
+		t.Credentials = &Credentials{
+			AccessKeyID:     env[storeapi.AWS_ACCESS_KEY_ID],
+			SecretAccessKey: env[storeapi.AWS_SECRET_ACCESS_KEY],
+		}
+	}
+	return t, true
+}
+
+// SetCredentials loads the credentials from the data of the Secret named by
+// t.SecretName, with the keys of objectstore-api.
+func (t *Target) SetCredentials(data map[string][]byte) {
+	t.Credentials = &Credentials{
+		AccessKeyID:     string(data[storeapi.AWS_ACCESS_KEY_ID]),
+		SecretAccessKey: string(data[storeapi.AWS_SECRET_ACCESS_KEY]),
+		CACertData:      data[storeapi.CA_CERT_DATA],
+	}
+}
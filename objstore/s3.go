@@ -0,0 +1,284 @@
+package objstore
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	kmapi "kmodules.xyz/client-go/api/v1"
+	"kmodules.xyz/client-go/conditions"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// s3Client sends the few S3 requests of the round trip, signed with AWS
+// Signature Version 4.
+type s3Client struct {
+	endpoint  *url.URL
+	bucket    string
+	region    string
+	pathStyle bool
+	creds     Credentials
+	hc        *http.Client
+	now       func() time.Time
+}
+
+// s3Error is the XML error body of S3.
+type s3Error struct {
+	StatusCode int    `xml:"-"`
+	Code       string `xml:"Code"`
+	Message    string `xml:"Message"`
+}
+
+func (e *s3Error) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("status %d", e.StatusCode)
+	}
+	return fmt.Sprintf("%s: %s (status %d)", e.Code, e.Message, e.StatusCode)
+}
+
+func newS3Client(t Target, pathStyle bool, timeout time.Duration) (*s3Client, error) {
+	endpoint := t.Endpoint
+	region := t.Region
+	if region == "" {
+		region = "us-east-1"
+	}
+	if endpoint == "" {
+		endpoint = "https://s3." + region + ".amazonaws.com"
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	tr := http.DefaultTransport.(*http.Transport).Clone()
+	tr.TLSClientConfig = &tls.Config{InsecureSkipVerify: t.InsecureTLS} // #nosec G402 -- set by the user
+	if t.Credentials != nil && len(t.Credentials.CACertData) > 0 {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		pool.AppendCertsFromPEM(t.Credentials.CACertData)
+		tr.TLSClientConfig.RootCAs = pool
+	}
+	c := &s3Client{
+		endpoint:  u,
+		bucket:    t.Bucket,
+		region:    region,
+		pathStyle: pathStyle,
+		hc:        &http.Client{Transport: tr, Timeout: timeout},
+		now:       time.Now,
+	}
+	if t.Credentials != nil {
+		c.creds = *t.Credentials
+	}
+	return c, nil
+}
+
+// url addresses key in the bucket, as https://host/bucket/key with path style
+// or as https://bucket.host/key with virtual host style.
+func (c *s3Client) url(key string, query url.Values) *url.URL {
+	u := *c.endpoint
+	p := "/" + key
+	if c.pathStyle {
+		p = "/" + c.bucket + p
+	} else {
+		u.Host = c.bucket + "." + u.Host
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + p
+	u.RawPath = ""
+	u.RawQuery = canonicalQuery(query)
+	return &u
+}
+
+func (c *s3Client) do(ctx context.Context, method, key string, query url.Values, body []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.url(key, query).String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	c.sign(req, body)
+	resp, err := c.hc.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		e := &s3Error{StatusCode: resp.StatusCode}
+		_ = xml.Unmarshal(data, e)
+		return nil, e
+	}
+	return data, nil
+}
+
+// sign adds the Authorization header of Signature Version 4, signing the host,
+// x-amz-content-sha256 and x-amz-date headers.
+func (c *s3Client) sign(req *http.Request, body []byte) {
+	now := c.now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	day := now.Format("20060102")
+	payloadHash := sha256Hex(body)
+	req.Header.Set("x-amz-date", amzDate)
+	req.Header.Set("x-amz-content-sha256", payloadHash)
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		canonicalPath(req.URL.Path),
+		req.URL.RawQuery,
+		"host:" + req.URL.Host + "\n" +
+			"x-amz-content-sha256:" + payloadHash + "\n" +
+			"x-amz-date:" + amzDate + "\n",
+		"host;x-amz-content-sha256;x-amz-date",
+		payloadHash,
+	}, "\n")
+	scope := day + "/" + c.region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	key := hmacSHA256([]byte("AWS4"+c.creds.SecretAccessKey), day)
+	key = hmacSHA256(key, c.region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=host;x-amz-content-sha256;x-amz-date, Signature=%s",
+		c.creds.AccessKeyID, scope, signature))
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// awsEscape escapes s the way Signature Version 4 expects, every byte but the
+// unreserved characters of RFC 3986.
+func awsEscape(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
+
+func canonicalPath(p string) string {
+	segs := strings.Split(p, "/")
+	for i, s := range segs {
+		segs[i] = awsEscape(s)
+	}
+	return strings.Join(segs, "/")
+}
+
+func canonicalQuery(q url.Values) string {
+	keys := make([]string, 0, len(q))
+	for k := range q {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var parts []string
+	for _, k := range keys {
+		for _, v := range q[k] {
+			parts = append(parts, awsEscape(k)+"="+awsEscape(v))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// listPutDelete lists the prefix, then writes and deletes a marker object
+// below it, and returns the key of the marker.
+func (c *s3Client) listPutDelete(ctx context.Context, prefix string) (string, error) {
+	prefix = strings.Trim(prefix, "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+	if _, err := c.do(ctx, http.MethodGet, "", url.Values{"list-type": {"2"}, "prefix": {prefix}, "max-keys": {"1"}}, nil); err != nil {
+		return "", fmt.Errorf("list %s: %w", prefix, err)
+	}
+	key := fmt.Sprintf("%s.objstore-check-%d", prefix, c.now().UnixNano())
+	if _, err := c.do(ctx, http.MethodPut, key, nil, []byte("objstore check\n")); err != nil {
+		return key, fmt.Errorf("put %s: %w", key, err)
+	}
+	if _, err := c.do(ctx, http.MethodDelete, key, nil, nil); err != nil {
+		return key, fmt.Errorf("delete %s: %w", key, err)
+	}
+	return key, nil
+}
+
+func roundTrip(ctx context.Context, t Target, opts Options) *kmapi.Condition {
+	const ct = ConditionRoundTripSucceeded
+	if t.Provider != ProviderS3 {
+		return conditions.UnknownCondition(ct, "NotSupported", "round trips are only supported for S3 compatible stores")
+	}
+	if t.Credentials == nil {
+		return conditions.UnknownCondition(ct, "NotLoaded", "the credentials were not loaded")
+	}
+	timeout := opts.Timeout
+	if timeout == 0 {
+		timeout = 30 * time.Second
+	}
+
+	// Without a configured style, try what S3 clients default to for the endpoint.
+	pathStyle := !isAWS(t)
+	if t.PathStyle != nil {
+		pathStyle = *t.PathStyle
+	}
+	c, err := newS3Client(t, pathStyle, timeout)
+	if err != nil {
+		return failed(ct, "RequestFailed", "%v", err)
+	}
+	key, err := c.listPutDelete(ctx, t.Prefix)
+	if err == nil {
+		return passed(ct, "listed, wrote and deleted %s with %s", key, styleName(pathStyle))
+	}
+
+	var s3err *s3Error
+	if errors.As(err, &s3err) {
+		switch s3err.Code {
+		case "InvalidAccessKeyId", "SignatureDoesNotMatch", "AccessDenied", "NoSuchBucket", "AuthorizationHeaderMalformed":
+			return failed(ct, s3err.Code, "%v", err)
+		}
+	}
+	// Unresolvable hosts and unroutable buckets may only be a matter of the
+	// addressing style.
+	if other, oerr := newS3Client(t, !pathStyle, timeout); oerr == nil {
+		if _, oerr := other.listPutDelete(ctx, t.Prefix); oerr == nil {
+			reason := "PathStyleRequired"
+			if pathStyle {
+				reason = "VirtualHostStyleRequired"
+			}
+			return failed(ct, reason, "fails with %s: %v; succeeds with %s", styleName(pathStyle), err, styleName(!pathStyle))
+		}
+	}
+	var dnsErr *net.DNSError
+	var certErr *tls.CertificateVerificationError
+	switch {
+	case errors.As(err, &certErr):
+		return failed(ct, "CertificateInvalid", "%v", err)
+	case errors.As(err, &dnsErr):
+		return failed(ct, "HostNotFound", "%v", err)
+	case s3err != nil && s3err.Code != "":
+		return failed(ct, s3err.Code, "%v", err)
+	}
+	return failed(ct, "RequestFailed", "%v", err)
+}
+
+func styleName(pathStyle bool) string {
+	if pathStyle {
+		return "path style"
+	}
+	return "virtual host style"
+}
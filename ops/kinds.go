@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	catalogapi "kubedb.dev/apimachinery/apis/catalog/v1alpha1"
+	dbapi "kubedb.dev/apimachinery/apis/kubedb/v1"
+	opsapi "kubedb.dev/apimachinery/apis/ops/v1alpha1"
+	"strings"
+)
+
+// dbKind ties a KubeDB database kind to the catalog kind of its versions and to
+// the kind of the ops requests that change it.
+type dbKind struct {
+	Kind           string
+	VersionKind    string
+	OpsRequestKind string
+}
+
+var dbKinds = []dbKind{
+	{dbapi.ResourceKindElasticsearch, catalogapi.ResourceKindElasticsearchVersion, opsapi.ResourceKindElasticsearchOpsRequest},
+	{dbapi.ResourceKindKafka, catalogapi.ResourceKindKafkaVersion, opsapi.ResourceKindKafkaOpsRequest},
+	{dbapi.ResourceKindMariaDB, catalogapi.ResourceKindMariaDBVersion, opsapi.ResourceKindMariaDBOpsRequest},
+	{dbapi.ResourceKindMemcached, catalogapi.ResourceKindMemcachedVersion, opsapi.ResourceKindMemcachedOpsRequest},
+	{dbapi.ResourceKindMongoDB, catalogapi.ResourceKindMongoDBVersion, opsapi.ResourceKindMongoDBOpsRequest},
+	{dbapi.ResourceKindMySQL, catalogapi.ResourceKindMySQLVersion, opsapi.ResourceKindMySQLOpsRequest},
+	{dbapi.ResourceKindPerconaXtraDB, catalogapi.ResourceKindPerconaXtraDBVersion, opsapi.ResourceKindPerconaXtraDBOpsRequest},
+	{dbapi.ResourceKindPgBouncer, catalogapi.ResourceKindPgBouncerVersion, opsapi.ResourceKindPgBouncerOpsRequest},
+	{dbapi.ResourceKindPostgres, catalogapi.ResourceKindPostgresVersion, opsapi.ResourceKindPostgresOpsRequest},
+	{dbapi.ResourceKindProxySQL, catalogapi.ResourceKindProxySQLVersion, opsapi.ResourceKindProxySQLOpsRequest},
+	{dbapi.ResourceKindRedis, catalogapi.ResourceKindRedisVersion, opsapi.ResourceKindRedisOpsRequest},
+}
+
+func lookupKind(kind string) (*dbKind, error) {
+	for i := range dbKinds {
+		if strings.EqualFold(dbKinds[i].Kind, kind) {
+			return &dbKinds[i], nil
+		}
+	}
+	names := make([]string, 0, len(dbKinds))
+	for _, k := range dbKinds {
+		names = append(names, k.Kind)
+	}
+	return nil, fmt.Errorf("unsupported database kind %q, expected one of %s", kind, strings.Join(names, ", "))
+}
+
+func (k *dbKind) GVK() schema.GroupVersionKind {
+	return dbapi.SchemeGroupVersion.WithKind(k.Kind)
+}
+
+func (k *dbKind) VersionGVK() schema.GroupVersionKind {
+	return catalogapi.SchemeGroupVersion.WithKind(k.VersionKind)
+}
+
+func (k *dbKind) OpsRequestGVK() schema.GroupVersionKind {
+	return opsapi.SchemeGroupVersion.WithKind(k.OpsRequestKind)
+}
@@ -0,0 +1,78 @@
+// ops works with KubeDB databases the way the ops manager expects: changes to a
+// running database go through *OpsRequest objects instead of spec edits.
+//
+//	go run ./ops [--kubeconfig=...] [--context=...] upgrade-plan [-n demo] [--to 6.0.12] [--apply] mongodb mg
+//	go run ./ops request -n demo --apply --watch postgres pg scale 3
+//	go run ./ops request -n demo postgres pg resources --cpu=500m --memory=1Gi
+//	go run ./ops watch -n demo postgres pg-horizontalscaling-3
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/ArnobKumarSaha/k8s/kubeclient"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/klog/v2"
+	kubedbscheme "kubedb.dev/apimachinery/client/clientset/versioned/scheme"
+	"os"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var (
+	scm = runtime.NewScheme()
+)
+
+func init() {
+	utilruntime.Must(clientgoscheme.AddToScheme(scm))
+	utilruntime.Must(kubedbscheme.AddToScheme(scm))
+}
+
+var kopts kubeclient.Options
+
+var commands = map[string]func(args []string) error{
+	"upgrade-plan": runUpgradePlan,
+	"request":      runRequest,
+	"watch":        runWatch,
+}
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "Usage: ops [flags] <command> [args]")
+		fmt.Fprintln(flag.CommandLine.Output(), "Commands: upgrade-plan, request, watch")
+		flag.PrintDefaults()
+	}
+	kopts.AddFlags(flag.CommandLine)
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	run, ok := commands[flag.Arg(0)]
+	if !ok {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err := run(flag.Args()[1:]); err != nil {
+		klog.Fatalln(err)
+	}
+}
+
+func newClient() (client.Client, error) {
+	return kubeclient.New(kopts, scm).Client()
+}
+
+// parseInterspersed parses flags that may come before, between or after the
+// positional arguments, and returns the positional arguments.
+func parseInterspersed(fs *flag.FlagSet, args []string) []string {
+	var pos []string
+	for {
+		_ = fs.Parse(args)
+		if fs.NArg() == 0 {
+			return pos
+		}
+		pos = append(pos, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
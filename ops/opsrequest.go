@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"k8s.io/apimachinery/pkg/runtime"
+	"regexp"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"strings"
+)
+
+const opsTypeUpdateVersion = "UpdateVersion"
+
+var invalidNameChars = regexp.MustCompile(`[^a-z0-9.-]+`)
+
+// opsRequestName derives a readable name like mg-updateversion-6.0.12.
+func opsRequestName(db, opsType, suffix string) string {
+	name := strings.ToLower(db + "-" + opsType)
+	if suffix != "" {
+		name += "-" + strings.ToLower(suffix)
+	}
+	name = strings.Trim(invalidNameChars.ReplaceAllString(name, "-"), "-.")
+	if len(name) > 63 {
+		name = strings.TrimRight(name[:63], "-.")
+	}
+	return name
+}
+
+// newOpsRequest builds the typed ops request of kind k.OpsRequestKind for db. The
+// spec is given as unstructured content, since every KubeDB ops request shares
+// the type and databaseRef fields but names its type specific section differently.
+func newOpsRequest(k *dbKind, db client.Object, opsType, suffix string, spec map[string]interface{}) (client.Object, error) {
+	content := map[string]interface{}{
+		"spec": map[string]interface{}{
+			"type": opsType,
+			"databaseRef": map[string]interface{}{
+				"name": db.GetName(),
+			},
+		},
+	}
+	for k, v := range spec {
+		content["spec"].(map[string]interface{})[k] = v
+	}
+
+	obj, err := scm.New(k.OpsRequestGVK())
+	if err != nil {
+		return nil, err
+	}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(content, obj); err != nil {
+		return nil, fmt.Errorf("invalid %s spec: %w", k.OpsRequestKind, err)
+	}
+	req := obj.(client.Object)
+	req.GetObjectKind().SetGroupVersionKind(k.OpsRequestGVK())
+	req.SetName(opsRequestName(db.GetName(), opsType, suffix))
+	req.SetNamespace(db.GetNamespace())
+	return req, nil
+}
@@ -0,0 +1,328 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	opsapi "kubedb.dev/apimachinery/apis/ops/v1alpha1"
+	"os"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+	"strconv"
+	"time"
+)
+
+const (
+	opsTypeHorizontalScaling = "HorizontalScaling"
+	opsTypeVerticalScaling   = "VerticalScaling"
+	opsTypeVolumeExpansion   = "VolumeExpansion"
+	opsTypeRestart           = "Restart"
+	opsTypeReconfigureTLS    = "ReconfigureTLS"
+)
+
+type requestOptions struct {
+	cpu, memory string
+	offline     bool
+}
+
+// buildOpsRequest turns an intent like "scale 3" into the ops request for db,
+// after checking it against the current spec of db.
+func buildOpsRequest(k *dbKind, db client.Object, intent string, args []string, opts requestOptions) (client.Object, error) {
+	t, err := newOpsTarget(db)
+	if err != nil {
+		return nil, err
+	}
+	stamp := time.Now().Format("20060102-150405")
+
+	switch intent {
+	case "scale":
+		if len(args) != 1 {
+			return nil, fmt.Errorf("usage: scale <replicas>")
+		}
+		n, err := strconv.ParseInt(args[0], 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid replicas %q: %w", args[0], err)
+		}
+		replicas := int32(n)
+		min, max, err := t.ReplicaLimits()
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case replicas == t.Replicas():
+			return nil, fmt.Errorf("%s already runs %d replicas", db.GetName(), replicas)
+		case replicas < min:
+			return nil, fmt.Errorf("%d replicas is below the minimum of %d", replicas, min)
+		case max > 0 && replicas > max:
+			return nil, fmt.Errorf("%d replicas is above the maximum of %d", replicas, max)
+		}
+		return newOpsRequest(k, db, opsTypeHorizontalScaling, args[0], t.HorizontalScaling(replicas))
+
+	case "expand":
+		if len(args) != 1 {
+			return nil, fmt.Errorf("usage: expand <size>")
+		}
+		size, err := resource.ParseQuantity(args[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid size %q: %w", args[0], err)
+		}
+		cur, err := t.Storage()
+		if err != nil {
+			return nil, err
+		}
+		if size.Cmp(*cur) <= 0 {
+			return nil, fmt.Errorf("volumes can only grow, %s is not larger than the current %s", size.String(), cur.String())
+		}
+		mode := opsapi.VolumeExpansionModeOnline
+		if opts.offline {
+			mode = opsapi.VolumeExpansionModeOffline
+		}
+		return newOpsRequest(k, db, opsTypeVolumeExpansion, size.String(), t.VolumeExpansion(size, mode))
+
+	case "resources":
+		if opts.cpu == "" && opts.memory == "" {
+			return nil, fmt.Errorf("usage: resources --cpu=<cpu> --memory=<memory>")
+		}
+		cur := t.Resources()
+		res := *cur.DeepCopy()
+		if res.Requests == nil {
+			res.Requests = core.ResourceList{}
+		}
+		if res.Limits == nil {
+			res.Limits = core.ResourceList{}
+		}
+		if opts.cpu != "" {
+			q, err := resource.ParseQuantity(opts.cpu)
+			if err != nil {
+				return nil, fmt.Errorf("invalid cpu %q: %w", opts.cpu, err)
+			}
+			res.Requests[core.ResourceCPU] = q
+		}
+		if opts.memory != "" {
+			q, err := resource.ParseQuantity(opts.memory)
+			if err != nil {
+				return nil, fmt.Errorf("invalid memory %q: %w", opts.memory, err)
+			}
+			// Memory can not be throttled, so like KubeDB itself the limit follows
+			// the request instead of letting the pods get OOM killed later.
+			res.Requests[core.ResourceMemory] = q
+			res.Limits[core.ResourceMemory] = q
+		}
+		for name, limit := range res.Limits {
+			if req, ok := res.Requests[name]; ok && req.Cmp(limit) > 0 {
+				return nil, fmt.Errorf("%s request %s is above the limit %s", name, req.String(), limit.String())
+			}
+		}
+		if equality.Semantic.DeepEqual(cur, res) {
+			return nil, fmt.Errorf("%s already has these resources", db.GetName())
+		}
+		return newOpsRequest(k, db, opsTypeVerticalScaling, stamp, t.VerticalScaling(res))
+
+	case "restart":
+		return newOpsRequest(k, db, opsTypeRestart, stamp, section("restart", map[string]interface{}{}))
+
+	case "rotate-tls":
+		if t.TLS() == nil {
+			return nil, fmt.Errorf("%s does not use TLS, there are no certificates to rotate", db.GetName())
+		}
+		return newOpsRequest(k, db, opsTypeReconfigureTLS, stamp, section("tls", map[string]interface{}{
+			"rotateCertificates": true,
+		}))
+	}
+	return nil, fmt.Errorf("unknown intent %q, expected one of scale, expand, resources, restart, rotate-tls", intent)
+}
+
+func runRequest(args []string) error {
+	fs := flag.NewFlagSet("request", flag.ExitOnError)
+	namespace := fs.String("n", "default", "Namespace of the database.")
+	apply := fs.Bool("apply", false, "Create the ops request instead of printing it.")
+	watch := fs.Bool("watch", false, "With --apply, wait for the ops request to finish.")
+	timeout := fs.Duration("timeout", 30*time.Minute, "How long --watch waits.")
+	var opts requestOptions
+	fs.StringVar(&opts.cpu, "cpu", "", "CPU request for the resources intent.")
+	fs.StringVar(&opts.memory, "memory", "", "Memory request and limit for the resources intent.")
+	fs.BoolVar(&opts.offline, "offline", false, "Expand volumes offline, for storage classes that can not expand online.")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: ops request [flags] <kind> <name> scale <replicas> | expand <size> | resources | restart | rotate-tls")
+		fs.PrintDefaults()
+	}
+	pos := parseInterspersed(fs, args)
+	if len(pos) < 3 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	k, err := lookupKind(pos[0])
+	if err != nil {
+		return err
+	}
+
+	kc, err := newClient()
+	if err != nil {
+		return err
+	}
+	ctx := context.TODO()
+
+	obj, err := scm.New(k.GVK())
+	if err != nil {
+		return err
+	}
+	db := obj.(client.Object)
+	if err := kc.Get(ctx, types.NamespacedName{Namespace: *namespace, Name: pos[1]}, db); err != nil {
+		return err
+	}
+	req, err := buildOpsRequest(k, db, pos[2], pos[3:], opts)
+	if err != nil {
+		return fmt.Errorf("refusing to create the ops request: %w", err)
+	}
+	if err := warnPending(ctx, kc, k, db); err != nil {
+		return err
+	}
+
+	if !*apply {
+		data, err := yaml.Marshal(req)
+		if err != nil {
+			return err
+		}
+		fmt.Print(string(data))
+		return nil
+	}
+	if err := kc.Create(ctx, req); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "created %s %s/%s\n", k.OpsRequestKind, req.GetNamespace(), req.GetName())
+	if !*watch {
+		return nil
+	}
+	return watchOpsRequest(ctx, kc, k, client.ObjectKeyFromObject(req), *timeout)
+}
+
+func runWatch(args []string) error {
+	fs := flag.NewFlagSet("watch", flag.ExitOnError)
+	namespace := fs.String("n", "default", "Namespace of the ops request.")
+	timeout := fs.Duration("timeout", 30*time.Minute, "How long to wait.")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: ops watch [flags] <kind> <ops-request-name>")
+		fs.PrintDefaults()
+	}
+	pos := parseInterspersed(fs, args)
+	if len(pos) != 2 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	k, err := lookupKind(pos[0])
+	if err != nil {
+		return err
+	}
+	kc, err := newClient()
+	if err != nil {
+		return err
+	}
+	return watchOpsRequest(context.TODO(), kc, k, types.NamespacedName{Namespace: *namespace, Name: pos[1]}, *timeout)
+}
+
+// opsRequestStatus reads the status every ops request kind shares.
+func opsRequestStatus(obj runtime.Object) (*opsapi.OpsRequestStatus, error) {
+	u, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil, err
+	}
+	var status opsapi.OpsRequestStatus
+	if m, ok := u["status"].(map[string]interface{}); ok {
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(m, &status); err != nil {
+			return nil, err
+		}
+	}
+	return &status, nil
+}
+
+func terminal(phase opsapi.OpsRequestPhase) bool {
+	switch phase {
+	case opsapi.OpsRequestPhaseSuccessful, opsapi.OpsRequestPhaseFailed, opsapi.OpsRequestPhaseSkipped, opsapi.OpsRequestDenied:
+		return true
+	}
+	return false
+}
+
+// warnPending points out unfinished ops requests of db. The ops manager runs them
+// one at a time, so a new request waits for those.
+func warnPending(ctx context.Context, kc client.Client, k *dbKind, db client.Object) error {
+	listGVK := k.OpsRequestGVK()
+	listGVK.Kind += "List"
+	obj, err := scm.New(listGVK)
+	if err != nil {
+		return err
+	}
+	list := obj.(client.ObjectList)
+	if err := kc.List(ctx, list, client.InNamespace(db.GetNamespace())); err != nil {
+		return err
+	}
+	return meta.EachListItem(list, func(item runtime.Object) error {
+		u, err := runtime.DefaultUnstructuredConverter.ToUnstructured(item)
+		if err != nil {
+			return err
+		}
+		ref, _, _ := unstructured.NestedString(u, "spec", "databaseRef", "name")
+		if ref != db.GetName() {
+			return nil
+		}
+		status, err := opsRequestStatus(item)
+		if err != nil {
+			return err
+		}
+		if !terminal(status.Phase) {
+			fmt.Fprintf(os.Stderr, "warning: %s %s is still %s, the new request will wait for it\n",
+				k.OpsRequestKind, item.(client.Object).GetName(), status.Phase)
+		}
+		return nil
+	})
+}
+
+// watchOpsRequest polls the ops request until it reaches a final phase, printing
+// every phase change and new condition on the way.
+func watchOpsRequest(ctx context.Context, kc client.Client, k *dbKind, key types.NamespacedName, timeout time.Duration) error {
+	obj, err := scm.New(k.OpsRequestGVK())
+	if err != nil {
+		return err
+	}
+	req := obj.(client.Object)
+
+	var phase opsapi.OpsRequestPhase
+	seen := map[string]bool{}
+	err = wait.PollUntilContextTimeout(ctx, 5*time.Second, timeout, true, func(ctx context.Context) (bool, error) {
+		if err := kc.Get(ctx, key, req); err != nil {
+			return false, err
+		}
+		status, err := opsRequestStatus(req)
+		if err != nil {
+			return false, err
+		}
+		for _, c := range status.Conditions {
+			id := fmt.Sprintf("%s/%s/%s", c.Type, c.Status, c.Reason)
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+			fmt.Fprintf(os.Stderr, "%s  %s=%s  %s\n", c.LastTransitionTime.Format(time.RFC3339), c.Type, c.Status, c.Message)
+		}
+		if status.Phase != phase {
+			phase = status.Phase
+			fmt.Fprintf(os.Stderr, "phase: %s\n", phase)
+		}
+		return terminal(phase), nil
+	})
+	if err != nil {
+		return fmt.Errorf("%s %s did not finish: %w", k.OpsRequestKind, key, err)
+	}
+	if phase != opsapi.OpsRequestPhaseSuccessful {
+		return fmt.Errorf("%s %s ended %s", k.OpsRequestKind, key, phase)
+	}
+	return nil
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
+	kmapi "kmodules.xyz/client-go/api/v1"
+	"kubedb.dev/apimachinery/apis/kubedb"
+	dbapi "kubedb.dev/apimachinery/apis/kubedb/v1"
+	"reflect"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"strings"
+	"testing"
+)
+
+// newPostgres returns a Postgres of 3 replicas with 10Gi of storage and 1Gi of
+// memory.
+func newPostgres() *dbapi.Postgres {
+	db := &dbapi.Postgres{}
+	db.Name, db.Namespace = "pg", "demo"
+	db.Spec.Replicas = ptr.To[int32](3)
+	db.Spec.StorageType = dbapi.StorageTypeDurable
+	db.Spec.Storage = &core.PersistentVolumeClaimSpec{
+		Resources: core.VolumeResourceRequirements{Requests: core.ResourceList{core.ResourceStorage: resource.MustParse("10Gi")}},
+	}
+	db.Spec.PodTemplate.Spec.Containers = []core.Container{{
+		Name: kubedb.PostgresContainerName,
+		Resources: core.ResourceRequirements{
+			Requests: core.ResourceList{core.ResourceCPU: resource.MustParse("500m"), core.ResourceMemory: resource.MustParse("1Gi")},
+			Limits:   core.ResourceList{core.ResourceCPU: resource.MustParse("1"), core.ResourceMemory: resource.MustParse("1Gi")},
+		},
+	}}
+	return db
+}
+
+// newMongoDB returns a replica set of 3 members.
+func newMongoDB() *dbapi.MongoDB {
+	db := &dbapi.MongoDB{}
+	db.Name, db.Namespace = "mg", "demo"
+	db.Spec.Replicas = ptr.To[int32](3)
+	db.Spec.ReplicaSet = &dbapi.MongoDBReplicaSet{Name: "rs0"}
+	return db
+}
+
+func TestBuildOpsRequest(t *testing.T) {
+	cases := []struct {
+		name   string
+		db     client.Object
+		intent string
+		args   []string
+		opts   requestOptions
+		// want maps a field path of the ops request, joined by dots, to its value.
+		want    map[string]interface{}
+		wantErr string
+	}{
+		{
+			name:   "scale",
+			db:     newPostgres(),
+			intent: "scale",
+			args:   []string{"5"},
+			want:   map[string]interface{}{"spec.type": "HorizontalScaling", "spec.horizontalScaling.replicas": int64(5)},
+		},
+		{
+			name:    "scale to the current replicas",
+			db:      newPostgres(),
+			intent:  "scale",
+			args:    []string{"3"},
+			wantErr: "pg already runs 3 replicas",
+		},
+		{
+			name:    "scale below the minimum",
+			db:      newPostgres(),
+			intent:  "scale",
+			args:    []string{"0"},
+			wantErr: "0 replicas is below the minimum of 1",
+		},
+		{
+			name:    "scale above the maximum",
+			db:      newMongoDB(),
+			intent:  "scale",
+			args:    []string{"51"},
+			wantErr: "51 replicas is above the maximum of 50",
+		},
+		{
+			name: "scale a standalone",
+			db: func() client.Object {
+				db := newMongoDB()
+				db.Spec.ReplicaSet = nil
+				return db
+			}(),
+			intent:  "scale",
+			args:    []string{"3"},
+			wantErr: "standalone MongoDB can not be scaled horizontally",
+		},
+		{
+			name:    "invalid replicas",
+			db:      newPostgres(),
+			intent:  "scale",
+			args:    []string{"three"},
+			wantErr: `invalid replicas "three"`,
+		},
+		{
+			name:   "expand",
+			db:     newPostgres(),
+			intent: "expand",
+			args:   []string{"20Gi"},
+			opts:   requestOptions{offline: true},
+			want: map[string]interface{}{
+				"spec.type":                     "VolumeExpansion",
+				"spec.volumeExpansion.postgres": "20Gi",
+				"spec.volumeExpansion.mode":     "Offline",
+			},
+		},
+		{
+			name:    "shrink",
+			db:      newPostgres(),
+			intent:  "expand",
+			args:    []string{"5Gi"},
+			wantErr: "volumes can only grow, 5Gi is not larger than the current 10Gi",
+		},
+		{
+			name:    "expand to the current size",
+			db:      newPostgres(),
+			intent:  "expand",
+			args:    []string{"10240Mi"},
+			wantErr: "10Gi is not larger than the current 10Gi",
+		},
+		{
+			name: "expand ephemeral storage",
+			db: func() client.Object {
+				db := newPostgres()
+				db.Spec.StorageType = dbapi.StorageTypeEphemeral
+				return db
+			}(),
+			intent:  "expand",
+			args:    []string{"20Gi"},
+			wantErr: "ephemeral storage can not be expanded",
+		},
+		{
+			name:   "memory sets the request and the limit",
+			db:     newPostgres(),
+			intent: "resources",
+			opts:   requestOptions{memory: "2Gi"},
+			want: map[string]interface{}{
+				"spec.type": "VerticalScaling",
+				"spec.verticalScaling.postgres.resources.requests.memory": "2Gi",
+				"spec.verticalScaling.postgres.resources.limits.memory":   "2Gi",
+				"spec.verticalScaling.postgres.resources.limits.cpu":      "1",
+			},
+		},
+		{
+			name:    "cpu request above the limit",
+			db:      newPostgres(),
+			intent:  "resources",
+			opts:    requestOptions{cpu: "2"},
+			wantErr: "cpu request 2 is above the limit 1",
+		},
+		{
+			name:    "unchanged resources",
+			db:      newPostgres(),
+			intent:  "resources",
+			opts:    requestOptions{cpu: "500m", memory: "1Gi"},
+			wantErr: "pg already has these resources",
+		},
+		{
+			name:    "resources without flags",
+			db:      newPostgres(),
+			intent:  "resources",
+			wantErr: "usage: resources",
+		},
+		{
+			name:    "rotate without TLS",
+			db:      newPostgres(),
+			intent:  "rotate-tls",
+			wantErr: "pg does not use TLS",
+		},
+		{
+			name: "rotate",
+			db: func() client.Object {
+				db := newPostgres()
+				db.Spec.TLS = &kmapi.TLSConfig{}
+				return db
+			}(),
+			intent: "rotate-tls",
+			want:   map[string]interface{}{"spec.type": "ReconfigureTLS", "spec.tls.rotateCertificates": true},
+		},
+		{
+			name:    "unknown intent",
+			db:      newPostgres(),
+			intent:  "upgrade",
+			wantErr: `unknown intent "upgrade"`,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			gvks, _, err := scm.ObjectKinds(c.db)
+			if err != nil {
+				t.Fatal(err)
+			}
+			k, err := lookupKind(gvks[0].Kind)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req, err := buildOpsRequest(k, c.db, c.intent, c.args, c.opts)
+			if c.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), c.wantErr) {
+					t.Fatalf("buildOpsRequest() error = %v, want %q", err, c.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if req.GetNamespace() != "demo" || req.GetObjectKind().GroupVersionKind() != k.OpsRequestGVK() {
+				t.Errorf("ops request is a %s in %q, want a %s in demo", req.GetObjectKind().GroupVersionKind(), req.GetNamespace(), k.OpsRequestKind)
+			}
+			u, err := runtime.DefaultUnstructuredConverter.ToUnstructured(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ref, _, _ := unstructured.NestedString(u, "spec", "databaseRef", "name"); ref != c.db.GetName() {
+				t.Errorf("databaseRef = %q, want %s", ref, c.db.GetName())
+			}
+			for path, want := range c.want {
+				got, _, _ := unstructured.NestedFieldNoCopy(u, strings.Split(path, ".")...)
+				if !reflect.DeepEqual(got, want) {
+					t.Errorf("%s = %#v, want %#v", path, got, want)
+				}
+			}
+		})
+	}
+}
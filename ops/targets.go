@@ -0,0 +1,209 @@
+package main
+
+import (
+	"fmt"
+	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
+	kmapi "kmodules.xyz/client-go/api/v1"
+	ofstv2 "kmodules.xyz/offshoot-api/api/v2"
+	"kubedb.dev/apimachinery/apis/kubedb"
+	dbapi "kubedb.dev/apimachinery/apis/kubedb/v1"
+	opsapi "kubedb.dev/apimachinery/apis/ops/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// opsTarget reads the current state of a database and writes the type specific
+// sections of its ops requests. Every kind names these sections differently,
+// e.g. spec.verticalScaling.postgres vs spec.verticalScaling.replicaSet.
+type opsTarget interface {
+	Replicas() int32
+	// ReplicaLimits returns the replica range allowed in the current mode. A max
+	// of 0 means unbounded. An error means the mode can not be scaled.
+	ReplicaLimits() (min, max int32, err error)
+	// Storage returns the requested size of the data volume, or an error for
+	// ephemeral storage.
+	Storage() (*resource.Quantity, error)
+	Resources() core.ResourceRequirements
+	TLS() *kmapi.TLSConfig
+
+	HorizontalScaling(replicas int32) map[string]interface{}
+	VerticalScaling(res core.ResourceRequirements) map[string]interface{}
+	VolumeExpansion(size resource.Quantity, mode opsapi.VolumeExpansionMode) map[string]interface{}
+}
+
+func newOpsTarget(db client.Object) (opsTarget, error) {
+	switch db := db.(type) {
+	case *dbapi.Postgres:
+		return postgresTarget{db}, nil
+	case *dbapi.MongoDB:
+		return mongoDBTarget{db}, nil
+	case *dbapi.MySQL:
+		return mySQLTarget{db}, nil
+	}
+	return nil, fmt.Errorf("ops requests for %T are not supported yet", db)
+}
+
+func durableStorage(st dbapi.StorageType, pvc *core.PersistentVolumeClaimSpec) (*resource.Quantity, error) {
+	if st == dbapi.StorageTypeEphemeral {
+		return nil, fmt.Errorf("ephemeral storage can not be expanded")
+	}
+	if pvc == nil {
+		return nil, fmt.Errorf("spec.storage is not set")
+	}
+	q, ok := pvc.Resources.Requests[core.ResourceStorage]
+	if !ok {
+		return nil, fmt.Errorf("spec.storage.resources.requests.storage is not set")
+	}
+	return &q, nil
+}
+
+func containerResources(tpl *ofstv2.PodTemplateSpec, name string) core.ResourceRequirements {
+	if tpl == nil {
+		return core.ResourceRequirements{}
+	}
+	for _, c := range tpl.Spec.Containers {
+		if c.Name == name {
+			return c.Resources
+		}
+	}
+	return core.ResourceRequirements{}
+}
+
+func section(name string, content interface{}) map[string]interface{} {
+	return map[string]interface{}{name: content}
+}
+
+func volume(mode opsapi.VolumeExpansionMode, field string, size resource.Quantity) map[string]interface{} {
+	return section("volumeExpansion", map[string]interface{}{
+		"mode": string(mode),
+		field:  size.String(),
+	})
+}
+
+func podResources(field string, res core.ResourceRequirements) map[string]interface{} {
+	// ToUnstructured only fails for non struct values.
+	u, _ := runtime.DefaultUnstructuredConverter.ToUnstructured(&res)
+	return section("verticalScaling", map[string]interface{}{
+		field: map[string]interface{}{
+			"resources": u,
+		},
+	})
+}
+
+type postgresTarget struct{ db *dbapi.Postgres }
+
+func (t postgresTarget) Replicas() int32 { return ptr.Deref(t.db.Spec.Replicas, 1) }
+
+func (t postgresTarget) ReplicaLimits() (int32, int32, error) { return 1, 0, nil }
+
+func (t postgresTarget) Storage() (*resource.Quantity, error) {
+	return durableStorage(t.db.Spec.StorageType, t.db.Spec.Storage)
+}
+
+func (t postgresTarget) Resources() core.ResourceRequirements {
+	return containerResources(&t.db.Spec.PodTemplate, kubedb.PostgresContainerName)
+}
+
+func (t postgresTarget) TLS() *kmapi.TLSConfig { return t.db.Spec.TLS }
+
+func (t postgresTarget) HorizontalScaling(replicas int32) map[string]interface{} {
+	return section("horizontalScaling", map[string]interface{}{"replicas": int64(replicas)})
+}
+
+func (t postgresTarget) VerticalScaling(res core.ResourceRequirements) map[string]interface{} {
+	return podResources("postgres", res)
+}
+
+func (t postgresTarget) VolumeExpansion(size resource.Quantity, mode opsapi.VolumeExpansionMode) map[string]interface{} {
+	return volume(mode, "postgres", size)
+}
+
+type mongoDBTarget struct{ db *dbapi.MongoDB }
+
+func (t mongoDBTarget) Replicas() int32 { return ptr.Deref(t.db.Spec.Replicas, 1) }
+
+// MongoDB allows at most 50 members in a replica set.
+func (t mongoDBTarget) ReplicaLimits() (int32, int32, error) {
+	switch {
+	case t.db.Spec.ShardTopology != nil:
+		return 0, 0, fmt.Errorf("sharded MongoDB is scaled per shard, config server and mongos, which this command does not do")
+	case t.db.Spec.ReplicaSet == nil:
+		return 0, 0, fmt.Errorf("standalone MongoDB can not be scaled horizontally")
+	}
+	return 1, 50, nil
+}
+
+func (t mongoDBTarget) Storage() (*resource.Quantity, error) {
+	if t.db.Spec.ShardTopology != nil {
+		return nil, fmt.Errorf("sharded MongoDB is expanded per shard and config server, which this command does not do")
+	}
+	return durableStorage(t.db.Spec.StorageType, t.db.Spec.Storage)
+}
+
+func (t mongoDBTarget) Resources() core.ResourceRequirements {
+	return containerResources(t.db.Spec.PodTemplate, kubedb.MongoDBContainerName)
+}
+
+func (t mongoDBTarget) TLS() *kmapi.TLSConfig { return t.db.Spec.TLS }
+
+func (t mongoDBTarget) HorizontalScaling(replicas int32) map[string]interface{} {
+	return section("horizontalScaling", map[string]interface{}{"replicas": int64(replicas)})
+}
+
+func (t mongoDBTarget) field() string {
+	if t.db.Spec.ReplicaSet != nil {
+		return "replicaSet"
+	}
+	return "standalone"
+}
+
+func (t mongoDBTarget) VerticalScaling(res core.ResourceRequirements) map[string]interface{} {
+	return podResources(t.field(), res)
+}
+
+func (t mongoDBTarget) VolumeExpansion(size resource.Quantity, mode opsapi.VolumeExpansionMode) map[string]interface{} {
+	return volume(mode, t.field(), size)
+}
+
+type mySQLTarget struct{ db *dbapi.MySQL }
+
+func (t mySQLTarget) Replicas() int32 { return ptr.Deref(t.db.Spec.Replicas, 1) }
+
+// Group replication supports at most 9 members and needs 3 to tolerate a failure.
+func (t mySQLTarget) ReplicaLimits() (int32, int32, error) {
+	if t.db.Spec.Topology == nil || t.db.Spec.Topology.Mode == nil {
+		return 0, 0, fmt.Errorf("standalone MySQL can not be scaled horizontally")
+	}
+	switch mode := *t.db.Spec.Topology.Mode; mode {
+	case dbapi.MySQLModeGroupReplication, dbapi.MySQLModeInnoDBCluster:
+		return 3, 9, nil
+	case dbapi.MySQLModeSemiSync:
+		return 2, 0, nil
+	default:
+		return 0, 0, fmt.Errorf("MySQL in %s mode can not be scaled horizontally", mode)
+	}
+}
+
+func (t mySQLTarget) Storage() (*resource.Quantity, error) {
+	return durableStorage(t.db.Spec.StorageType, t.db.Spec.Storage)
+}
+
+func (t mySQLTarget) Resources() core.ResourceRequirements {
+	return containerResources(&t.db.Spec.PodTemplate, kubedb.MySQLContainerName)
+}
+
+func (t mySQLTarget) TLS() *kmapi.TLSConfig { return t.db.Spec.TLS }
+
+func (t mySQLTarget) HorizontalScaling(replicas int32) map[string]interface{} {
+	return section("horizontalScaling", map[string]interface{}{"member": int64(replicas)})
+}
+
+func (t mySQLTarget) VerticalScaling(res core.ResourceRequirements) map[string]interface{} {
+	return podResources("mysql", res)
+}
+
+func (t mySQLTarget) VolumeExpansion(size resource.Quantity, mode opsapi.VolumeExpansionMode) map[string]interface{} {
+	return volume(mode, "mysql", size)
+}
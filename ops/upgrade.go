@@ -0,0 +1,317 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/Masterminds/semver/v3"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	catalogapi "kubedb.dev/apimachinery/apis/catalog/v1alpha1"
+	"os"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+	"sort"
+	"strings"
+)
+
+// catalogVersion is the part of a catalog *Version object that decides upgrades.
+type catalogVersion struct {
+	Name         string
+	Version      *semver.Version
+	Distribution string
+	Deprecated   bool
+	Constraints  catalogapi.UpdateConstraints
+}
+
+func toCatalogVersion(obj runtime.Object) (*catalogVersion, error) {
+	u, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil, err
+	}
+	name, _, _ := unstructured.NestedString(u, "metadata", "name")
+	raw, _, _ := unstructured.NestedString(u, "spec", "version")
+	v, err := semver.NewVersion(raw)
+	if err != nil {
+		return nil, fmt.Errorf("spec.version %q of %s is not a semantic version: %w", raw, name, err)
+	}
+	cv := &catalogVersion{Name: name, Version: v}
+	cv.Distribution, _, _ = unstructured.NestedString(u, "spec", "distribution")
+	cv.Deprecated, _, _ = unstructured.NestedBool(u, "spec", "deprecated")
+	if m, ok, _ := unstructured.NestedMap(u, "spec", "updateConstraints"); ok {
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(m, &cv.Constraints); err != nil {
+			return nil, err
+		}
+	}
+	return cv, nil
+}
+
+// allows reports whether a database running v may be updated to target. Like the
+// KubeDB ops manager, target must match no denylist entry and, if the allowlist
+// is not empty, at least one allowlist entry. Entries are semver constraints.
+func (v *catalogVersion) allows(target *catalogVersion) (bool, error) {
+	for _, c := range v.Constraints.Denylist {
+		ok, err := matches(c, target.Version)
+		if err != nil {
+			return false, fmt.Errorf("denylist of %s: %w", v.Name, err)
+		}
+		if ok {
+			return false, nil
+		}
+	}
+	if len(v.Constraints.Allowlist) == 0 {
+		return true, nil
+	}
+	for _, c := range v.Constraints.Allowlist {
+		ok, err := matches(c, target.Version)
+		if err != nil {
+			return false, fmt.Errorf("allowlist of %s: %w", v.Name, err)
+		}
+		if ok {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func matches(constraint string, v *semver.Version) (bool, error) {
+	c, err := semver.NewConstraint(constraint)
+	if err != nil {
+		return false, err
+	}
+	return c.Check(v), nil
+}
+
+type upgradePlan struct {
+	From *catalogVersion
+	// Targets are the versions From allows as a single UpdateVersion hop.
+	Targets []*catalogVersion
+	// Path lists the hops to the chosen target, without From.
+	Path []*catalogVersion
+	// Skipped explains why a version can never be a target.
+	Skipped map[string]string
+}
+
+// planUpgrade finds the shortest chain of UpdateVersion hops from the version named
+// from to the one named to. Only newer, non deprecated versions of the same
+// distribution are considered. If to is empty, the newest reachable version is
+// chosen. Among equally short paths, the one through newer versions wins.
+func planUpgrade(versions []*catalogVersion, from, to string) (*upgradePlan, error) {
+	plan := &upgradePlan{Skipped: map[string]string{}}
+	byName := map[string]*catalogVersion{}
+	for _, v := range versions {
+		byName[v.Name] = v
+	}
+	if plan.From = byName[from]; plan.From == nil {
+		return nil, fmt.Errorf("version %s is not in the catalog", from)
+	}
+
+	var candidates []*catalogVersion
+	for _, v := range versions {
+		switch {
+		case v.Name == from:
+		case v.Deprecated:
+			plan.Skipped[v.Name] = "deprecated"
+		case plan.From.Distribution != "" && v.Distribution != plan.From.Distribution:
+			plan.Skipped[v.Name] = fmt.Sprintf("distribution %s differs from %s", v.Distribution, plan.From.Distribution)
+		case !v.Version.GreaterThan(plan.From.Version):
+			plan.Skipped[v.Name] = "not newer than " + from
+		default:
+			candidates = append(candidates, v)
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].Version.GreaterThan(candidates[j].Version)
+	})
+
+	// Breadth first search, so the first time a version is reached is by the fewest hops.
+	prev := map[string]*catalogVersion{}
+	queue := []*catalogVersion{plan.From}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for _, next := range candidates {
+			if _, seen := prev[next.Name]; seen || !next.Version.GreaterThan(cur.Version) {
+				continue
+			}
+			ok, err := cur.allows(next)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				continue
+			}
+			prev[next.Name] = cur
+			queue = append(queue, next)
+			if cur == plan.From {
+				plan.Targets = append(plan.Targets, next)
+			}
+		}
+	}
+
+	if to == "" {
+		for _, v := range candidates {
+			if _, ok := prev[v.Name]; ok {
+				to = v.Name
+				break
+			}
+		}
+		if to == "" {
+			return plan, nil
+		}
+	}
+	if to == from {
+		return nil, fmt.Errorf("the database already runs %s", from)
+	}
+	if reason, ok := plan.Skipped[to]; ok {
+		return nil, fmt.Errorf("version %s can not be a target: %s", to, reason)
+	}
+	if _, ok := byName[to]; !ok {
+		return nil, fmt.Errorf("version %s is not in the catalog", to)
+	}
+	if _, ok := prev[to]; !ok {
+		return nil, fmt.Errorf("no chain of allowed updates leads from %s to %s", from, to)
+	}
+	for v := byName[to]; v != plan.From; v = prev[v.Name] {
+		plan.Path = append([]*catalogVersion{v}, plan.Path...)
+	}
+	return plan, nil
+}
+
+func runUpgradePlan(args []string) error {
+	fs := flag.NewFlagSet("upgrade-plan", flag.ExitOnError)
+	namespace := fs.String("n", "default", "Namespace of the database.")
+	to := fs.String("to", "", "Name of the target catalog version. Defaults to the newest reachable version.")
+	apply := fs.Bool("apply", false, "Create the ops request of the first hop.")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: ops upgrade-plan [flags] <kind> <name>")
+		fs.PrintDefaults()
+	}
+	pos := parseInterspersed(fs, args)
+	if len(pos) != 2 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	k, err := lookupKind(pos[0])
+	if err != nil {
+		return err
+	}
+
+	kc, err := newClient()
+	if err != nil {
+		return err
+	}
+	ctx := context.TODO()
+
+	obj, err := scm.New(k.GVK())
+	if err != nil {
+		return err
+	}
+	db := obj.(client.Object)
+	if err := kc.Get(ctx, types.NamespacedName{Namespace: *namespace, Name: pos[1]}, db); err != nil {
+		return err
+	}
+	current, err := specVersion(db)
+	if err != nil {
+		return err
+	}
+
+	listGVK := k.VersionGVK()
+	listGVK.Kind += "List"
+	obj, err = scm.New(listGVK)
+	if err != nil {
+		return err
+	}
+	list := obj.(client.ObjectList)
+	if err := kc.List(ctx, list); err != nil {
+		return err
+	}
+	var versions []*catalogVersion
+	err = meta.EachListItem(list, func(item runtime.Object) error {
+		v, err := toCatalogVersion(item)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "skipping:", err)
+			return nil
+		}
+		versions = append(versions, v)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	plan, err := planUpgrade(versions, current, *to)
+	if err != nil {
+		return err
+	}
+	printPlan(os.Stderr, k, db, plan)
+	if len(plan.Path) == 0 {
+		return nil
+	}
+
+	for i, hop := range plan.Path {
+		req, err := newOpsRequest(k, db, opsTypeUpdateVersion, hop.Name, map[string]interface{}{
+			"updateVersion": map[string]interface{}{
+				"targetVersion": hop.Name,
+			},
+		})
+		if err != nil {
+			return err
+		}
+		data, err := yaml.Marshal(req)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("---\n# hop %d of %d\n%s", i+1, len(plan.Path), data)
+
+		if *apply && i == 0 {
+			if err := kc.Create(ctx, req); err != nil {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "created %s %s/%s\n", k.OpsRequestKind, req.GetNamespace(), req.GetName())
+		}
+	}
+	if len(plan.Path) > 1 {
+		fmt.Fprintln(os.Stderr, "Each hop is validated against the version the database runs at that time, "+
+			"so create the next ops request only after the previous one has succeeded.")
+	}
+	return nil
+}
+
+func specVersion(db client.Object) (string, error) {
+	u, err := runtime.DefaultUnstructuredConverter.ToUnstructured(db)
+	if err != nil {
+		return "", err
+	}
+	v, _, _ := unstructured.NestedString(u, "spec", "version")
+	if v == "" {
+		return "", fmt.Errorf("%s/%s has no spec.version", db.GetNamespace(), db.GetName())
+	}
+	return v, nil
+}
+
+func printPlan(w *os.File, k *dbKind, db client.Object, plan *upgradePlan) {
+	fmt.Fprintf(w, "%s %s/%s runs %s (%s)\n", k.Kind, db.GetNamespace(), db.GetName(), plan.From.Name, plan.From.Version)
+	if plan.From.Deprecated {
+		fmt.Fprintf(w, "  %s is deprecated\n", plan.From.Name)
+	}
+	targets := make([]string, 0, len(plan.Targets))
+	for _, v := range plan.Targets {
+		targets = append(targets, v.Name)
+	}
+	if len(targets) == 0 {
+		fmt.Fprintln(w, "  no direct update is allowed")
+	} else {
+		fmt.Fprintf(w, "  direct updates allowed to: %s\n", strings.Join(targets, ", "))
+	}
+	if len(plan.Path) == 0 {
+		return
+	}
+	hops := []string{plan.From.Name}
+	for _, v := range plan.Path {
+		hops = append(hops, v.Name)
+	}
+	fmt.Fprintf(w, "  path: %s\n", strings.Join(hops, " -> "))
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"github.com/Masterminds/semver/v3"
+	catalogapi "kubedb.dev/apimachinery/apis/catalog/v1alpha1"
+	"strings"
+	"testing"
+)
+
+func newVersion(name, version string, deprecated bool, allow, deny []string) *catalogVersion {
+	return &catalogVersion{
+		Name:         name,
+		Version:      semver.MustParse(version),
+		Distribution: "Official",
+		Deprecated:   deprecated,
+		Constraints:  catalogapi.UpdateConstraints{Allowlist: allow, Denylist: deny},
+	}
+}
+
+func TestPlanUpgrade(t *testing.T) {
+	// 13.13 may only go to 14.x, 14.x only to 15.x and 15.10 is deprecated, so
+	// 16.4 is three hops away and 15.10 is never a target.
+	versions := []*catalogVersion{
+		newVersion("13.13", "13.13.0", false, []string{">= 14.0, < 15.0"}, nil),
+		newVersion("14.10", "14.10.0", false, []string{">= 15.0, < 16.0"}, nil),
+		newVersion("14.11", "14.11.0", false, []string{">= 15.0, < 16.0"}, []string{"15.5"}),
+		newVersion("15.5", "15.5.0", false, nil, []string{"< 16.3"}),
+		newVersion("15.10", "15.10.0", true, nil, nil),
+		newVersion("16.1", "16.1.0", false, nil, nil),
+		newVersion("16.4", "16.4.0", false, nil, nil),
+		newVersion("17.0-timescale", "17.0.0", false, nil, nil),
+	}
+	versions[len(versions)-1].Distribution = "TimescaleDB"
+
+	cases := []struct {
+		name    string
+		from    string
+		to      string
+		targets string
+		path    string
+		wantErr string
+	}{
+		{name: "single hop", from: "15.5", to: "16.4", targets: "16.4", path: "16.4"},
+		{name: "multi hop", from: "13.13", to: "16.4", targets: "14.11,14.10", path: "14.10,15.5,16.4"},
+		{name: "nothing reachable", from: "14.11"},
+		{name: "newest reachable by default", from: "13.13", targets: "14.11,14.10", path: "14.10,15.5,16.4"},
+		{name: "denied on every path", from: "15.5", to: "16.1", wantErr: "no chain of allowed updates leads from 15.5 to 16.1"},
+		{name: "deprecated target", from: "14.10", to: "15.10", wantErr: "version 15.10 can not be a target: deprecated"},
+		{name: "other distribution", from: "15.5", to: "17.0-timescale", wantErr: "distribution TimescaleDB differs from Official"},
+		{name: "older target", from: "16.1", to: "15.5", wantErr: "not newer than 16.1"},
+		{name: "same version", from: "16.4", to: "16.4", wantErr: "already runs 16.4"},
+		{name: "unknown target", from: "16.1", to: "18.0", wantErr: "version 18.0 is not in the catalog"},
+		{name: "unknown source", from: "12.0", to: "16.4", wantErr: "version 12.0 is not in the catalog"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			plan, err := planUpgrade(versions, c.from, c.to)
+			if c.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), c.wantErr) {
+					t.Fatalf("planUpgrade(%s, %s) error = %v, want %q", c.from, c.to, err, c.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("planUpgrade(%s, %s): %v", c.from, c.to, err)
+			}
+			if got := names(plan.Targets); got != c.targets {
+				t.Errorf("planUpgrade(%s, %s) targets = %q, want %q", c.from, c.to, got, c.targets)
+			}
+			if got := names(plan.Path); got != c.path {
+				t.Errorf("planUpgrade(%s, %s) path = %q, want %q", c.from, c.to, got, c.path)
+			}
+			if plan.Skipped["15.10"] != "deprecated" {
+				t.Errorf("planUpgrade(%s, %s) skipped = %v, want 15.10 deprecated", c.from, c.to, plan.Skipped)
+			}
+		})
+	}
+}
+
+func names(versions []*catalogVersion) string {
+	out := make([]string, 0, len(versions))
+	for _, v := range versions {
+		out = append(out, v.Name)
+	}
+	return strings.Join(out, ",")
+}
@@ -7,7 +7,6 @@ import (
 	"gomodules.xyz/jsonpatch/v2"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/json"
-	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/yaml"
@@ -31,7 +30,7 @@ func main() {
 		panic(err)
 	}
 
-	cfg := ctrl.GetConfigOrDie()
+	cfg := restConfig()
 	kc, err := client.New(cfg, client.Options{
 		Scheme: scm,
 		Mapper: nil,
@@ -2,7 +2,9 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
+	"github.com/ArnobKumarSaha/k8s/kubeclient"
 	bapi "go.bytebuilders.dev/catalog/api/v1alpha1"
 	"gomodules.xyz/jsonpatch/v2"
 	core "k8s.io/api/core/v1"
@@ -12,13 +14,13 @@ import (
 	"k8s.io/apimachinery/pkg/util/json"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
 	"k8s.io/klog/v2"
 	cu "kmodules.xyz/client-go/client"
 	coreutil "kmodules.xyz/client-go/core/v1"
 	kubedbscheme "kubedb.dev/apimachinery/client/clientset/versioned/scheme"
 	psapi "kubeops.dev/petset/apis/apps/v1"
 	skapi "kubeops.dev/sidekick/apis/apps/v1alpha1"
-	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/yaml"
 	"time"
@@ -34,6 +36,17 @@ func init() {
 	utilruntime.Must(psapi.AddToScheme(scm))
 	utilruntime.Must(skapi.AddToScheme(scm))
 	utilruntime.Must(bapi.AddToScheme(scm))
+
+	kopts.AddFlags(flag.CommandLine)
+}
+
+var kopts kubeclient.Options
+
+// restConfig parses the flags, so whichever main of this demo is enabled takes
+// --context and --client-settings.
+func restConfig() *rest.Config {
+	flag.Parse()
+	return kubeclient.New(kopts, scm).RESTConfigOrDie()
 }
 
 // var ps = `apiVersion: apps/v1
@@ -72,7 +85,7 @@ func _main() {
 		panic(err)
 	}
 
-	cfg := ctrl.GetConfigOrDie()
+	cfg := restConfig()
 	kc, err := client.New(cfg, client.Options{
 		Scheme: scm,
 		Mapper: nil,
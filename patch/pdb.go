@@ -10,7 +10,6 @@ import (
 	"k8s.io/client-go/kubernetes"
 	core_util "kmodules.xyz/client-go/core/v1"
 	policy_util "kmodules.xyz/client-go/policy"
-	ctrl "sigs.k8s.io/controller-runtime"
 )
 
 func main_() {
@@ -21,9 +20,15 @@ func main_() {
 
 func useGeneratedClient() error {
 	fmt.Println("Using Generated client")
-	cfg := ctrl.GetConfigOrDie()
-	cfg.QPS = 100
-	cfg.Burst = 100
+	cfg := restConfig()
+	// Unless --client-settings sets them, keep the limits this demo always used
+	// instead of the client-go defaults of 5 and 10.
+	if cfg.QPS == 0 {
+		cfg.QPS = 100
+	}
+	if cfg.Burst == 0 {
+		cfg.Burst = 100
+	}
 
 	kc, err := kubernetes.NewForConfig(cfg)
 	if err != nil {
@@ -12,7 +12,6 @@ import (
 	cu "kmodules.xyz/client-go/client"
 	dbapi "kubedb.dev/apimachinery/apis/kubedb/v1"
 	skapi "kubeops.dev/sidekick/apis/apps/v1alpha1"
-	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/yaml"
 	"time"
@@ -167,7 +166,7 @@ func __main() {
 		panic(err)
 	}
 
-	cfg := ctrl.GetConfigOrDie()
+	cfg := restConfig()
 	kc, err := client.New(cfg, client.Options{
 		Scheme: scm,
 		Mapper: nil,
@@ -0,0 +1,90 @@
+package main
+
+import (
+	apps "k8s.io/api/apps/v1"
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	psapi "kubeops.dev/petset/apis/apps/v1"
+)
+
+// toPetSet returns the PetSet that runs the pods and claims of sts: same name,
+// labels, annotations and spec. The last applied configuration of kubectl is
+// dropped, as it describes the StatefulSet. The pod template and the
+// volumeClaimTemplates are copied as they are, so that the claims keep their
+// names. The PetSet controller hashes its revisions its own way though, so the
+// PetSet gets the OnDelete update strategy to keep it from rolling the pods it
+// adopts. migrate restores the update strategy of sts once the pods turn out
+// to be on the revision of the PetSet.
+func toPetSet(sts *apps.StatefulSet) *psapi.PetSet {
+	in := sts.DeepCopy()
+
+	annotations := in.Annotations
+	delete(annotations, core.LastAppliedConfigAnnotation)
+	if len(annotations) == 0 {
+		annotations = nil
+	}
+
+	claims := make([]core.PersistentVolumeClaim, 0, len(in.Spec.VolumeClaimTemplates))
+	for _, c := range in.Spec.VolumeClaimTemplates {
+		claims = append(claims, core.PersistentVolumeClaim{
+			TypeMeta: c.TypeMeta,
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        c.Name,
+				Labels:      c.Labels,
+				Annotations: c.Annotations,
+			},
+			Spec: c.Spec,
+		})
+	}
+	if len(claims) == 0 {
+		claims = nil
+	}
+
+	return &psapi.PetSet{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: psapi.SchemeGroupVersion.String(),
+			Kind:       "PetSet",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        in.Name,
+			Namespace:   in.Namespace,
+			Labels:      in.Labels,
+			Annotations: annotations,
+		},
+		Spec: psapi.PetSetSpec{
+			Replicas: in.Spec.Replicas,
+			Selector: in.Spec.Selector,
+			Template: psapi.PodTemplateSpec{
+				ObjectMeta: in.Spec.Template.ObjectMeta,
+				Spec:       in.Spec.Template.Spec,
+			},
+			VolumeClaimTemplates:                 claims,
+			ServiceName:                          in.Spec.ServiceName,
+			PodManagementPolicy:                  in.Spec.PodManagementPolicy,
+			UpdateStrategy:                       apps.StatefulSetUpdateStrategy{Type: apps.OnDeleteStatefulSetStrategyType},
+			RevisionHistoryLimit:                 in.Spec.RevisionHistoryLimit,
+			MinReadySeconds:                      in.Spec.MinReadySeconds,
+			PersistentVolumeClaimRetentionPolicy: in.Spec.PersistentVolumeClaimRetentionPolicy,
+			Ordinals:                             in.Spec.Ordinals,
+		},
+	}
+}
+
+// toStatefulSet returns sts as it can be created again, without the fields the
+// API server sets.
+func toStatefulSet(sts *apps.StatefulSet) *apps.StatefulSet {
+	out := &apps.StatefulSet{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: apps.SchemeGroupVersion.String(),
+			Kind:       "StatefulSet",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        sts.Name,
+			Namespace:   sts.Namespace,
+			Labels:      sts.Labels,
+			Annotations: sts.Annotations,
+		},
+		Spec: sts.Spec,
+	}
+	return out.DeepCopy()
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"github.com/ArnobKumarSaha/k8s/objsource"
+	apps "k8s.io/api/apps/v1"
+	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	psapi "kubeops.dev/petset/apis/apps/v1"
+	"reflect"
+	"sigs.k8s.io/yaml"
+	"strings"
+	"testing"
+)
+
+func loadStatefulSet(t *testing.T) *apps.StatefulSet {
+	t.Helper()
+	src, err := objsource.LoadDir("testdata", scm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	objs, err := src.List(context.TODO(), apps.SchemeGroupVersion.WithKind("StatefulSet"), "demo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(objs) != 1 {
+		t.Fatalf("testdata has %d StatefulSets in demo, want 1", len(objs))
+	}
+	return objs[0].(*apps.StatefulSet)
+}
+
+func TestToPetSet(t *testing.T) {
+	one := intstr.FromInt32(1)
+	cases := []struct {
+		name string
+		got  func(ps *psapi.PetSet) interface{}
+		want interface{}
+	}{
+		{
+			name: "name and namespace",
+			got:  func(ps *psapi.PetSet) interface{} { return ps.Namespace + "/" + ps.Name },
+			want: "demo/web",
+		},
+		{
+			name: "type",
+			got:  func(ps *psapi.PetSet) interface{} { return ps.APIVersion + " " + ps.Kind },
+			want: "apps.k8s.appscode.com/v1 PetSet",
+		},
+		{
+			name: "last applied configuration is dropped",
+			got:  func(ps *psapi.PetSet) interface{} { return ps.Annotations },
+			want: map[string]string{"team": "storage"},
+		},
+		{
+			name: "server fields are not copied",
+			got: func(ps *psapi.PetSet) interface{} {
+				return []interface{}{ps.UID, ps.ResourceVersion, ps.Generation, ps.Status.Replicas}
+			},
+			want: []interface{}{types.UID(""), "", int64(0), int32(0)},
+		},
+		{
+			name: "replicas and ordinals",
+			got: func(ps *psapi.PetSet) interface{} {
+				return []int32{*ps.Spec.Replicas, ps.Spec.Ordinals.Start}
+			},
+			want: []int32{3, 1},
+		},
+		{
+			name: "pod management policy and service",
+			got: func(ps *psapi.PetSet) interface{} {
+				return string(ps.Spec.PodManagementPolicy) + " " + ps.Spec.ServiceName
+			},
+			want: "Parallel web",
+		},
+		{
+			name: "update strategy",
+			got:  func(ps *psapi.PetSet) interface{} { return ps.Spec.UpdateStrategy },
+			want: apps.StatefulSetUpdateStrategy{Type: apps.OnDeleteStatefulSetStrategyType},
+		},
+		{
+			name: "retention policy",
+			got:  func(ps *psapi.PetSet) interface{} { return ps.Spec.PersistentVolumeClaimRetentionPolicy },
+			want: &apps.StatefulSetPersistentVolumeClaimRetentionPolicy{
+				WhenDeleted: apps.RetainPersistentVolumeClaimRetentionPolicyType,
+				WhenScaled:  apps.DeletePersistentVolumeClaimRetentionPolicyType,
+			},
+		},
+		{
+			name: "history and readiness",
+			got: func(ps *psapi.PetSet) interface{} {
+				return []int32{*ps.Spec.RevisionHistoryLimit, ps.Spec.MinReadySeconds}
+			},
+			want: []int32{5, 10},
+		},
+		{
+			name: "claims keep their name and drop their status",
+			got: func(ps *psapi.PetSet) interface{} {
+				var out []string
+				for _, c := range ps.Spec.VolumeClaimTemplates {
+					out = append(out, c.Name+" "+string(c.Status.Phase)+" "+c.Spec.Resources.Requests.Storage().String())
+				}
+				return out
+			},
+			want: []string{"www  1Gi"},
+		},
+		{
+			name: "pod template",
+			got: func(ps *psapi.PetSet) interface{} {
+				c := ps.Spec.Template.Spec.Containers
+				return []string{ps.Spec.Template.Labels["app.kubernetes.io/name"], c[0].Image, c[0].VolumeMounts[0].Name}
+			},
+			want: []string{"web", "nginx:1.27", "www"},
+		},
+	}
+
+	sts := loadStatefulSet(t)
+	before := sts.DeepCopy()
+	ps := toPetSet(sts)
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := c.got(ps); !reflect.DeepEqual(got, c.want) {
+				t.Errorf("got %#v, want %#v", got, c.want)
+			}
+		})
+	}
+	if !reflect.DeepEqual(sts, before) {
+		t.Error("toPetSet changed the StatefulSet")
+	}
+	want := apps.StatefulSetUpdateStrategy{
+		Type:          apps.RollingUpdateStatefulSetStrategyType,
+		RollingUpdate: &apps.RollingUpdateStatefulSetStrategy{Partition: new(int32), MaxUnavailable: &one},
+	}
+	if !reflect.DeepEqual(sts.Spec.UpdateStrategy, want) {
+		t.Errorf("update strategy of the StatefulSet = %+v, want %+v to restore", sts.Spec.UpdateStrategy, want)
+	}
+
+	sts.Annotations = map[string]string{core.LastAppliedConfigAnnotation: "{}"}
+	sts.Spec.VolumeClaimTemplates = nil
+	if ps := toPetSet(sts); ps.Annotations != nil || ps.Spec.VolumeClaimTemplates != nil {
+		t.Errorf("toPetSet() = annotations %v and claims %v, want none", ps.Annotations, ps.Spec.VolumeClaimTemplates)
+	}
+}
+
+func TestStaleRevision(t *testing.T) {
+	pod := func(name, rev string) core.Pod {
+		var p core.Pod
+		p.Name = name
+		p.Labels = map[string]string{apps.ControllerRevisionHashLabelKey: rev}
+		return p
+	}
+	pods := []core.Pod{pod("web-1", "web-abc"), pod("web-2", "web-def"), {}}
+	pods[2].Name = "web-3"
+	want := []string{"web-2 (web-def)", "web-3 ()"}
+	if got := staleRevision(pods, "web-abc"); !reflect.DeepEqual(got, want) {
+		t.Errorf("staleRevision() = %q, want %q", got, want)
+	}
+	if got := staleRevision(pods[:1], "web-abc"); got != nil {
+		t.Errorf("staleRevision() = %q, want none", got)
+	}
+}
+
+func TestRestoreHint(t *testing.T) {
+	sts := loadStatefulSet(t)
+	hint := restoreHint(types.NamespacedName{Namespace: "demo", Name: "web"}, sts.Spec.UpdateStrategy)
+	want := `  kubectl -n demo patch petset web --type=merge -p '{"spec":{"updateStrategy":{"type":"RollingUpdate","rollingUpdate":{"partition":0,"maxUnavailable":1}}}}'`
+	if hint != want {
+		t.Errorf("restoreHint() = %s, want %s", hint, want)
+	}
+}
+
+func TestRollbackHint(t *testing.T) {
+	sts := loadStatefulSet(t)
+	hint := rollbackHint(sts)
+	if !strings.Contains(hint, "kubectl -n demo delete petset web --cascade=orphan\n") {
+		t.Errorf("hint has no orphaning delete of the PetSet:\n%s", hint)
+	}
+	_, manifest, _ := strings.Cut(hint, "create the StatefulSet again:\n")
+	var got apps.StatefulSet
+	if err := yaml.Unmarshal([]byte(manifest), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Kind != "StatefulSet" || got.UID != "" || got.ResourceVersion != "" || got.Status.Replicas != 0 {
+		t.Errorf("StatefulSet of the hint has server fields:\n%s", manifest)
+	}
+	if !reflect.DeepEqual(got.Spec, sts.Spec) || !reflect.DeepEqual(got.Annotations, sts.Annotations) {
+		t.Errorf("StatefulSet of the hint differs from the deleted one:\n%s", manifest)
+	}
+}
@@ -0,0 +1,323 @@
+// petsetmigrate moves a running StatefulSet onto an equivalent PetSet without
+// restarting its pods or losing its PVCs. It checks the PetSet with a server
+// side dry run, deletes the StatefulSet with orphan propagation so that its pods
+// and PVCs survive, creates the PetSet, waits until the PetSet controller has
+// adopted the pods, and then watches the PetSet for --settle to verify that its
+// generation stays stable. The PetSet starts with the OnDelete update strategy,
+// and gets the one of the StatefulSet only once its pods are on the revision of
+// the PetSet, so that it does not roll them. If the PetSet fails once the
+// StatefulSet is deleted, it prints how to go back.
+//
+// With --dry-run it only prints the PetSet, for a StatefulSet of the cluster or
+// of the files given with -f.
+//
+//	go run ./petsetmigrate --context=kind-kind -n demo --dry-run web
+//	go run ./petsetmigrate -f ./petsetmigrate/testdata --dry-run -n demo web
+//	go run ./petsetmigrate --context=kind-kind -n demo web
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/ArnobKumarSaha/k8s/kubeclient"
+	"github.com/ArnobKumarSaha/k8s/objsource"
+	apps "k8s.io/api/apps/v1"
+	core "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/klog/v2"
+	psapi "kubeops.dev/petset/apis/apps/v1"
+	"os"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+	"time"
+)
+
+var (
+	scm = runtime.NewScheme()
+)
+
+func init() {
+	utilruntime.Must(clientgoscheme.AddToScheme(scm))
+	utilruntime.Must(psapi.AddToScheme(scm))
+}
+
+type options struct {
+	namespace string
+	file      string
+	dryRun    bool
+	timeout   time.Duration
+	settle    time.Duration
+}
+
+func main() {
+	var o options
+	flag.StringVar(&o.namespace, "n", "default", "Namespace of the StatefulSet.")
+	flag.StringVar(&o.file, "f", "", "With --dry-run, read the StatefulSet from the YAML and JSON files below this file or directory instead of the cluster.")
+	flag.BoolVar(&o.dryRun, "dry-run", false, "Only print the PetSet.")
+	flag.DurationVar(&o.timeout, "timeout", 5*time.Minute, "How long to wait for the StatefulSet to be deleted and for the pods to be adopted.")
+	flag.DurationVar(&o.settle, "settle", 30*time.Second, "How long the generation of the PetSet must stay stable after adoption.")
+	var kopts kubeclient.Options
+	kopts.AddFlags(flag.CommandLine)
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "Usage: petsetmigrate [flags] <statefulset>")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	key := types.NamespacedName{Namespace: o.namespace, Name: flag.Arg(0)}
+
+	if o.file != "" {
+		if !o.dryRun {
+			klog.Fatalln("-f needs --dry-run, the migration works on the cluster")
+		}
+		src, err := objsource.LoadDir(o.file, scm)
+		if err != nil {
+			klog.Fatalln(err)
+		}
+		if err := printPetSet(context.TODO(), src, key); err != nil {
+			klog.Fatalln(err)
+		}
+		return
+	}
+
+	kc, err := kubeclient.New(kopts, scm).Client()
+	if err != nil {
+		klog.Fatalln(err)
+	}
+	if o.dryRun {
+		err = printPetSet(context.TODO(), objsource.Live(kc), key)
+	} else {
+		err = migrate(context.TODO(), kc, key, o)
+	}
+	if err != nil {
+		klog.Fatalln(err)
+	}
+}
+
+func printPetSet(ctx context.Context, src objsource.Source, key types.NamespacedName) error {
+	objs, err := src.List(ctx, apps.SchemeGroupVersion.WithKind("StatefulSet"), key.Namespace)
+	if err != nil {
+		return err
+	}
+	for _, obj := range objs {
+		if obj.GetName() != key.Name {
+			continue
+		}
+		data, err := yaml.Marshal(toPetSet(obj.(*apps.StatefulSet)))
+		if err != nil {
+			return err
+		}
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	return fmt.Errorf("StatefulSet %s not found", key)
+}
+
+func migrate(ctx context.Context, kc client.Client, key types.NamespacedName, o options) error {
+	var sts apps.StatefulSet
+	if err := kc.Get(ctx, key, &sts); err != nil {
+		return err
+	}
+	ps := toPetSet(&sts)
+
+	// Fail before touching the StatefulSet if the PetSet cannot be created.
+	var existing psapi.PetSet
+	switch err := kc.Get(ctx, key, &existing); {
+	case err == nil:
+		return fmt.Errorf("PetSet %s already exists", key)
+	case !apierrors.IsNotFound(err):
+		return err
+	}
+	if err := kc.Create(ctx, ps.DeepCopy(), client.DryRunAll); err != nil {
+		return fmt.Errorf("PetSet %s would not be created: %w", key, err)
+	}
+
+	fmt.Fprintf(os.Stderr, "deleting StatefulSet %s, orphaning its pods and PVCs\n", key)
+	if err := kc.Delete(ctx, &sts, client.PropagationPolicy(metav1.DeletePropagationOrphan), client.Preconditions{UID: &sts.UID}); err != nil {
+		return err
+	}
+	err := wait.PollUntilContextTimeout(ctx, time.Second, o.timeout, true, func(ctx context.Context) (bool, error) {
+		err := kc.Get(ctx, key, &apps.StatefulSet{})
+		if apierrors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, err
+	})
+	if err != nil {
+		return fmt.Errorf("StatefulSet %s was not deleted: %w", key, err)
+	}
+
+	fmt.Fprintf(os.Stderr, "creating PetSet %s\n", key)
+	if err := kc.Create(ctx, ps); err != nil {
+		data, _ := yaml.Marshal(toPetSet(&sts))
+		return fmt.Errorf("the pods of StatefulSet %s are orphaned, create this PetSet by hand: %w\n%s", key, err, data)
+	}
+
+	fmt.Fprintf(os.Stderr, "waiting for PetSet %s to adopt the pods\n", key)
+	var pods []core.Pod
+	err = wait.PollUntilContextTimeout(ctx, 2*time.Second, o.timeout, true, func(ctx context.Context) (bool, error) {
+		var adopted bool
+		var err error
+		pods, adopted, err = podsOf(ctx, kc, ps)
+		return adopted, err
+	})
+	if err != nil {
+		return fmt.Errorf("PetSet %s did not adopt the pods: %w\n%s", key, err, rollbackHint(&sts))
+	}
+	fmt.Fprintf(os.Stderr, "PetSet %s adopted %d pods\n", key, len(pods))
+	if missing, err := missingClaims(ctx, kc, ps); err != nil {
+		return err
+	} else if len(missing) > 0 {
+		fmt.Fprintf(os.Stderr, "warning: PVCs %v of the pods do not exist\n", missing)
+	}
+
+	stale, err := verify(ctx, kc, ps, o.settle)
+	if err != nil {
+		return fmt.Errorf("%w\n%s", err, rollbackHint(&sts))
+	}
+	if len(stale) > 0 {
+		return fmt.Errorf("PetSet %s keeps the OnDelete update strategy, as pods %v are not on its revision. Delete them one at a time to roll them, then restore the update strategy with\n%s", key, stale, restoreHint(key, sts.Spec.UpdateStrategy))
+	}
+
+	if sts.Spec.UpdateStrategy.Type != apps.OnDeleteStatefulSetStrategyType {
+		fmt.Fprintf(os.Stderr, "restoring the %s update strategy of PetSet %s\n", sts.Spec.UpdateStrategy.Type, key)
+		var cur psapi.PetSet
+		if err := kc.Get(ctx, key, &cur); err != nil {
+			return err
+		}
+		orig := cur.DeepCopy()
+		cur.Spec.UpdateStrategy = sts.Spec.UpdateStrategy
+		if err := kc.Patch(ctx, &cur, client.MergeFrom(orig)); err != nil {
+			return fmt.Errorf("PetSet %s runs the pods with the OnDelete update strategy, restore it with\n%s\n%w", key, restoreHint(key, sts.Spec.UpdateStrategy), err)
+		}
+	}
+	fmt.Printf("PetSet %s runs the %d pods of StatefulSet %s\n", key, len(pods), key)
+	return nil
+}
+
+// restoreHint returns the kubectl command that sets the update strategy of the
+// PetSet key to s.
+func restoreHint(key types.NamespacedName, s apps.StatefulSetUpdateStrategy) string {
+	patch := map[string]interface{}{"spec": map[string]interface{}{"updateStrategy": s}}
+	data, err := json.Marshal(patch)
+	if err != nil {
+		return err.Error()
+	}
+	return fmt.Sprintf("  kubectl -n %s patch petset %s --type=merge -p '%s'", key.Namespace, key.Name, data)
+}
+
+// rollbackHint tells how to hand the pods back to sts, which is deleted by the
+// time the PetSet fails.
+func rollbackHint(sts *apps.StatefulSet) string {
+	data, err := yaml.Marshal(toStatefulSet(sts))
+	if err != nil {
+		return err.Error()
+	}
+	return fmt.Sprintf("To go back, delete the PetSet but not its pods with\n  kubectl -n %s delete petset %s --cascade=orphan\nand create the StatefulSet again:\n%s", sts.Namespace, sts.Name, data)
+}
+
+// podsOf returns the pods ps selects, and whether ps controls all of them.
+func podsOf(ctx context.Context, kc client.Client, ps *psapi.PetSet) ([]core.Pod, bool, error) {
+	sel, err := metav1.LabelSelectorAsSelector(ps.Spec.Selector)
+	if err != nil {
+		return nil, false, err
+	}
+	var list core.PodList
+	if err := kc.List(ctx, &list, client.InNamespace(ps.Namespace), client.MatchingLabelsSelector{Selector: sel}); err != nil {
+		return nil, false, err
+	}
+	for i := range list.Items {
+		if !metav1.IsControlledBy(&list.Items[i], ps) {
+			return list.Items, false, nil
+		}
+	}
+	return list.Items, true, nil
+}
+
+// missingClaims returns the PVCs of the volumeClaimTemplates of ps for its
+// ordinals that do not exist.
+func missingClaims(ctx context.Context, kc client.Client, ps *psapi.PetSet) ([]string, error) {
+	replicas, start := int32(1), int32(0)
+	if ps.Spec.Replicas != nil {
+		replicas = *ps.Spec.Replicas
+	}
+	if ps.Spec.Ordinals != nil {
+		start = ps.Spec.Ordinals.Start
+	}
+	var missing []string
+	for _, c := range ps.Spec.VolumeClaimTemplates {
+		for i := start; i < start+replicas; i++ {
+			name := fmt.Sprintf("%s-%s-%d", c.Name, ps.Name, i)
+			err := kc.Get(ctx, types.NamespacedName{Namespace: ps.Namespace, Name: name}, &core.PersistentVolumeClaim{})
+			switch {
+			case apierrors.IsNotFound(err):
+				missing = append(missing, name)
+			case err != nil:
+				return nil, err
+			}
+		}
+	}
+	return missing, nil
+}
+
+// verify watches ps for settle, and fails if its generation changes or if its
+// controller does not observe it. It then returns the pods of ps that are not on
+// its update revision, listed again as the controller may have replaced some.
+func verify(ctx context.Context, kc client.Client, ps *psapi.PetSet, settle time.Duration) ([]string, error) {
+	key := client.ObjectKeyFromObject(ps)
+	var cur psapi.PetSet
+	if err := kc.Get(ctx, key, &cur); err != nil {
+		return nil, err
+	}
+	generation := cur.Generation
+	fmt.Fprintf(os.Stderr, "watching PetSet %s at generation %d for %s\n", key, generation, settle)
+
+	deadline := time.Now().Add(settle)
+	for time.Now().Before(deadline) {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(2 * time.Second):
+		}
+		if err := kc.Get(ctx, key, &cur); err != nil {
+			return nil, err
+		}
+		if cur.Generation != generation {
+			return nil, fmt.Errorf("generation of PetSet %s changed from %d to %d", key, generation, cur.Generation)
+		}
+	}
+
+	if cur.Status.ObservedGeneration != cur.Generation {
+		return nil, fmt.Errorf("PetSet %s is at generation %d, its controller observed %d", key, cur.Generation, cur.Status.ObservedGeneration)
+	}
+	if cur.Status.UpdateRevision == "" {
+		return nil, fmt.Errorf("PetSet %s reports no update revision", key)
+	}
+	pods, _, err := podsOf(ctx, kc, &cur)
+	if err != nil {
+		return nil, err
+	}
+	return staleRevision(pods, cur.Status.UpdateRevision), nil
+}
+
+// staleRevision returns the pods whose revision hash is not rev.
+func staleRevision(pods []core.Pod, rev string) []string {
+	var stale []string
+	for _, pod := range pods {
+		if h := pod.Labels[apps.ControllerRevisionHashLabelKey]; h != rev {
+			stale = append(stale, fmt.Sprintf("%s (%s)", pod.Name, h))
+		}
+	}
+	return stale
+}
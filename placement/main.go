@@ -0,0 +1,248 @@
+// placement simulates where the PetSet controller and the scheduler put the
+// pods of a PetSet under its PlacementPolicy, without a multi-zone cluster. The
+// nodes are either Nodes, e.g. saved with kubectl get nodes -o yaml, or are
+// made up from a NodeTopology: --nodes-per-zone nodes of each of its node groups
+// in each of --zones, with the allocatable resources of the group.
+//
+// It prints the node and zone of every ordinal, why the ordinals that fit on no
+// node are unschedulable, and the final spread of the pods over the zones and
+// nodes against the maxSkew of the policy. It exits with 1 when an ordinal is
+// unschedulable or a maxSkew is not respected.
+//
+// The replicas of a topology domain of a node affinity rule are the ordinals
+// placed in the domain, as a list of ordinals and ranges like "0-1,4". Pods
+// already running on the nodes are not taken into account.
+//
+//	go run ./placement ./placement/testdata/petset
+//	go run ./placement --zones=us-east-2a,us-east-2b --nodes-per-zone=2 --replicas=7 ./placement/testdata/petset
+//	go run ./placement ./placement/testdata/petset ./placement/testdata/nodes
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/ArnobKumarSaha/k8s/objsource"
+	"io"
+	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/klog/v2"
+	nodeapi "kmodules.xyz/resource-metadata/apis/node/v1alpha1"
+	psapi "kubeops.dev/petset/apis/apps/v1"
+	"os"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+var (
+	scm = runtime.NewScheme()
+)
+
+func init() {
+	utilruntime.Must(clientgoscheme.AddToScheme(scm))
+	utilruntime.Must(psapi.AddToScheme(scm))
+	utilruntime.Must(nodeapi.AddToScheme(scm))
+}
+
+type options struct {
+	petset       string
+	topology     string
+	zones        string
+	nodesPerZone int
+	replicas     int
+}
+
+func main() {
+	var o options
+	flag.StringVar(&o.petset, "petset", "", "Name of the PetSet to simulate, if the files have several.")
+	flag.StringVar(&o.topology, "topology", "", "Place the pods on the nodes of this NodeTopology. By default the Nodes in the files are used, or the only NodeTopology.")
+	flag.StringVar(&o.zones, "zones", "zone-a,zone-b,zone-c", "Zones of the nodes made up from a NodeTopology.")
+	flag.IntVar(&o.nodesPerZone, "nodes-per-zone", 1, "Nodes of each node group of a NodeTopology per zone.")
+	flag.IntVar(&o.replicas, "replicas", -1, "Simulate this many replicas instead of those of the PetSet.")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "Usage: placement [flags] <file or directory>...")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	res, err := run(context.TODO(), os.Stdout, flag.Args(), o)
+	if err != nil {
+		klog.Fatalln(err)
+	}
+	if len(res.unschedulable()) > 0 {
+		os.Exit(1)
+	}
+	for _, sp := range res.Spreads {
+		if !sp.Respected() {
+			os.Exit(1)
+		}
+	}
+}
+
+// objects is what the files hold.
+type objects struct {
+	petsets    []client.Object
+	policies   []client.Object
+	topologies []client.Object
+	nodes      []client.Object
+}
+
+func load(ctx context.Context, paths []string) (*objects, error) {
+	var objs objects
+	for _, p := range paths {
+		src, err := objsource.LoadDir(p, scm)
+		if err != nil {
+			return nil, err
+		}
+		for gvk, dst := range map[schema.GroupVersionKind]*[]client.Object{
+			psapi.SchemeGroupVersion.WithKind("PetSet"):                           &objs.petsets,
+			psapi.SchemeGroupVersion.WithKind(psapi.ResourceKindPlacementPolicy):  &objs.policies,
+			nodeapi.SchemeGroupVersion.WithKind(nodeapi.ResourceKindNodeTopology): &objs.topologies,
+			core.SchemeGroupVersion.WithKind("Node"):                              &objs.nodes,
+		} {
+			list, err := src.List(ctx, gvk, "")
+			if err != nil {
+				return nil, err
+			}
+			*dst = append(*dst, list...)
+		}
+	}
+	return &objs, nil
+}
+
+func pick(objs []client.Object, kind, name string) (client.Object, error) {
+	var names []string
+	for _, obj := range objs {
+		if name == "" && len(objs) == 1 || obj.GetName() == name {
+			return obj, nil
+		}
+		names = append(names, obj.GetName())
+	}
+	switch {
+	case len(objs) == 0:
+		return nil, fmt.Errorf("no %s in the files", kind)
+	case name == "":
+		return nil, fmt.Errorf("the files have several %ss, pick one of %s", kind, strings.Join(names, ", "))
+	}
+	return nil, fmt.Errorf("%s %s not found, the files have %s", kind, name, strings.Join(names, ", "))
+}
+
+func (objs *objects) nodesFor(o options) ([]*node, string, error) {
+	if o.topology == "" && len(objs.nodes) > 0 {
+		var out []*node
+		for _, obj := range objs.nodes {
+			out = append(out, fromNode(obj.(*core.Node)))
+		}
+		return out, fmt.Sprintf("%d Nodes", len(out)), nil
+	}
+	obj, err := pick(objs.topologies, nodeapi.ResourceKindNodeTopology, o.topology)
+	if err != nil {
+		return nil, "", err
+	}
+	nt := obj.(*nodeapi.NodeTopology)
+	var zones []string
+	for _, z := range strings.Split(o.zones, ",") {
+		if z = strings.TrimSpace(z); z != "" {
+			zones = append(zones, z)
+		}
+	}
+	if len(zones) == 0 || o.nodesPerZone < 1 {
+		return nil, "", fmt.Errorf("need at least one zone and one node per zone")
+	}
+	out := fromTopology(nt, zones, o.nodesPerZone)
+	return out, fmt.Sprintf("NodeTopology %s: %d nodes in %d zones", nt.Name, len(out), len(zones)), nil
+}
+
+func run(ctx context.Context, w io.Writer, paths []string, o options) (*result, error) {
+	objs, err := load(ctx, paths)
+	if err != nil {
+		return nil, err
+	}
+	obj, err := pick(objs.petsets, "PetSet", o.petset)
+	if err != nil {
+		return nil, err
+	}
+	pet := obj.(*psapi.PetSet)
+	if o.replicas >= 0 {
+		r := int32(o.replicas)
+		pet.Spec.Replicas = &r
+	}
+	var policy *psapi.PlacementPolicy
+	if ref := pet.Spec.PodPlacementPolicy; ref != nil && ref.Name != "" {
+		obj, err := pick(objs.policies, psapi.ResourceKindPlacementPolicy, ref.Name)
+		if err != nil {
+			return nil, err
+		}
+		policy = obj.(*psapi.PlacementPolicy)
+	}
+	nodes, nodesDesc, err := objs.nodesFor(o)
+	if err != nil {
+		return nil, err
+	}
+
+	sim, err := newSimulator(pet, policy, nodes)
+	if err != nil {
+		return nil, err
+	}
+	res := sim.run()
+
+	fmt.Fprintf(w, "PetSet %s/%s", pet.Namespace, pet.Name)
+	if policy != nil {
+		fmt.Fprintf(w, " with PlacementPolicy %s", policy.Name)
+	}
+	fmt.Fprintf(w, " on %s, each pod requesting %s\n\n", nodesDesc, formatResources(sim.requests))
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ORDINAL\tPOD\tNODE\tZONE")
+	for _, a := range res.Assignments {
+		nodeName, zone := a.Node, a.Zone
+		if nodeName == "" {
+			nodeName = "<unschedulable>"
+		}
+		if zone == "" {
+			zone = "-"
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\n", a.Ordinal, a.Pod, nodeName, zone)
+	}
+	if err := tw.Flush(); err != nil {
+		return nil, err
+	}
+
+	if unschedulable := res.unschedulable(); len(unschedulable) > 0 {
+		fmt.Fprintln(w, "\nUnschedulable:")
+		for _, a := range unschedulable {
+			fmt.Fprintf(w, "  %s: %s\n", a.Pod, a.Reason)
+		}
+		if pet.Spec.PodManagementPolicy != "Parallel" {
+			fmt.Fprintf(w, "  With OrderedReady pod management, the PetSet controller stops at %s.\n", unschedulable[0].Pod)
+		}
+	}
+
+	if len(res.Spreads) > 0 {
+		fmt.Fprintln(w, "\nSpread:")
+		for _, sp := range res.Spreads {
+			verdict := "respected"
+			if !sp.Respected() {
+				verdict = "NOT respected"
+			}
+			fmt.Fprintf(w, "  %s (%s, %s): skew %d, maxSkew %d %s\n", sp.Constraint, sp.TopologyKey, sp.WhenUnsatisfiable, sp.Skew, sp.MaxSkew, verdict)
+			domains := make([]string, 0, len(sp.Counts))
+			for d := range sp.Counts {
+				domains = append(domains, d)
+			}
+			sort.Strings(domains)
+			for _, d := range domains {
+				fmt.Fprintf(w, "    %s: %d\n", d, sp.Counts[d])
+			}
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"fmt"
+	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+	nodeapi "kmodules.xyz/resource-metadata/apis/node/v1alpha1"
+	"strings"
+)
+
+// node is a node the pods of the PetSet are placed on, with what they request
+// from it so far.
+type node struct {
+	name          string
+	labels        labels.Set
+	taints        []core.Taint
+	unschedulable bool
+	allocatable   core.ResourceList
+	requested     core.ResourceList
+}
+
+func (n *node) zone() string {
+	return n.labels[core.LabelTopologyZone]
+}
+
+// insufficient returns the first resource of requests that does not fit on n
+// anymore, or "".
+func (n *node) insufficient(requests core.ResourceList) core.ResourceName {
+	for _, name := range sortedNames(requests) {
+		alloc, ok := n.allocatable[name]
+		if !ok {
+			if name == core.ResourcePods {
+				continue
+			}
+			return name
+		}
+		free := alloc.DeepCopy()
+		free.Sub(n.requested[name])
+		if free.Cmp(requests[name]) < 0 {
+			return name
+		}
+	}
+	return ""
+}
+
+func (n *node) add(requests core.ResourceList) {
+	for name, q := range requests {
+		sum := n.requested[name].DeepCopy()
+		sum.Add(q)
+		n.requested[name] = sum
+	}
+}
+
+func fromNode(in *core.Node) *node {
+	lbls := labels.Set{core.LabelHostname: in.Name}
+	for k, v := range in.Labels {
+		lbls[k] = v
+	}
+	return &node{
+		name:          in.Name,
+		labels:        lbls,
+		taints:        in.Spec.Taints,
+		unschedulable: in.Spec.Unschedulable,
+		allocatable:   in.Status.Allocatable,
+		requested:     core.ResourceList{},
+	}
+}
+
+// fromTopology makes perZone nodes of every node group of nt in each of zones,
+// named <zone>-<topology value>-<i>. They are labeled with the topology value
+// and the In requirements of nt, and tainted with the topology value when nt
+// selects nodes by taint.
+func fromTopology(nt *nodeapi.NodeTopology, zones []string, perZone int) []*node {
+	var out []*node
+	for _, zone := range zones {
+		for _, g := range nt.Spec.NodeGroups {
+			for i := 0; i < perZone; i++ {
+				name := fmt.Sprintf("%s-%s-%d", zone, g.TopologyValue, i)
+				lbls := labels.Set{
+					core.LabelHostname:     name,
+					core.LabelTopologyZone: zone,
+					nt.Spec.TopologyKey:    g.TopologyValue,
+				}
+				for _, r := range nt.Spec.Requirements {
+					if r.Operator == core.NodeSelectorOpIn && len(r.Values) > 0 {
+						lbls[r.Key] = r.Values[0]
+					}
+				}
+				n := &node{
+					name:        name,
+					labels:      lbls,
+					allocatable: groupAllocatable(g),
+					requested:   core.ResourceList{},
+				}
+				if nt.Spec.NodeSelectionPolicy == nodeapi.NodeSelectionPolicyTaint {
+					n.taints = []core.Taint{{Key: nt.Spec.TopologyKey, Value: g.TopologyValue, Effect: core.TaintEffectNoSchedule}}
+				}
+				out = append(out, n)
+			}
+		}
+	}
+	return out
+}
+
+// groupAllocatable is what the pods can request from a node of g: its requests,
+// or its limits, or the deprecated allocatable.
+func groupAllocatable(g nodeapi.NodeGroup) core.ResourceList {
+	var out core.ResourceList
+	switch {
+	case len(g.Resources.Requests) > 0:
+		out = g.Resources.Requests.DeepCopy()
+	case len(g.Resources.Limits) > 0:
+		out = g.Resources.Limits.DeepCopy()
+	default:
+		out = g.Allocatable.DeepCopy()
+	}
+	if out == nil {
+		out = core.ResourceList{}
+	}
+	return out
+}
+
+// matchesTerms reports whether the labels of n match one of terms, as required
+// node affinity does. Field selectors are matched against the node name.
+func matchesTerms(n *node, terms []core.NodeSelectorTerm) bool {
+	for _, t := range terms {
+		if len(t.MatchExpressions) == 0 && len(t.MatchFields) == 0 {
+			continue
+		}
+		ok := matchesRequirements(n.labels, t.MatchExpressions)
+		if ok && len(t.MatchFields) > 0 {
+			ok = matchesRequirements(labels.Set{"metadata.name": n.name}, t.MatchFields)
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+var nodeSelectorOps = map[core.NodeSelectorOperator]selection.Operator{
+	core.NodeSelectorOpIn:           selection.In,
+	core.NodeSelectorOpNotIn:        selection.NotIn,
+	core.NodeSelectorOpExists:       selection.Exists,
+	core.NodeSelectorOpDoesNotExist: selection.DoesNotExist,
+	core.NodeSelectorOpGt:           selection.GreaterThan,
+	core.NodeSelectorOpLt:           selection.LessThan,
+}
+
+func matchesRequirements(set labels.Set, reqs []core.NodeSelectorRequirement) bool {
+	for _, r := range reqs {
+		op, ok := nodeSelectorOps[r.Operator]
+		if !ok {
+			return false
+		}
+		req, err := labels.NewRequirement(r.Key, op, r.Values)
+		if err != nil || !req.Matches(set) {
+			return false
+		}
+	}
+	return true
+}
+
+// podRequests is what a pod of spec requests: the sum of its containers or the
+// largest init container, whichever is more, plus its overhead and one pod.
+func podRequests(spec *core.PodSpec) core.ResourceList {
+	out := core.ResourceList{}
+	for _, c := range spec.Containers {
+		for name, q := range c.Resources.Requests {
+			sum := out[name].DeepCopy()
+			sum.Add(q)
+			out[name] = sum
+		}
+	}
+	for _, c := range spec.InitContainers {
+		for name, q := range c.Resources.Requests {
+			if cur, ok := out[name]; !ok || q.Cmp(cur) > 0 {
+				out[name] = q.DeepCopy()
+			}
+		}
+	}
+	for name, q := range spec.Overhead {
+		sum := out[name].DeepCopy()
+		sum.Add(q)
+		out[name] = sum
+	}
+	out[core.ResourcePods] = resource.MustParse("1")
+	return out
+}
+
+func tolerates(tolerations []core.Toleration, taints []core.Taint) bool {
+	for i := range taints {
+		if taints[i].Effect == core.TaintEffectPreferNoSchedule {
+			continue
+		}
+		tolerated := false
+		for j := range tolerations {
+			if tolerations[j].ToleratesTaint(&taints[i]) {
+				tolerated = true
+				break
+			}
+		}
+		if !tolerated {
+			return false
+		}
+	}
+	return true
+}
+
+func formatResources(rl core.ResourceList) string {
+	var parts []string
+	for _, name := range sortedNames(rl) {
+		q := rl[name]
+		parts = append(parts, fmt.Sprintf("%s=%s", name, q.String()))
+	}
+	return strings.Join(parts, ",")
+}
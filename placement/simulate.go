@@ -0,0 +1,374 @@
+package main
+
+import (
+	"fmt"
+	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	psapi "kubeops.dev/petset/apis/apps/v1"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// assignment is where one ordinal of the PetSet goes. Node is empty when no
+// node fits, and Reason tells why, the way the scheduler does.
+type assignment struct {
+	Ordinal int32
+	Pod     string
+	Node    string
+	Zone    string
+	Reason  string
+}
+
+// spread is the final distribution of the pods over the domains of a spread
+// constraint.
+type spread struct {
+	Constraint        string
+	TopologyKey       string
+	MaxSkew           int32
+	WhenUnsatisfiable core.UnsatisfiableConstraintAction
+	Counts            map[string]int
+	Skew              int
+}
+
+func (s spread) Respected() bool {
+	return s.Skew <= int(s.MaxSkew)
+}
+
+type result struct {
+	Assignments []assignment
+	Spreads     []spread
+}
+
+func (r *result) unschedulable() []assignment {
+	var out []assignment
+	for _, a := range r.Assignments {
+		if a.Node == "" {
+			out = append(out, a)
+		}
+	}
+	return out
+}
+
+// constraint is a zone or node spread constraint of a placement policy.
+type constraint struct {
+	name              string
+	key               string
+	maxSkew           int32
+	whenUnsatisfiable core.UnsatisfiableConstraintAction
+}
+
+// affinityRule is a node affinity rule of a placement policy, with the
+// ordinals of each domain parsed.
+type affinityRule struct {
+	psapi.NodeAffinityRule
+	ordinals []sets.Set[int32]
+}
+
+// values returns the topology values ordinal is pinned to by r, or nil if no
+// domain of r lists it.
+func (r affinityRule) values(ordinal int32) []string {
+	for i, d := range r.Domains {
+		if r.ordinals[i].Has(ordinal) {
+			return d.Values
+		}
+	}
+	return nil
+}
+
+type simulator struct {
+	pet         *psapi.PetSet
+	nodes       []*node
+	requests    core.ResourceList
+	constraints []constraint
+	rules       []affinityRule
+	// placed counts the pods placed so far by node name.
+	placed map[string]int
+}
+
+func newSimulator(pet *psapi.PetSet, policy *psapi.PlacementPolicy, nodes []*node) (*simulator, error) {
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].name < nodes[j].name })
+	s := &simulator{
+		pet:      pet,
+		nodes:    nodes,
+		requests: podRequests(&pet.Spec.Template.Spec),
+		placed:   map[string]int{},
+	}
+	if policy == nil {
+		return s, nil
+	}
+	if c := policy.Spec.ZoneSpreadConstraint; c != nil {
+		s.constraints = append(s.constraints, constraint{"zone", core.LabelTopologyZone, defaultSkew(c.MaxSkew), defaultAction(c.WhenUnsatisfiable)})
+	}
+	if c := policy.Spec.NodeSpreadConstraint; c != nil {
+		s.constraints = append(s.constraints, constraint{"node", core.LabelHostname, defaultSkew(c.MaxSkew), defaultAction(c.WhenUnsatisfiable)})
+	}
+	if policy.Spec.Affinity != nil {
+		for _, r := range policy.Spec.Affinity.NodeAffinity {
+			rule := affinityRule{NodeAffinityRule: r}
+			rule.WhenUnsatisfiable = defaultAction(r.WhenUnsatisfiable)
+			if rule.Weight == 0 {
+				rule.Weight = 50
+			}
+			for _, d := range r.Domains {
+				ords, err := parseOrdinals(d.Replicas)
+				if err != nil {
+					return nil, fmt.Errorf("node affinity for %s, domain %v: %w", r.TopologyKey, d.Values, err)
+				}
+				rule.ordinals = append(rule.ordinals, ords)
+			}
+			s.rules = append(s.rules, rule)
+		}
+	}
+	return s, nil
+}
+
+func defaultSkew(v int32) int32 {
+	if v <= 0 {
+		return 1
+	}
+	return v
+}
+
+func defaultAction(a core.UnsatisfiableConstraintAction) core.UnsatisfiableConstraintAction {
+	if a == "" {
+		return core.DoNotSchedule
+	}
+	return a
+}
+
+// parseOrdinals parses the replicas of a topology domain: the ordinals placed
+// in the domain as a comma separated list of ordinals and ranges, e.g. "0-1,4".
+func parseOrdinals(s string) (sets.Set[int32], error) {
+	out := sets.New[int32]()
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		lo, hi, isRange := strings.Cut(part, "-")
+		first, err := strconv.ParseInt(strings.TrimSpace(lo), 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid replicas %q", s)
+		}
+		last := first
+		if isRange {
+			if last, err = strconv.ParseInt(strings.TrimSpace(hi), 10, 32); err != nil || last < first {
+				return nil, fmt.Errorf("invalid replicas %q", s)
+			}
+		}
+		for o := first; o <= last; o++ {
+			out.Insert(int32(o))
+		}
+	}
+	return out, nil
+}
+
+// ordinals returns the ordinals of the PetSet, in the order they are created.
+func (s *simulator) ordinals() []int32 {
+	replicas, start := int32(1), int32(0)
+	if s.pet.Spec.Replicas != nil {
+		replicas = *s.pet.Spec.Replicas
+	}
+	if s.pet.Spec.Ordinals != nil {
+		start = s.pet.Spec.Ordinals.Start
+	}
+	out := make([]int32, 0, replicas)
+	for i := int32(0); i < replicas; i++ {
+		out = append(out, start+i)
+	}
+	return out
+}
+
+// run places the ordinals one after another, as the PetSet controller creates
+// them and the scheduler binds them.
+func (s *simulator) run() *result {
+	var res result
+	for _, o := range s.ordinals() {
+		res.Assignments = append(res.Assignments, s.place(o))
+	}
+	for _, c := range s.constraints {
+		counts := s.counts(c.key, s.eligible(-1))
+		sp := spread{
+			Constraint:        c.name,
+			TopologyKey:       c.key,
+			MaxSkew:           c.maxSkew,
+			WhenUnsatisfiable: c.whenUnsatisfiable,
+			Counts:            counts,
+		}
+		sp.Skew = maxCount(counts) - minCount(counts)
+		res.Spreads = append(res.Spreads, sp)
+	}
+	return &res
+}
+
+// matchesPod reports whether n matches the node selector and required node
+// affinity of the pod template, and, unless ordinal is negative, the node
+// affinity rules of the placement policy that must be met for ordinal. This is
+// what decides the eligible domains of the spread constraints.
+func (s *simulator) matchesPod(n *node, ordinal int32) string {
+	spec := &s.pet.Spec.Template.Spec
+	for k, v := range spec.NodeSelector {
+		if n.labels[k] != v {
+			return "didn't match Pod's node affinity/selector"
+		}
+	}
+	if a := spec.Affinity; a != nil && a.NodeAffinity != nil && a.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution != nil {
+		if !matchesTerms(n, a.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms) {
+			return "didn't match Pod's node affinity/selector"
+		}
+	}
+	if ordinal < 0 {
+		return ""
+	}
+	for _, r := range s.rules {
+		if r.WhenUnsatisfiable != core.DoNotSchedule {
+			continue
+		}
+		if values := r.values(ordinal); values != nil && !sets.New(values...).Has(n.labels[r.TopologyKey]) {
+			return "didn't match placement policy node affinity"
+		}
+	}
+	return ""
+}
+
+func (s *simulator) eligible(ordinal int32) []*node {
+	var out []*node
+	for _, n := range s.nodes {
+		if s.matchesPod(n, ordinal) == "" {
+			out = append(out, n)
+		}
+	}
+	return out
+}
+
+// counts returns the number of pods placed in each domain of key among nodes,
+// including empty domains.
+func (s *simulator) counts(key string, nodes []*node) map[string]int {
+	out := map[string]int{}
+	for _, n := range nodes {
+		v, ok := n.labels[key]
+		if !ok {
+			continue
+		}
+		out[v] += s.placed[n.name]
+	}
+	return out
+}
+
+func (s *simulator) place(ordinal int32) assignment {
+	a := assignment{Ordinal: ordinal, Pod: fmt.Sprintf("%s-%d", s.pet.Name, ordinal)}
+	eligible := s.eligible(ordinal)
+	domainCounts := make([]map[string]int, len(s.constraints))
+	for i, c := range s.constraints {
+		domainCounts[i] = s.counts(c.key, eligible)
+	}
+
+	rejected := map[string]int{}
+	var best *node
+	bestScore := 0
+	for _, n := range s.nodes {
+		why := s.filter(n, ordinal, domainCounts)
+		if why != "" {
+			rejected[why]++
+			continue
+		}
+		score := s.score(n, ordinal, domainCounts)
+		if best == nil || score > bestScore {
+			best, bestScore = n, score
+		}
+	}
+	if best == nil {
+		a.Reason = unschedulableReason(len(s.nodes), rejected)
+		return a
+	}
+	best.add(s.requests)
+	s.placed[best.name]++
+	a.Node, a.Zone = best.name, best.zone()
+	return a
+}
+
+// filter returns why ordinal cannot be placed on n, or "".
+func (s *simulator) filter(n *node, ordinal int32, domainCounts []map[string]int) string {
+	if n.unschedulable {
+		return "node(s) were unschedulable"
+	}
+	if why := s.matchesPod(n, ordinal); why != "" {
+		return "node(s) " + why
+	}
+	if !tolerates(s.pet.Spec.Template.Spec.Tolerations, n.taints) {
+		return "node(s) had untolerated taint"
+	}
+	if name := n.insufficient(s.requests); name != "" {
+		return "Insufficient " + string(name)
+	}
+	for i, c := range s.constraints {
+		if c.whenUnsatisfiable != core.DoNotSchedule {
+			continue
+		}
+		v, ok := n.labels[c.key]
+		if !ok {
+			return fmt.Sprintf("node(s) didn't have the %s label", c.key)
+		}
+		if domainCounts[i][v]+1-minCount(domainCounts[i]) > int(c.maxSkew) {
+			return fmt.Sprintf("node(s) didn't match %s spread constraint", c.name)
+		}
+	}
+	return ""
+}
+
+// score prefers the nodes in the domains of the soft node affinity rules for
+// ordinal, by their weight, then the domains and nodes with the fewest pods.
+// Ties go to the first node by name.
+func (s *simulator) score(n *node, ordinal int32, domainCounts []map[string]int) int {
+	score := 0
+	for _, r := range s.rules {
+		if values := r.values(ordinal); values != nil && sets.New(values...).Has(n.labels[r.TopologyKey]) {
+			score += int(r.Weight) * 10000
+		}
+	}
+	for i, c := range s.constraints {
+		score -= domainCounts[i][n.labels[c.key]] * 100
+	}
+	score -= s.placed[n.name]
+	return score
+}
+
+func unschedulableReason(total int, rejected map[string]int) string {
+	reasons := make([]string, 0, len(rejected))
+	for why, count := range rejected {
+		reasons = append(reasons, fmt.Sprintf("%d %s", count, why))
+	}
+	sort.Strings(reasons)
+	return fmt.Sprintf("0/%d nodes are available: %s.", total, strings.Join(reasons, ", "))
+}
+
+func maxCount(m map[string]int) int {
+	out := 0
+	for _, c := range m {
+		if c > out {
+			out = c
+		}
+	}
+	return out
+}
+
+func minCount(m map[string]int) int {
+	first := true
+	out := 0
+	for _, c := range m {
+		if first || c < out {
+			out, first = c, false
+		}
+	}
+	return out
+}
+
+func sortedNames(rl core.ResourceList) []core.ResourceName {
+	names := make([]core.ResourceName, 0, len(rl))
+	for name := range rl {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return names[i] < names[j] })
+	return names
+}
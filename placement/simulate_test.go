@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/sets"
+	psapi "kubeops.dev/petset/apis/apps/v1"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func newPetSet(replicas int32, cpu string) *psapi.PetSet {
+	pet := &psapi.PetSet{}
+	pet.Name = "ace-db"
+	pet.Spec.Replicas = &replicas
+	pet.Spec.Template.Spec.Containers = []core.Container{{
+		Name:      "postgres",
+		Resources: core.ResourceRequirements{Requests: core.ResourceList{core.ResourceCPU: resource.MustParse(cpu)}},
+	}}
+	return pet
+}
+
+func newNode(name, zone, cpu string) *node {
+	return &node{
+		name:        name,
+		labels:      map[string]string{core.LabelHostname: name, core.LabelTopologyZone: zone},
+		allocatable: core.ResourceList{core.ResourceCPU: resource.MustParse(cpu)},
+		requested:   core.ResourceList{},
+	}
+}
+
+// placements returns pod=node, or pod=<reason> if it was not placed.
+func placements(res *result) []string {
+	var out []string
+	for _, a := range res.Assignments {
+		if a.Node == "" {
+			out = append(out, a.Pod+"="+a.Reason)
+			continue
+		}
+		out = append(out, a.Pod+"="+a.Node)
+	}
+	return out
+}
+
+func TestSimulate(t *testing.T) {
+	zoneSpread := func(action core.UnsatisfiableConstraintAction) *psapi.PlacementPolicy {
+		policy := &psapi.PlacementPolicy{}
+		policy.Spec.ZoneSpreadConstraint = &psapi.ZoneSpreadConstraint{MaxSkew: 1, WhenUnsatisfiable: action}
+		return policy
+	}
+	// Zone a has room for all pods, zone b for one only.
+	zones := func() []*node {
+		return []*node{newNode("a-1", "a", "4"), newNode("a-2", "a", "4"), newNode("b-1", "b", "1")}
+	}
+
+	cases := []struct {
+		name   string
+		pet    *psapi.PetSet
+		policy *psapi.PlacementPolicy
+		nodes  []*node
+		want   []string
+		// spreads are constraint, counts and whether maxSkew is respected.
+		spreads []string
+	}{
+		{
+			name:   "zone spread DoNotSchedule leaves the pod that would break maxSkew pending",
+			pet:    newPetSet(4, "1"),
+			policy: zoneSpread(core.DoNotSchedule),
+			nodes:  zones(),
+			want: []string{
+				"ace-db-0=a-1",
+				"ace-db-1=b-1",
+				"ace-db-2=a-2",
+				"ace-db-3=0/3 nodes are available: 1 Insufficient cpu, 2 node(s) didn't match zone spread constraint.",
+			},
+			spreads: []string{"zone map[a:2 b:1] true"},
+		},
+		{
+			name:    "zone spread ScheduleAnyway places every pod and breaks maxSkew",
+			pet:     newPetSet(4, "1"),
+			policy:  zoneSpread(core.ScheduleAnyway),
+			nodes:   zones(),
+			want:    []string{"ace-db-0=a-1", "ace-db-1=b-1", "ace-db-2=a-2", "ace-db-3=a-1"},
+			spreads: []string{"zone map[a:3 b:1] false"},
+		},
+		{
+			name:  "insufficient resources",
+			pet:   newPetSet(3, "1500m"),
+			nodes: []*node{newNode("a-1", "a", "2"), newNode("b-1", "b", "2")},
+			want:  []string{"ace-db-0=a-1", "ace-db-1=b-1", "ace-db-2=0/2 nodes are available: 2 Insufficient cpu."},
+		},
+		{
+			name: "unschedulable and tainted nodes",
+			pet:  newPetSet(2, "1"),
+			nodes: func() []*node {
+				nodes := []*node{newNode("a-1", "a", "2"), newNode("a-2", "a", "2"), newNode("b-1", "b", "1")}
+				nodes[0].unschedulable = true
+				nodes[1].taints = []core.Taint{{Key: "dedicated", Value: "kafka", Effect: core.TaintEffectNoSchedule}}
+				return nodes
+			}(),
+			want: []string{
+				"ace-db-0=b-1",
+				"ace-db-1=0/3 nodes are available: 1 Insufficient cpu, 1 node(s) had untolerated taint, 1 node(s) were unschedulable.",
+			},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			sim, err := newSimulator(c.pet, c.policy, c.nodes)
+			if err != nil {
+				t.Fatal(err)
+			}
+			res := sim.run()
+			if got := placements(res); !reflect.DeepEqual(got, c.want) {
+				t.Errorf("placements = %q, want %q", got, c.want)
+			}
+			var spreads []string
+			for _, sp := range res.Spreads {
+				spreads = append(spreads, fmt.Sprintf("%s %v %v", sp.Constraint, sp.Counts, sp.Respected()))
+			}
+			if !reflect.DeepEqual(spreads, c.spreads) {
+				t.Errorf("spreads = %q, want %q", spreads, c.spreads)
+			}
+		})
+	}
+}
+
+func TestParseOrdinals(t *testing.T) {
+	cases := []struct {
+		in      string
+		want    []int32
+		wantErr bool
+	}{
+		{in: "0-1,4", want: []int32{0, 1, 4}},
+		{in: " 2 , 0 - 1 ", want: []int32{0, 1, 2}},
+		{in: "3,3-4", want: []int32{3, 4}},
+		{in: "5-5", want: []int32{5}},
+		{in: "", want: []int32{}},
+		{in: "0,,1", want: []int32{0, 1}},
+		{in: "4-1", wantErr: true},
+		{in: "-1", wantErr: true},
+		{in: "1-", wantErr: true},
+		{in: "0-1-2", wantErr: true},
+		{in: "a", wantErr: true},
+		{in: "0;1", wantErr: true},
+		{in: "99999999999", wantErr: true},
+	}
+	for _, c := range cases {
+		got, err := parseOrdinals(c.in)
+		if c.wantErr {
+			if err == nil || !strings.Contains(err.Error(), fmt.Sprintf("invalid replicas %q", c.in)) {
+				t.Errorf("parseOrdinals(%q) = %v, %v, want an invalid replicas error", c.in, sets.List(got), err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseOrdinals(%q) failed: %v", c.in, err)
+			continue
+		}
+		if list := sets.List(got); !reflect.DeepEqual(list, c.want) {
+			t.Errorf("parseOrdinals(%q) = %v, want %v", c.in, list, c.want)
+		}
+	}
+}
@@ -0,0 +1,240 @@
+// refcheck verifies, before they are applied, that the Secrets and ConfigMaps
+// referenced by the pod specs of manifests exist in their namespace with the
+// referenced keys. It walks env, envFrom, volumes, projected volumes and
+// imagePullSecrets of Pods, Deployments, StatefulSets, DaemonSets, ReplicaSets,
+// Jobs, CronJobs, PetSets and Sidekicks. Secrets and ConfigMaps in the manifests
+// count as existing, as they are applied together. Optional references are not
+// checked. It exits with 1 when a reference is missing.
+//
+//	go run ./refcheck --context=kind-kind sidekick.yaml
+//	go run ./refcheck --from-dir ./dump ./manifests
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/ArnobKumarSaha/k8s/kubeclient"
+	"github.com/ArnobKumarSaha/k8s/objsource"
+	"io"
+	apps "k8s.io/api/apps/v1"
+	batch "k8s.io/api/batch/v1"
+	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/klog/v2"
+	psapi "kubeops.dev/petset/apis/apps/v1"
+	skapi "kubeops.dev/sidekick/apis/apps/v1alpha1"
+	"os"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var (
+	scm = runtime.NewScheme()
+)
+
+func init() {
+	utilruntime.Must(clientgoscheme.AddToScheme(scm))
+	utilruntime.Must(psapi.AddToScheme(scm))
+	utilruntime.Must(skapi.AddToScheme(scm))
+}
+
+var workloadKinds = []schema.GroupVersionKind{
+	core.SchemeGroupVersion.WithKind("Pod"),
+	apps.SchemeGroupVersion.WithKind("Deployment"),
+	apps.SchemeGroupVersion.WithKind("StatefulSet"),
+	apps.SchemeGroupVersion.WithKind("DaemonSet"),
+	apps.SchemeGroupVersion.WithKind("ReplicaSet"),
+	batch.SchemeGroupVersion.WithKind("Job"),
+	batch.SchemeGroupVersion.WithKind("CronJob"),
+	psapi.SchemeGroupVersion.WithKind("PetSet"),
+	skapi.SchemeGroupVersion.WithKind("Sidekick"),
+}
+
+func main() {
+	var fromDir, namespace string
+	var offline bool
+	flag.StringVar(&fromDir, "from-dir", "", "Look up Secrets and ConfigMaps in the YAML and JSON files below this directory instead of the cluster.")
+	flag.StringVar(&namespace, "n", "default", "Namespace of manifests without one.")
+	flag.BoolVar(&offline, "offline", false, "Only look up Secrets and ConfigMaps in the manifests themselves.")
+	var kopts kubeclient.Options
+	kopts.AddFlags(flag.CommandLine)
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "Usage: refcheck [flags] <file or directory>...")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var cluster objsource.Source
+	switch {
+	case offline:
+	case fromDir != "":
+		ds, err := objsource.LoadDir(fromDir, scm)
+		if err != nil {
+			klog.Fatalln(err)
+		}
+		cluster = ds
+	default:
+		kc, err := kubeclient.New(kopts, scm).Client()
+		if err != nil {
+			klog.Fatalln(err)
+		}
+		cluster = objsource.Live(kc)
+	}
+
+	missing, err := run(context.TODO(), os.Stdout, flag.Args(), cluster, namespace)
+	if err != nil {
+		klog.Fatalln(err)
+	}
+	if missing > 0 {
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, w io.Writer, paths []string, cluster objsource.Source, defaultNamespace string) (int, error) {
+	var manifests []objsource.Source
+	for _, p := range paths {
+		src, err := objsource.LoadDir(p, scm)
+		if err != nil {
+			return 0, err
+		}
+		manifests = append(manifests, src)
+	}
+	idx := &index{cluster: cluster, manifests: manifests, defaultNamespace: defaultNamespace, namespaces: map[string]*namespaceIndex{}}
+
+	missing := 0
+	for _, src := range manifests {
+		for _, gvk := range workloadKinds {
+			objs, err := src.List(ctx, gvk, "")
+			if err != nil {
+				return 0, err
+			}
+			for _, obj := range objs {
+				refs, _ := podReferences(obj)
+				ns := obj.GetNamespace()
+				if ns == "" {
+					ns = defaultNamespace
+				}
+				var problems []string
+				for _, ref := range refs {
+					if ref.Optional {
+						continue
+					}
+					problem, err := idx.check(ctx, ns, ref)
+					if err != nil {
+						return 0, err
+					}
+					if problem != "" {
+						problems = append(problems, fmt.Sprintf("  %s: %s", ref.Path, problem))
+					}
+				}
+				if len(problems) == 0 {
+					continue
+				}
+				missing += len(problems)
+				fmt.Fprintf(w, "%s %s/%s:\n", gvk.Kind, ns, obj.GetName())
+				for _, p := range problems {
+					fmt.Fprintln(w, p)
+				}
+			}
+		}
+	}
+	if missing == 0 {
+		fmt.Fprintln(w, "All Secret and ConfigMap references exist.")
+	}
+	return missing, nil
+}
+
+// index answers which Secrets and ConfigMaps exist with which keys, from the
+// manifests and the cluster, loading each namespace once. Secrets and ConfigMaps
+// of the manifests without a namespace are in defaultNamespace, like workloads.
+type index struct {
+	cluster          objsource.Source
+	manifests        []objsource.Source
+	defaultNamespace string
+	namespaces       map[string]*namespaceIndex
+}
+
+type namespaceIndex struct {
+	// keys of every object, by kind and name.
+	keys map[string]map[string]map[string]bool
+}
+
+func (idx *index) check(ctx context.Context, ns string, ref reference) (string, error) {
+	ni, err := idx.namespace(ctx, ns)
+	if err != nil {
+		return "", err
+	}
+	keys, ok := ni.keys[ref.Kind][ref.Name]
+	switch {
+	case !ok:
+		return fmt.Sprintf("%s %s not found", ref.Kind, ref.Name), nil
+	case ref.Key != "" && !keys[ref.Key]:
+		return fmt.Sprintf("%s %s has no key %s", ref.Kind, ref.Name, ref.Key), nil
+	}
+	return "", nil
+}
+
+func (idx *index) namespace(ctx context.Context, ns string) (*namespaceIndex, error) {
+	if ni, ok := idx.namespaces[ns]; ok {
+		return ni, nil
+	}
+	ni := &namespaceIndex{keys: map[string]map[string]map[string]bool{kindSecret: {}, kindConfigMap: {}}}
+	for _, kind := range []string{kindSecret, kindConfigMap} {
+		gvk := core.SchemeGroupVersion.WithKind(kind)
+		if idx.cluster != nil {
+			objs, err := idx.cluster.List(ctx, gvk, ns)
+			if err != nil {
+				return nil, err
+			}
+			for _, obj := range objs {
+				ni.add(obj)
+			}
+		}
+		for _, src := range idx.manifests {
+			objs, err := src.List(ctx, gvk, "")
+			if err != nil {
+				return nil, err
+			}
+			for _, obj := range objs {
+				objNS := obj.GetNamespace()
+				if objNS == "" {
+					objNS = idx.defaultNamespace
+				}
+				if objNS == ns {
+					ni.add(obj)
+				}
+			}
+		}
+	}
+	idx.namespaces[ns] = ni
+	return ni, nil
+}
+
+func (ni *namespaceIndex) add(obj client.Object) {
+	keys := map[string]bool{}
+	switch o := obj.(type) {
+	case *core.Secret:
+		for k := range o.Data {
+			keys[k] = true
+		}
+		for k := range o.StringData {
+			keys[k] = true
+		}
+		ni.keys[kindSecret][o.Name] = keys
+	case *core.ConfigMap:
+		for k := range o.Data {
+			keys[k] = true
+		}
+		for k := range o.BinaryData {
+			keys[k] = true
+		}
+		ni.keys[kindConfigMap][o.Name] = keys
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const pod = `apiVersion: v1
+kind: Pod
+metadata:
+  name: ace-db-0
+spec:
+  containers:
+  - name: postgres
+    image: postgres:15.5
+    env:
+    - name: POSTGRES_PASSWORD
+      valueFrom:
+        secretKeyRef:
+          name: ace-db-auth
+          key: password
+`
+
+const sidekick = `apiVersion: apps.k8s.appscode.com/v1alpha1
+kind: Sidekick
+metadata:
+  name: ace-db-sidekick
+spec:
+  leader:
+    selector:
+      matchLabels:
+        app: ace-db
+  containers:
+  - name: wal-g
+    image: ghcr.io/kubedb/postgres-archiver:v0.9.0
+    env:
+    - name: POSTGRES_USER
+      valueFrom:
+        secretKeyRef:
+          name: ace-db-auth
+          key: username
+    envFrom:
+    - secretRef:
+        name: default-storage-cred
+`
+
+const authSecret = `apiVersion: v1
+kind: Secret
+metadata:
+  name: ace-db-auth
+stringData:
+  username: postgres
+  password: s3cr3t
+`
+
+const storageCred = `apiVersion: v1
+kind: Secret
+metadata:
+  name: default-storage-cred
+  namespace: %s
+stringData:
+  AWS_ACCESS_KEY_ID: minio
+`
+
+func TestRun(t *testing.T) {
+	cases := []struct {
+		name     string
+		files    []string
+		missing  int
+		problems []string
+	}{
+		{
+			name:  "secret without namespace in the same file",
+			files: []string{pod + "---\n" + authSecret},
+		},
+		{
+			name:     "secret without the key",
+			files:    []string{pod + "---\n" + strings.Replace(authSecret, "  password: s3cr3t\n", "", 1)},
+			missing:  1,
+			problems: []string{"spec.containers[0].env[0].valueFrom.secretKeyRef: Secret ace-db-auth has no key password"},
+		},
+		{
+			name:    "sidekick envFrom and secretKeyRef",
+			files:   []string{sidekick, authSecret, strings.Replace(storageCred, "%s", "ace", 1)},
+			missing: 0,
+		},
+		{
+			name:     "sidekick envFrom of a secret in another namespace",
+			files:    []string{sidekick, authSecret, strings.Replace(storageCred, "%s", "other", 1)},
+			missing:  1,
+			problems: []string{"Sidekick ace/ace-db-sidekick:", "spec.containers[0].envFrom[0].secretRef: Secret default-storage-cred not found"},
+		},
+		{
+			name:     "nothing in the manifests",
+			files:    []string{pod, sidekick},
+			missing:  3,
+			problems: []string{"Secret ace-db-auth not found", "Secret default-storage-cred not found"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for i, content := range c.files {
+				if err := os.WriteFile(filepath.Join(dir, string(rune('a'+i))+".yaml"), []byte(content), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			var out bytes.Buffer
+			missing, err := run(context.TODO(), &out, []string{dir}, nil, "ace")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if missing != c.missing {
+				t.Errorf("run() = %d missing, want %d\n%s", missing, c.missing, out.String())
+			}
+			for _, p := range c.problems {
+				if !strings.Contains(out.String(), p) {
+					t.Errorf("run() output has no %q:\n%s", p, out.String())
+				}
+			}
+		})
+	}
+}
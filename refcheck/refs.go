@@ -0,0 +1,169 @@
+package main
+
+import (
+	"fmt"
+	apps "k8s.io/api/apps/v1"
+	batch "k8s.io/api/batch/v1"
+	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	psapi "kubeops.dev/petset/apis/apps/v1"
+	skapi "kubeops.dev/sidekick/apis/apps/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	kindSecret    = "Secret"
+	kindConfigMap = "ConfigMap"
+)
+
+// reference is a Secret or ConfigMap, or one key of it, used by a pod spec.
+type reference struct {
+	Kind string
+	Name string
+	// Key is empty when the whole object is used, like by envFrom.
+	Key      string
+	Optional bool
+	Path     *field.Path
+}
+
+func (r reference) String() string {
+	if r.Key == "" {
+		return fmt.Sprintf("%s %s", r.Kind, r.Name)
+	}
+	return fmt.Sprintf("%s %s key %s", r.Kind, r.Name, r.Key)
+}
+
+// container holds the fields of a container that can reference Secrets and
+// ConfigMaps, shared by core.Container and the Container of Sidekicks.
+type container struct {
+	env     []core.EnvVar
+	envFrom []core.EnvFromSource
+	path    *field.Path
+}
+
+// podReferences returns the references of the pod spec of obj, and false for
+// objects without one.
+func podReferences(obj client.Object) ([]reference, bool) {
+	var spec *core.PodSpec
+	var path *field.Path
+	switch o := obj.(type) {
+	case *core.Pod:
+		spec, path = &o.Spec, field.NewPath("spec")
+	case *apps.Deployment:
+		spec, path = &o.Spec.Template.Spec, field.NewPath("spec", "template", "spec")
+	case *apps.StatefulSet:
+		spec, path = &o.Spec.Template.Spec, field.NewPath("spec", "template", "spec")
+	case *apps.DaemonSet:
+		spec, path = &o.Spec.Template.Spec, field.NewPath("spec", "template", "spec")
+	case *apps.ReplicaSet:
+		spec, path = &o.Spec.Template.Spec, field.NewPath("spec", "template", "spec")
+	case *batch.Job:
+		spec, path = &o.Spec.Template.Spec, field.NewPath("spec", "template", "spec")
+	case *batch.CronJob:
+		spec, path = &o.Spec.JobTemplate.Spec.Template.Spec, field.NewPath("spec", "jobTemplate", "spec", "template", "spec")
+	case *psapi.PetSet:
+		spec, path = &o.Spec.Template.Spec, field.NewPath("spec", "template", "spec")
+	case *skapi.Sidekick:
+		return sidekickReferences(o), true
+	default:
+		return nil, false
+	}
+
+	var ctrs []container
+	for i, c := range spec.InitContainers {
+		ctrs = append(ctrs, container{c.Env, c.EnvFrom, path.Child("initContainers").Index(i)})
+	}
+	for i, c := range spec.Containers {
+		ctrs = append(ctrs, container{c.Env, c.EnvFrom, path.Child("containers").Index(i)})
+	}
+	for i, c := range spec.EphemeralContainers {
+		ctrs = append(ctrs, container{c.Env, c.EnvFrom, path.Child("ephemeralContainers").Index(i)})
+	}
+	return collectReferences(ctrs, spec.Volumes, spec.ImagePullSecrets, path), true
+}
+
+// sidekickReferences reads a Sidekick, whose containers are its own Container type.
+func sidekickReferences(sk *skapi.Sidekick) []reference {
+	path := field.NewPath("spec")
+	var ctrs []container
+	for i, c := range sk.Spec.InitContainers {
+		ctrs = append(ctrs, container{c.Env, c.EnvFrom, path.Child("initContainers").Index(i)})
+	}
+	for i, c := range sk.Spec.Containers {
+		ctrs = append(ctrs, container{c.Env, c.EnvFrom, path.Child("containers").Index(i)})
+	}
+	for i, c := range sk.Spec.EphemeralContainers {
+		ctrs = append(ctrs, container{c.Env, c.EnvFrom, path.Child("ephemeralContainers").Index(i)})
+	}
+	return collectReferences(ctrs, sk.Spec.Volumes, sk.Spec.ImagePullSecrets, path)
+}
+
+func collectReferences(ctrs []container, volumes []core.Volume, pullSecrets []core.LocalObjectReference, path *field.Path) []reference {
+	var out []reference
+	for _, c := range ctrs {
+		for i, e := range c.env {
+			if e.ValueFrom == nil {
+				continue
+			}
+			p := c.path.Child("env").Index(i).Child("valueFrom")
+			if r := e.ValueFrom.SecretKeyRef; r != nil {
+				out = append(out, reference{kindSecret, r.Name, r.Key, optional(r.Optional), p.Child("secretKeyRef")})
+			}
+			if r := e.ValueFrom.ConfigMapKeyRef; r != nil {
+				out = append(out, reference{kindConfigMap, r.Name, r.Key, optional(r.Optional), p.Child("configMapKeyRef")})
+			}
+		}
+		for i, e := range c.envFrom {
+			p := c.path.Child("envFrom").Index(i)
+			if r := e.SecretRef; r != nil {
+				out = append(out, reference{kindSecret, r.Name, "", optional(r.Optional), p.Child("secretRef")})
+			}
+			if r := e.ConfigMapRef; r != nil {
+				out = append(out, reference{kindConfigMap, r.Name, "", optional(r.Optional), p.Child("configMapRef")})
+			}
+		}
+	}
+
+	for i, v := range volumes {
+		p := path.Child("volumes").Index(i)
+		if s := v.Secret; s != nil {
+			out = append(out, withItems(reference{kindSecret, s.SecretName, "", optional(s.Optional), p.Child("secret")}, s.Items)...)
+		}
+		if cm := v.ConfigMap; cm != nil {
+			out = append(out, withItems(reference{kindConfigMap, cm.Name, "", optional(cm.Optional), p.Child("configMap")}, cm.Items)...)
+		}
+		if v.Projected == nil {
+			continue
+		}
+		for j, src := range v.Projected.Sources {
+			sp := p.Child("projected", "sources").Index(j)
+			if s := src.Secret; s != nil {
+				out = append(out, withItems(reference{kindSecret, s.Name, "", optional(s.Optional), sp.Child("secret")}, s.Items)...)
+			}
+			if cm := src.ConfigMap; cm != nil {
+				out = append(out, withItems(reference{kindConfigMap, cm.Name, "", optional(cm.Optional), sp.Child("configMap")}, cm.Items)...)
+			}
+		}
+	}
+
+	for i, s := range pullSecrets {
+		out = append(out, reference{kindSecret, s.Name, "", false, path.Child("imagePullSecrets").Index(i)})
+	}
+	return out
+}
+
+// withItems returns a reference to the object, and one for each key it projects.
+func withItems(obj reference, items []core.KeyToPath) []reference {
+	out := []reference{obj}
+	for i, item := range items {
+		r := obj
+		r.Key = item.Key
+		r.Path = obj.Path.Child("items").Index(i).Child("key")
+		out = append(out, r)
+	}
+	return out
+}
+
+func optional(b *bool) bool {
+	return b != nil && *b
+}
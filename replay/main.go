@@ -0,0 +1,55 @@
+// replay serves a cassette recorded with --record, for clients that do not use
+// kubeclient, like kubectl:
+//
+//	go run ./replay --cassette run.jsonl --write-kubeconfig /tmp/replay.kubeconfig
+//	kubectl --kubeconfig /tmp/replay.kubeconfig get petsets -n demo
+package main
+
+import (
+	"flag"
+	"github.com/ArnobKumarSaha/k8s/cassette"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"k8s.io/klog/v2"
+	"os"
+	"os/signal"
+)
+
+func main() {
+	var path, addr, kubeconfig string
+	flag.StringVar(&path, "cassette", "", "Path of the cassette to replay.")
+	flag.StringVar(&addr, "addr", "127.0.0.1:8001", "Address to serve on.")
+	flag.StringVar(&kubeconfig, "write-kubeconfig", "", "Write a kubeconfig for the server to this path.")
+	flag.Parse()
+	if path == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	interactions, err := cassette.Load(path)
+	if err != nil {
+		klog.Fatalln(err)
+	}
+	srv := cassette.NewServer(interactions)
+	url, err := srv.Start(addr)
+	if err != nil {
+		klog.Fatalln(err)
+	}
+	defer srv.Close()
+
+	if kubeconfig != "" {
+		cfg := clientcmdapi.NewConfig()
+		cfg.Clusters["replay"] = &clientcmdapi.Cluster{Server: url}
+		cfg.AuthInfos["replay"] = &clientcmdapi.AuthInfo{}
+		cfg.Contexts["replay"] = &clientcmdapi.Context{Cluster: "replay", AuthInfo: "replay"}
+		cfg.CurrentContext = "replay"
+		if err := clientcmd.WriteToFile(*cfg, kubeconfig); err != nil {
+			klog.Fatalln(err)
+		}
+	}
+	klog.Infof("replaying %d interactions of %s on %s", len(interactions), path, url)
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt)
+	<-stop
+}
@@ -0,0 +1,386 @@
+// walgcheck validates the object store settings of WAL-G archivers: the bucket
+// and prefix, the endpoint URL, TLS, the addressing style and the credentials,
+// see package objstore. It reads the WALG_* and AWS_* environment of Sidekicks
+// and Pods, KubeStash BackupStorages, and objectstore-api Backends given with
+// --backend. The referenced Secrets are looked up in the manifests and then in
+// the cluster. With --round-trip it lists the prefix and writes and deletes an
+// object below it, to confirm the credentials and the path style against the
+// store itself, like a local MinIO.
+//
+//	go run ./walgcheck --context=kind-kind -n ace
+//	go run ./walgcheck --offline sidekick.yaml secrets.yaml
+//	go run ./walgcheck --backend backend.yaml -n demo --round-trip
+//
+// It exits with 1 when the Ready condition of any target is False.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/ArnobKumarSaha/k8s/kubeclient"
+	"github.com/ArnobKumarSaha/k8s/objsource"
+	"github.com/ArnobKumarSaha/k8s/objstore"
+	"io"
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/klog/v2"
+	kmapi "kmodules.xyz/client-go/api/v1"
+	storeapi "kmodules.xyz/objectstore-api/api/v1"
+	skapi "kubeops.dev/sidekick/apis/apps/v1alpha1"
+	storageapi "kubestash.dev/apimachinery/apis/storage/v1alpha1"
+	"os"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+	"text/tabwriter"
+	"time"
+)
+
+var (
+	scm = runtime.NewScheme()
+)
+
+func init() {
+	utilruntime.Must(clientgoscheme.AddToScheme(scm))
+	utilruntime.Must(skapi.AddToScheme(scm))
+	utilruntime.Must(storageapi.AddToScheme(scm))
+}
+
+type result struct {
+	Object     string           `json:"object"`
+	Container  string           `json:"container,omitempty"`
+	Target     objstore.Target  `json:"target"`
+	Conditions kmapi.Conditions `json:"conditions"`
+}
+
+func main() {
+	var backend, fromDir, namespace, output string
+	var offline bool
+	var opts objstore.Options
+	flag.StringVar(&backend, "backend", "", "Also check the objectstore-api Backend in this YAML or JSON file, in the namespace of -n.")
+	flag.StringVar(&fromDir, "from-dir", "", "Read cluster objects from the YAML and JSON files below this directory instead of the cluster.")
+	flag.StringVar(&namespace, "n", "default", "Namespace of manifests without one, and of the cluster objects to check when no manifests are given.")
+	flag.BoolVar(&offline, "offline", false, "Only look up Secrets in the manifests themselves.")
+	flag.StringVar(&output, "o", "text", "Output format: text, json or yaml.")
+	flag.BoolVar(&opts.RoundTrip, "round-trip", false, "List, write and delete an object in S3 compatible stores.")
+	flag.DurationVar(&opts.Timeout, "timeout", 30*time.Second, "Timeout of each round trip.")
+	var kopts kubeclient.Options
+	kopts.AddFlags(flag.CommandLine)
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "Usage: walgcheck [flags] [file or directory]...")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if offline && flag.NArg() == 0 && backend == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var cluster objsource.Source
+	switch {
+	case offline:
+	case fromDir != "":
+		ds, err := objsource.LoadDir(fromDir, scm)
+		if err != nil {
+			klog.Fatalln(err)
+		}
+		cluster = ds
+	default:
+		kc, err := kubeclient.New(kopts, scm).Client()
+		if err != nil {
+			klog.Fatalln(err)
+		}
+		cluster = objsource.Live(kc)
+	}
+
+	c := &checker{cluster: cluster, namespace: namespace}
+	for _, p := range flag.Args() {
+		src, err := objsource.LoadDir(p, scm)
+		if err != nil {
+			klog.Fatalln(err)
+		}
+		c.manifests = append(c.manifests, src)
+	}
+
+	ctx := context.TODO()
+	results, err := c.run(ctx, backend, flag.NArg() == 0)
+	if err != nil {
+		klog.Fatalln(err)
+	}
+	for i := range results {
+		results[i].Conditions = objstore.Check(ctx, results[i].Target, opts)
+	}
+	if err := write(os.Stdout, output, results); err != nil {
+		klog.Fatalln(err)
+	}
+	for _, r := range results {
+		if r.Conditions[0].Status == metav1.ConditionFalse {
+			os.Exit(1)
+		}
+	}
+}
+
+// checker finds the targets and loads their credentials.
+type checker struct {
+	cluster   objsource.Source
+	manifests []objsource.Source
+	namespace string
+}
+
+// run collects the targets of the manifests, or of the cluster objects in the
+// namespace when fromCluster is set, and of the backend file.
+func (c *checker) run(ctx context.Context, backend string, fromCluster bool) ([]result, error) {
+	sources, ns := c.manifests, ""
+	if fromCluster && c.cluster != nil {
+		sources, ns = []objsource.Source{c.cluster}, c.namespace
+	}
+
+	var results []result
+	for _, src := range sources {
+		for _, kind := range []string{"BackupStorage", "Sidekick", "Pod"} {
+			gvk := storageapi.GroupVersion.WithKind(kind)
+			switch kind {
+			case "Sidekick":
+				gvk = skapi.SchemeGroupVersion.WithKind(kind)
+			case "Pod":
+				gvk = core.SchemeGroupVersion.WithKind(kind)
+			}
+			objs, err := src.List(ctx, gvk, ns)
+			if err != nil {
+				return nil, err
+			}
+			for _, obj := range objs {
+				rs, err := c.targets(ctx, obj)
+				if err != nil {
+					return nil, err
+				}
+				results = append(results, rs...)
+			}
+		}
+	}
+
+	if backend != "" {
+		data, err := os.ReadFile(backend)
+		if err != nil {
+			return nil, err
+		}
+		var b storeapi.Backend
+		if err := yaml.UnmarshalStrict(data, &b); err != nil {
+			return nil, fmt.Errorf("%s: %w", backend, err)
+		}
+		t := objstore.FromBackend(b)
+		if err := c.loadCredentials(ctx, c.namespace, &t); err != nil {
+			return nil, err
+		}
+		results = append(results, result{Object: "Backend " + backend, Target: t})
+	}
+	return results, nil
+}
+
+func (c *checker) targets(ctx context.Context, obj client.Object) ([]result, error) {
+	ns := obj.GetNamespace()
+	if ns == "" {
+		ns = c.namespace
+	}
+	name := fmt.Sprintf("%s/%s", ns, obj.GetName())
+
+	var containers []core.Container
+	switch o := obj.(type) {
+	case *storageapi.BackupStorage:
+		t := objstore.FromBackupStorage(o)
+		if err := c.loadCredentials(ctx, ns, &t); err != nil {
+			return nil, err
+		}
+		return []result{{Object: "BackupStorage " + name, Target: t}}, nil
+	case *skapi.Sidekick:
+		for _, sc := range o.Spec.Containers {
+			containers = append(containers, core.Container{Name: sc.Name, Env: sc.Env, EnvFrom: sc.EnvFrom})
+		}
+		name = "Sidekick " + name
+	case *core.Pod:
+		containers = o.Spec.Containers
+		name = "Pod " + name
+	}
+
+	var results []result
+	for _, ctr := range containers {
+		env, unresolved, err := c.env(ctx, ns, ctr)
+		if err != nil {
+			return nil, err
+		}
+		t, ok := objstore.FromEnv(env)
+		if !ok {
+			continue
+		}
+		if t.Credentials != nil && (t.Credentials.AccessKeyID == "" || t.Credentials.SecretAccessKey == "") && len(unresolved) > 0 {
+			// The keys may be in the Secrets that were not found.
+			t.Credentials = nil
+			t.SecretName = sets.List(unresolved)[0]
+		}
+		results = append(results, result{Object: name, Container: ctr.Name, Target: t})
+	}
+	return results, nil
+}
+
+// env resolves the environment of ctr the way the kubelet does, with env
+// overriding envFrom. It returns the names of the Secrets and ConfigMaps that
+// were not found.
+func (c *checker) env(ctx context.Context, ns string, ctr core.Container) (map[string]string, sets.Set[string], error) {
+	env := map[string]string{}
+	unresolved := sets.New[string]()
+	for _, from := range ctr.EnvFrom {
+		var data map[string]string
+		var name string
+		var err error
+		switch {
+		case from.SecretRef != nil:
+			name = from.SecretRef.Name
+			data, err = c.data(ctx, ns, "Secret", name)
+		case from.ConfigMapRef != nil:
+			name = from.ConfigMapRef.Name
+			data, err = c.data(ctx, ns, "ConfigMap", name)
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if data == nil {
+			unresolved.Insert(name)
+		}
+		for k, v := range data {
+			env[from.Prefix+k] = v
+		}
+	}
+	for _, e := range ctr.Env {
+		if e.ValueFrom == nil {
+			env[e.Name] = e.Value
+			continue
+		}
+		var data map[string]string
+		var name, key string
+		var err error
+		switch {
+		case e.ValueFrom.SecretKeyRef != nil:
+			name, key = e.ValueFrom.SecretKeyRef.Name, e.ValueFrom.SecretKeyRef.Key
+			data, err = c.data(ctx, ns, "Secret", name)
+		case e.ValueFrom.ConfigMapKeyRef != nil:
+			name, key = e.ValueFrom.ConfigMapKeyRef.Name, e.ValueFrom.ConfigMapKeyRef.Key
+			data, err = c.data(ctx, ns, "ConfigMap", name)
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if data == nil {
+			unresolved.Insert(name)
+		} else if v, ok := data[key]; ok {
+			env[e.Name] = v
+		}
+	}
+	return env, unresolved, nil
+}
+
+func (c *checker) loadCredentials(ctx context.Context, ns string, t *objstore.Target) error {
+	if t.SecretName == "" {
+		return nil
+	}
+	data, err := c.data(ctx, ns, "Secret", t.SecretName)
+	if err != nil || data == nil {
+		return err
+	}
+	raw := map[string][]byte{}
+	for k, v := range data {
+		raw[k] = []byte(v)
+	}
+	t.SetCredentials(raw)
+	return nil
+}
+
+// data returns the data of the Secret or ConfigMap ns/name from the manifests or
+// else the cluster, or nil if it is not found.
+func (c *checker) data(ctx context.Context, ns, kind, name string) (map[string]string, error) {
+	sources := c.manifests
+	if c.cluster != nil {
+		sources = append(sources[:len(sources):len(sources)], c.cluster)
+	}
+	for _, src := range sources {
+		objs, err := src.List(ctx, core.SchemeGroupVersion.WithKind(kind), ns)
+		if err != nil {
+			return nil, err
+		}
+		for _, obj := range objs {
+			if obj.GetName() != name {
+				continue
+			}
+			data := map[string]string{}
+			switch o := obj.(type) {
+			case *core.Secret:
+				for k, v := range o.Data {
+					data[k] = string(v)
+				}
+				for k, v := range o.StringData {
+					data[k] = v
+				}
+			case *core.ConfigMap:
+				for k, v := range o.Data {
+					data[k] = v
+				}
+			}
+			return data, nil
+		}
+	}
+	return nil, nil
+}
+
+func write(w io.Writer, output string, results []result) error {
+	if results == nil {
+		results = []result{}
+	}
+	switch output {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(results)
+	case "yaml":
+		data, err := yaml.Marshal(results)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	case "text":
+	default:
+		return fmt.Errorf("unknown output format %q, expected text, json or yaml", output)
+	}
+
+	if len(results) == 0 {
+		fmt.Fprintln(w, "No WAL-G archivers or object stores found.")
+		return nil
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for i, r := range results {
+		if i > 0 {
+			fmt.Fprintln(tw)
+		}
+		obj := r.Object
+		if r.Container != "" {
+			obj += " container " + r.Container
+		}
+		fmt.Fprintf(tw, "%s: %s\n", obj, r.Target)
+		for _, c := range r.Conditions {
+			status := string(c.Status)
+			if c.Severity != kmapi.ConditionSeverityNone {
+				status += " (" + string(c.Severity) + ")"
+			}
+			fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\n", c.Type, status, c.Reason, c.Message)
+		}
+	}
+	return tw.Flush()
+}
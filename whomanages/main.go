@@ -0,0 +1,169 @@
+// whomanages explains who effectively manages an object before you edit it: the
+// controller owning it, the KubeDB operator, Helm, Flux or whoever wrote it last.
+// The actors are ranked with the evidence found for each, see package managedby.
+//
+//	go run ./whomanages -n ace sidekick ace-db-sidekick
+//	go run ./whomanages -f sidekick.yaml
+//
+// With -f the object is read from a file and the owner chain is not followed
+// beyond the first owner unless the cluster is reachable.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/ArnobKumarSaha/k8s/kubeclient"
+	"github.com/ArnobKumarSaha/k8s/managedby"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/klog/v2"
+	"os"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+var (
+	scm = runtime.NewScheme()
+)
+
+func init() {
+	utilruntime.Must(clientgoscheme.AddToScheme(scm))
+}
+
+func main() {
+	var namespace, file, output string
+	var offline bool
+	flag.StringVar(&namespace, "n", "default", "Namespace of the object. Ignored for cluster scoped kinds.")
+	flag.StringVar(&file, "f", "", "Read the object from this YAML or JSON file instead of the cluster.")
+	flag.BoolVar(&offline, "offline", false, "With -f, do not look up owners in the cluster.")
+	flag.StringVar(&output, "o", "text", "Output format: text or json.")
+	var kopts kubeclient.Options
+	kopts.AddFlags(flag.CommandLine)
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "Usage: whomanages [flags] <resource> <name>\n       whomanages [flags] -f <file>")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if (file == "" && flag.NArg() != 2) || (file != "" && flag.NArg() != 0) {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var kc client.Client
+	factory := kubeclient.New(kopts, scm)
+	if file == "" || !offline {
+		cfg, err := factory.RESTConfig()
+		if err != nil {
+			klog.Fatalln(err)
+		}
+		mapper, err := factory.RESTMapper()
+		if err != nil {
+			klog.Fatalln(err)
+		}
+		if kc, err = client.New(cfg, client.Options{Scheme: scm, Mapper: mapper}); err != nil {
+			klog.Fatalln(err)
+		}
+	}
+
+	var obj *unstructured.Unstructured
+	var err error
+	if file != "" {
+		obj, err = readFile(file)
+	} else {
+		obj, err = get(factory, kc, flag.Arg(0), flag.Arg(1), namespace)
+	}
+	if err != nil {
+		klog.Fatalln(err)
+	}
+
+	r := &managedby.Resolver{}
+	if kc != nil {
+		r.Reader = kc
+	}
+	res, err := r.Resolve(context.TODO(), obj)
+	if err != nil {
+		klog.Fatalln(err)
+	}
+
+	switch output {
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(res)
+	case "text":
+		printResult(res)
+	default:
+		err = fmt.Errorf("unknown output format %q", output)
+	}
+	if err != nil {
+		klog.Fatalln(err)
+	}
+}
+
+func get(factory *kubeclient.Factory, kc client.Client, resource, name, namespace string) (*unstructured.Unstructured, error) {
+	gvk, err := factory.KindFor(resource)
+	if err != nil {
+		return nil, err
+	}
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(gvk)
+	if namespaced, err := kc.IsObjectNamespaced(obj); err != nil {
+		return nil, err
+	} else if !namespaced {
+		namespace = ""
+	}
+	if err := kc.Get(context.TODO(), client.ObjectKey{Namespace: namespace, Name: name}, obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+func readFile(name string) (*unstructured.Unstructured, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	obj := &unstructured.Unstructured{}
+	if err := yaml.Unmarshal(data, &obj.Object); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return obj, nil
+}
+
+func printResult(res *managedby.Result) {
+	fmt.Println(res.Object)
+	if len(res.Owners) > 0 {
+		chain := res.Object.String()
+		for _, o := range res.Owners {
+			chain += " -> " + o.String()
+		}
+		fmt.Println("owners:", chain)
+	}
+	if len(res.Candidates) == 0 {
+		fmt.Println("no manager found, the object is only edited by hand")
+		return
+	}
+	for i, c := range res.Candidates {
+		fmt.Printf("%d. %s (score %d)\n", i+1, c.Actor, c.Score)
+		for _, e := range c.Evidence {
+			fmt.Printf("   - %s: %s\n", e.Source, e.Detail)
+		}
+	}
+	if len(res.Owners) > 0 && viaOwner(res.Candidates[0]) {
+		root := res.Owners[len(res.Owners)-1]
+		fmt.Printf("\nEdits to %s are reverted by %s, change %s instead.\n", res.Object, res.Candidates[0].Actor, root)
+	}
+}
+
+func viaOwner(c managedby.Candidate) bool {
+	for _, e := range c.Evidence {
+		if e.Source == "ownerReferences" {
+			return true
+		}
+	}
+	return false
+}